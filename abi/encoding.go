@@ -0,0 +1,353 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrUnknownExtractorType = errors.New("unknown extractor type")
+	ErrInvalidValue         = errors.New("invalid value")
+	ErrInvalidDecimal       = errors.New("invalid decimal value")
+	ErrValueOutOfRange      = errors.New("value out of range")
+	ErrMixedItemTypes       = errors.New("array items are of different types")
+	ErrInvalidRecord        = errors.New("invalid record fields")
+	ErrNonCanonical         = errors.New("value is not canonically encoded")
+	ErrPrecisionMismatch    = errors.New("decimal precision does not match")
+)
+
+// The composite types are encoded like abi.encode of Solidity, so the
+// subscribers of a nebula decode them with abi.decode:
+//
+//	Decimal (int256 mantissa, uint8 precision)
+//	Array   (uint8 itemType, bytes[] items)
+//	Record  (string[] names, uint8[] types, bytes[] values), sorted by name
+//
+// Uint256 is its 32 bytes, big-endian.
+var (
+	decimalArgs = ethabi.Arguments{{Type: newType("int256")}, {Type: newType("uint8")}}
+	arrayArgs   = ethabi.Arguments{{Type: newType("uint8")}, {Type: newType("bytes[]")}}
+	recordArgs  = ethabi.Arguments{{Type: newType("string[]")}, {Type: newType("uint8[]")}, {Type: newType("bytes[]")}}
+
+	maxInt256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(1))
+	minInt256 = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 255))
+)
+
+var dataTypes = map[ExtractorType]extractor.DataType{
+	Int64Type:   extractor.Int64,
+	StringType:  extractor.String,
+	BytesType:   extractor.Base64,
+	DecimalType: extractor.Decimal,
+	Uint256Type: extractor.Uint256,
+	ArrayType:   extractor.Array,
+	RecordType:  extractor.Record,
+}
+
+func newType(t string) ethabi.Type {
+	typ, err := ethabi.NewType(t, "", nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return typ
+}
+
+// DataType returns the type of the extractor data of t.
+func (t ExtractorType) DataType() (extractor.DataType, error) {
+	dataType, ok := dataTypes[t]
+	if !ok {
+		return "", ErrUnknownExtractorType
+	}
+
+	return dataType, nil
+}
+
+// ExtractorTypeOf returns the extractor type of the data type.
+func ExtractorTypeOf(dataType extractor.DataType) (ExtractorType, error) {
+	for t, v := range dataTypes {
+		if v == dataType {
+			return t, nil
+		}
+	}
+
+	return 0, ErrUnknownExtractorType
+}
+
+// Encode returns the canonical bytes of data as a value of type t. The
+// hashes of the pulses are taken from them.
+func Encode(data *extractor.Data, t ExtractorType) ([]byte, error) {
+	switch t {
+	case Int64Type:
+		v, err := strconv.ParseInt(data.Value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(v))
+		return b[:], nil
+	case StringType:
+		return []byte(data.Value), nil
+	case BytesType:
+		return base64.StdEncoding.DecodeString(data.Value)
+	case DecimalType:
+		mantissa, err := ParseDecimal(data.Value, data.Precision)
+		if err != nil {
+			return nil, err
+		}
+		return decimalArgs.Pack(mantissa, data.Precision)
+	case Uint256Type:
+		v, ok := new(big.Int).SetString(data.Value, 10)
+		if !ok {
+			return nil, ErrInvalidValue
+		}
+		if v.Sign() < 0 || v.BitLen() > 256 {
+			return nil, ErrValueOutOfRange
+		}
+		return common.LeftPadBytes(v.Bytes(), 32), nil
+	case ArrayType:
+		return encodeArray(data)
+	case RecordType:
+		return encodeRecord(data)
+	default:
+		return nil, ErrUnknownExtractorType
+	}
+}
+
+// Decode returns the data of the bytes of a value of type t. Only the
+// encodings Encode produces are accepted, so equal values have equal bytes.
+func Decode(value []byte, t ExtractorType) (*extractor.Data, error) {
+	data, err := decode(value, t)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := Encode(data, t)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(encoded, value) {
+		return nil, ErrNonCanonical
+	}
+
+	return data, nil
+}
+
+func decode(value []byte, t ExtractorType) (*extractor.Data, error) {
+	switch t {
+	case Int64Type:
+		if len(value) != 8 {
+			return nil, ErrInvalidValue
+		}
+		v := int64(binary.BigEndian.Uint64(value))
+		return &extractor.Data{Type: extractor.Int64, Value: strconv.FormatInt(v, 10)}, nil
+	case StringType:
+		return &extractor.Data{Type: extractor.String, Value: string(value)}, nil
+	case BytesType:
+		return &extractor.Data{Type: extractor.Base64, Value: base64.StdEncoding.EncodeToString(value)}, nil
+	case DecimalType:
+		values, err := decimalArgs.UnpackValues(value)
+		if err != nil {
+			return nil, err
+		}
+		precision := values[1].(uint8)
+		return &extractor.Data{
+			Type:      extractor.Decimal,
+			Value:     FormatDecimal(values[0].(*big.Int), precision),
+			Precision: precision,
+		}, nil
+	case Uint256Type:
+		if len(value) != 32 {
+			return nil, ErrInvalidValue
+		}
+		return &extractor.Data{Type: extractor.Uint256, Value: new(big.Int).SetBytes(value).String()}, nil
+	case ArrayType:
+		return decodeArray(value)
+	case RecordType:
+		return decodeRecord(value)
+	default:
+		return nil, ErrUnknownExtractorType
+	}
+}
+
+// CheckPrecision fails with ErrPrecisionMismatch if a Decimal of data, also
+// an item or a field, does not have precision fraction digits.
+func CheckPrecision(data *extractor.Data, precision uint8) error {
+	switch data.Type {
+	case extractor.Decimal:
+		if data.Precision != precision {
+			return ErrPrecisionMismatch
+		}
+	case extractor.Array:
+		for i := range data.Items {
+			if err := CheckPrecision(&data.Items[i], precision); err != nil {
+				return err
+			}
+		}
+	case extractor.Record:
+		for _, field := range data.Fields {
+			if err := CheckPrecision(&field, precision); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ParseDecimal returns the mantissa of value with precision fraction
+// digits. A value with more fraction digits is rejected, not rounded.
+func ParseDecimal(value string, precision uint8) (*big.Int, error) {
+	digits := strings.TrimPrefix(value, "-")
+	integer, fraction := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		integer, fraction = digits[:i], digits[i+1:]
+		if fraction == "" {
+			return nil, ErrInvalidDecimal
+		}
+	}
+	if integer == "" || len(fraction) > int(precision) {
+		return nil, ErrInvalidDecimal
+	}
+	for _, c := range integer + fraction {
+		if c < '0' || c > '9' {
+			return nil, ErrInvalidDecimal
+		}
+	}
+
+	mantissa, _ := new(big.Int).SetString(integer+fraction+strings.Repeat("0", int(precision)-len(fraction)), 10)
+	if digits != value {
+		mantissa.Neg(mantissa)
+	}
+	if mantissa.Cmp(minInt256) < 0 || mantissa.Cmp(maxInt256) > 0 {
+		return nil, ErrValueOutOfRange
+	}
+
+	return mantissa, nil
+}
+
+// FormatDecimal is the canonical text of the decimal with the mantissa.
+func FormatDecimal(mantissa *big.Int, precision uint8) string {
+	digits := new(big.Int).Abs(mantissa).String()
+	if len(digits) <= int(precision) {
+		digits = strings.Repeat("0", int(precision)-len(digits)+1) + digits
+	}
+
+	value := digits
+	if precision > 0 {
+		point := len(digits) - int(precision)
+		value = digits[:point] + "." + digits[point:]
+	}
+	if mantissa.Sign() < 0 {
+		value = "-" + value
+	}
+
+	return value
+}
+
+// encodeArray takes the item type from the first item. An empty array has
+// items of Int64Type.
+func encodeArray(data *extractor.Data) ([]byte, error) {
+	itemType := Int64Type
+	if len(data.Items) > 0 {
+		var err error
+		itemType, err = ExtractorTypeOf(data.Items[0].Type)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	items := make([][]byte, 0, len(data.Items))
+	for i := range data.Items {
+		if data.Items[i].Type != data.Items[0].Type {
+			return nil, ErrMixedItemTypes
+		}
+		item, err := Encode(&data.Items[i], itemType)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return arrayArgs.Pack(uint8(itemType), items)
+}
+
+func decodeArray(value []byte) (*extractor.Data, error) {
+	values, err := arrayArgs.UnpackValues(value)
+	if err != nil {
+		return nil, err
+	}
+
+	itemType := ExtractorType(values[0].(uint8))
+	data := &extractor.Data{Type: extractor.Array}
+	for _, v := range values[1].([][]byte) {
+		item, err := decode(v, itemType)
+		if err != nil {
+			return nil, err
+		}
+		data.Items = append(data.Items, *item)
+	}
+
+	return data, nil
+}
+
+func encodeRecord(data *extractor.Data) ([]byte, error) {
+	names := make([]string, 0, len(data.Fields))
+	for name := range data.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	types := make([]uint8, 0, len(names))
+	values := make([][]byte, 0, len(names))
+	for _, name := range names {
+		field := data.Fields[name]
+		t, err := ExtractorTypeOf(field.Type)
+		if err != nil {
+			return nil, err
+		}
+		value, err := Encode(&field, t)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, uint8(t))
+		values = append(values, value)
+	}
+
+	return recordArgs.Pack(names, types, values)
+}
+
+func decodeRecord(value []byte) (*extractor.Data, error) {
+	values, err := recordArgs.UnpackValues(value)
+	if err != nil {
+		return nil, err
+	}
+
+	names, types, fields := values[0].([]string), values[1].([]uint8), values[2].([][]byte)
+	if len(names) != len(types) || len(names) != len(fields) {
+		return nil, ErrInvalidRecord
+	}
+
+	data := &extractor.Data{Type: extractor.Record, Fields: make(map[string]extractor.Data)}
+	for i, name := range names {
+		if _, ok := data.Fields[name]; ok {
+			return nil, ErrInvalidRecord
+		}
+		field, err := decode(fields[i], ExtractorType(types[i]))
+		if err != nil {
+			return nil, err
+		}
+		data.Fields[name] = *field
+	}
+
+	return data, nil
+}
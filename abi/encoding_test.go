@@ -0,0 +1,105 @@
+package abi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+)
+
+func TestEncoding(t *testing.T) {
+	for i, c := range []struct {
+		t    ExtractorType
+		data extractor.Data
+	}{
+		{Int64Type, extractor.Data{Type: extractor.Int64, Value: "-42"}},
+		{StringType, extractor.Data{Type: extractor.String, Value: "BTC/USD"}},
+		{BytesType, extractor.Data{Type: extractor.Base64, Value: "AQID"}},
+		{DecimalType, extractor.Data{Type: extractor.Decimal, Value: "-12.0450", Precision: 4}},
+		{DecimalType, extractor.Data{Type: extractor.Decimal, Value: "0.07", Precision: 2}},
+		{DecimalType, extractor.Data{Type: extractor.Decimal, Value: "7", Precision: 0}},
+		{Uint256Type, extractor.Data{Type: extractor.Uint256, Value: "115792089237316195423570985008687907853269984665640564039457584007913129639935"}},
+		{ArrayType, extractor.Data{Type: extractor.Array, Items: []extractor.Data{
+			{Type: extractor.Decimal, Value: "1.50", Precision: 2},
+			{Type: extractor.Decimal, Value: "2.25", Precision: 2},
+		}}},
+		{RecordType, extractor.Data{Type: extractor.Record, Fields: map[string]extractor.Data{
+			"price":  {Type: extractor.Decimal, Value: "30125.5", Precision: 1},
+			"volume": {Type: extractor.Uint256, Value: "1000"},
+			"pair":   {Type: extractor.String, Value: "BTC/USD"},
+		}}},
+	} {
+		b, err := Encode(&c.data, c.t)
+		if err != nil {
+			t.Errorf("case %d: %v", i, err)
+			continue
+		}
+		decoded, err := Decode(b, c.t)
+		if err != nil {
+			t.Errorf("case %d: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(*decoded, c.data) {
+			t.Errorf("case %d: expected %+v, got %+v", i, c.data, *decoded)
+		}
+	}
+}
+
+func TestEncodingErrors(t *testing.T) {
+	for i, c := range []struct {
+		t    ExtractorType
+		data extractor.Data
+		err  error
+	}{
+		{DecimalType, extractor.Data{Value: "1.234", Precision: 2}, ErrInvalidDecimal},
+		{DecimalType, extractor.Data{Value: "1.", Precision: 2}, ErrInvalidDecimal},
+		{DecimalType, extractor.Data{Value: "1e3", Precision: 2}, ErrInvalidDecimal},
+		{Uint256Type, extractor.Data{Value: "-1"}, ErrValueOutOfRange},
+		{Uint256Type, extractor.Data{Value: "115792089237316195423570985008687907853269984665640564039457584007913129639936"}, ErrValueOutOfRange},
+		{Uint256Type, extractor.Data{Value: "0x10"}, ErrInvalidValue},
+		{ArrayType, extractor.Data{Items: []extractor.Data{
+			{Type: extractor.Int64, Value: "1"},
+			{Type: extractor.String, Value: "1"},
+		}}, ErrMixedItemTypes},
+		{ExtractorType(42), extractor.Data{}, ErrUnknownExtractorType},
+	} {
+		if _, err := Encode(&c.data, c.t); err != c.err {
+			t.Errorf("case %d: expected %v, got %v", i, c.err, err)
+		}
+	}
+
+	if _, err := Decode([]byte{1, 2, 3}, Int64Type); err != ErrInvalidValue {
+		t.Errorf("short int64: expected %v, got %v", ErrInvalidValue, err)
+	}
+
+	// The fields of a record must be sorted by name.
+	b, err := recordArgs.Pack([]string{"b", "a"}, []uint8{uint8(StringType), uint8(StringType)}, [][]byte{[]byte("x"), []byte("y")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(b, RecordType); err != ErrNonCanonical {
+		t.Errorf("unsorted record: expected %v, got %v", ErrNonCanonical, err)
+	}
+}
+
+func TestCheckPrecision(t *testing.T) {
+	for i, c := range []struct {
+		data extractor.Data
+		err  error
+	}{
+		{extractor.Data{Type: extractor.Decimal, Value: "1.50", Precision: 2}, nil},
+		{extractor.Data{Type: extractor.Decimal, Value: "1.500", Precision: 3}, ErrPrecisionMismatch},
+		{extractor.Data{Type: extractor.Int64, Value: "1"}, nil},
+		{extractor.Data{Type: extractor.Array, Items: []extractor.Data{
+			{Type: extractor.Decimal, Value: "1.5", Precision: 1},
+		}}, ErrPrecisionMismatch},
+		{extractor.Data{Type: extractor.Record, Fields: map[string]extractor.Data{
+			"price": {Type: extractor.Decimal, Value: "1.50", Precision: 2},
+			"pair":  {Type: extractor.String, Value: "BTC/USD"},
+		}}, nil},
+	} {
+		if err := CheckPrecision(&c.data, 2); err != c.err {
+			t.Errorf("case %d: expected %v, got %v", i, c.err, err)
+		}
+	}
+}
@@ -15,6 +15,10 @@ const (
 	Int64Type ExtractorType = iota
 	StringType
 	BytesType
+	DecimalType
+	Uint256Type
+	ArrayType
+	RecordType
 )
 
 func ParseExtractorType(extractorType string) (ExtractorType, error) {
@@ -25,6 +29,14 @@ func ParseExtractorType(extractorType string) (ExtractorType, error) {
 		return StringType, nil
 	case "bytes":
 		return BytesType, nil
+	case "decimal":
+		return DecimalType, nil
+	case "uint256":
+		return Uint256Type, nil
+	case "array":
+		return ArrayType, nil
+	case "record":
+		return RecordType, nil
 	default:
 		return 0, ErrParseExtractorType
 	}
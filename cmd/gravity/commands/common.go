@@ -4,6 +4,7 @@ const (
 	HomeFlag = "home"
 
 	DbDir                  = "db"
+	SnapshotsDir           = "snapshots"
 	PrivKeysConfigFileName = "privKey.json"
 	GenesisFileName        = "genesis.json"
 	LedgerConfigFileName   = "config.json"
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"path"
@@ -13,6 +14,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/tendermint/tendermint/privval"
 
 	"github.com/tendermint/tendermint/crypto"
@@ -31,8 +33,10 @@ import (
 	"github.com/Gravity-Tech/gravity-core/common/adaptors"
 	"github.com/Gravity-Tech/gravity-core/ledger/app"
 	"github.com/Gravity-Tech/gravity-core/ledger/scheduler"
+	"github.com/Gravity-Tech/gravity-core/ledger/snapshot"
 	"github.com/dgraph-io/badger"
 	"github.com/tendermint/tendermint/libs/log"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
 	"github.com/tendermint/tendermint/types"
 
 	cfg "github.com/tendermint/tendermint/config"
@@ -54,6 +58,10 @@ const (
 	PrivateRPCHostFlag            = "rpc"
 	NetworkFlag                   = "network"
 	BootstrapUrlFlag              = "bootstrap"
+	StateSyncFlag                 = "statesync"
+	StateSyncTrustHeightFlag      = "statesync-trust-height"
+	StateSyncTrustHashFlag        = "statesync-trust-hash"
+	StateSyncTrustPeriodFlag      = "statesync-trust-period"
 
 	Custom Network = "custom"
 	DevNet Network = "devnet"
@@ -74,6 +82,10 @@ var (
 		IsFastSync: true,
 		Mempool:    cfg.DefaultMempoolConfig(),
 		Details:    (&config.ValidatorDetails{}).DefaultNew(),
+
+		SnapshotInterval:   config.DefaultSnapshotInterval,
+		SnapshotKeepRecent: config.DefaultSnapshotKeepRecent,
+
 		Adapters: map[string]config.AdaptorsConfig{
 			account.Ethereum.String(): {
 				NodeUrl:                "https://ropsten.infura.io/v3/598efca7168947c6a186e2f85b600be1",
@@ -90,12 +102,12 @@ var (
 		ConsulsCount: 5,
 		GenesisTime:  time.Unix(1599142244, 0),
 		ChainID:      string(DevNetId),
-		Block: types.BlockParams{
+		Block: tmproto.BlockParams{
 			MaxBytes:   1048576,
 			MaxGas:     -1,
 			TimeIotaMs: 1000,
 		},
-		Evidence: types.EvidenceParams{
+		Evidence: tmproto.EvidenceParams{
 			MaxAgeNumBlocks: 100000,
 			MaxAgeDuration:  1728 * time.Second,
 		},
@@ -123,20 +135,22 @@ var (
 				"waves":    "E5gz7aTwjjbCbFMYmstvcvb6NvoZyZcQSt1wp68qMpBg",
 			},
 		},
+		ChainTypes: []config.ChainTypeConfig{config.BinanceSmartChain},
 	}
 
 	CustomNetGenesis = config.Genesis{
 		GenesisTime: time.Now(),
 		ChainID:     string(CustomId),
-		Block: types.BlockParams{
+		Block: tmproto.BlockParams{
 			MaxBytes:   1048576,
 			MaxGas:     -1,
 			TimeIotaMs: 1000,
 		},
-		Evidence: types.EvidenceParams{
+		Evidence: tmproto.EvidenceParams{
 			MaxAgeNumBlocks: 100000,
 			MaxAgeDuration:  1728 * time.Second,
 		},
+		ChainTypes: []config.ChainTypeConfig{config.BinanceSmartChain},
 	}
 )
 
@@ -173,6 +187,23 @@ var (
 						Name:  BootstrapUrlFlag,
 						Value: DefaultBootstrapUrl,
 					},
+					&cli.StringFlag{
+						Name:  StateSyncFlag,
+						Usage: "Comma separated public RPC servers to bootstrap the state from their snapshots",
+					},
+					&cli.Int64Flag{
+						Name:  StateSyncTrustHeightFlag,
+						Usage: "Height of a trusted ledger header for state sync",
+					},
+					&cli.StringFlag{
+						Name:  StateSyncTrustHashFlag,
+						Usage: "Hash of the trusted ledger header for state sync",
+					},
+					&cli.DurationFlag{
+						Name:  StateSyncTrustPeriodFlag,
+						Value: cfg.DefaultStateSyncConfig().TrustPeriod,
+						Usage: "Trust period of the light client used by state sync",
+					},
 				},
 			},
 		},
@@ -320,6 +351,11 @@ func startLedger(ctx *cli.Context) error {
 	}
 	defer db.Close()
 
+	snapshots, err := snapshot.NewStore(path.Join(home, SnapshotsDir), snapshot.DefaultChunkSize)
+	if err != nil {
+		return err
+	}
+
 	var privKeysCfg config.Keys
 	err = config.ParseConfig(path.Join(home, PrivKeysConfigFileName), &privKeysCfg)
 	if err != nil {
@@ -350,6 +386,17 @@ func startLedger(ctx *cli.Context) error {
 	tConfig.Mempool = ledgerConf.Mempool
 	tConfig.FastSyncMode = ledgerConf.IsFastSync
 	tConfig.RPC = ledgerConf.RPC
+	if ledgerConf.Instrumentation != nil {
+		tConfig.Instrumentation = ledgerConf.Instrumentation
+	}
+
+	if stateSyncServers := ctx.String(StateSyncFlag); stateSyncServers != "" {
+		tConfig.StateSync.Enable = true
+		tConfig.StateSync.RPCServers = strings.Split(stateSyncServers, ",")
+		tConfig.StateSync.TrustHeight = ctx.Int64(StateSyncTrustHeightFlag)
+		tConfig.StateSync.TrustHash = strings.TrimPrefix(ctx.String(StateSyncTrustHashFlag), "0x")
+		tConfig.StateSync.TrustPeriod = ctx.Duration(StateSyncTrustPeriodFlag)
+	}
 
 	tConfig.RootDir = home
 	tConfig.Consensus.RootDir = home
@@ -360,15 +407,15 @@ func startLedger(ctx *cli.Context) error {
 		return fmt.Errorf("failed to parse log level: %w", err)
 	}
 
-	var ledgerPrivKey ed25519.PrivKeyEd25519
 	ledgerPrivKeyBytes, err := hexutil.Decode(privKeysCfg.Validator.PrivKey)
 	if err != nil {
 		return err
 	}
-	copy(ledgerPrivKey[:], ledgerPrivKeyBytes)
+	ledgerPrivKey := make(ed25519.PrivKey, ed25519.PrivateKeySize)
+	copy(ledgerPrivKey, ledgerPrivKeyBytes)
 
 	var ledgerPubKey account.ConsulPubKey
-	ledgerPubKeyBytes := ledgerPrivKey.PubKey().Bytes()[5:]
+	ledgerPubKeyBytes := ledgerPrivKey.PubKey().Bytes()
 	copy(ledgerPubKey[:], ledgerPubKeyBytes)
 
 	ledgerValidator := &account.LedgerValidator{
@@ -376,7 +423,12 @@ func startLedger(ctx *cli.Context) error {
 		PubKey:  ledgerPubKey,
 	}
 
-	gravityApp, err := createApp(db, ledgerValidator, privKeysCfg.TargetChains, ledgerConf, genesis, bootstrap, tConfig.RPC.ListenAddress, sysCtx)
+	appMetrics, schedulerMetrics := app.NopMetrics(), scheduler.NopMetrics()
+	if ledgerConf.MetricsListenAddress != "" {
+		appMetrics, schedulerMetrics = app.PrometheusMetrics(MetricsNamespace), scheduler.PrometheusMetrics(MetricsNamespace)
+	}
+
+	gravityApp, err := createApp(db, snapshots, ledgerValidator, privKeysCfg.TargetChains, ledgerConf, genesis, bootstrap, tConfig.RPC.ListenAddress, appMetrics, schedulerMetrics, sysCtx)
 	if err != nil {
 		return fmt.Errorf("failed to parse gravity config: %w", err)
 	}
@@ -390,7 +442,7 @@ func startLedger(ctx *cli.Context) error {
 		}
 
 		validators = append(validators, types.GenesisValidator{
-			PubKey: ed25519.PubKeyEd25519(pubKey),
+			PubKey: ed25519.PubKey(pubKey[:]),
 			Power:  int64(v),
 		})
 	}
@@ -411,10 +463,10 @@ func startLedger(ctx *cli.Context) error {
 			return &types.GenesisDoc{
 				GenesisTime: genesis.GenesisTime,
 				ChainID:     genesis.ChainID,
-				ConsensusParams: &types.ConsensusParams{
+				ConsensusParams: &tmproto.ConsensusParams{
 					Block:    genesis.Block,
 					Evidence: genesis.Evidence,
-					Validator: types.ValidatorParams{
+					Validator: tmproto.ValidatorParams{
 						PubKeyTypes: []string{"ed25519"},
 					},
 				},
@@ -448,6 +500,20 @@ func startLedger(ctx *cli.Context) error {
 	}
 	go rpc.ListenRpcServer(rpcConfig)
 
+	if ledgerConf.MetricsListenAddress != "" {
+		metricsServer := &http.Server{
+			Addr:    ledgerConf.MetricsListenAddress,
+			Handler: promhttp.Handler(),
+		}
+		go func() {
+			err := metricsServer.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				logger.Error("Metrics listener", "err", err)
+			}
+		}()
+		defer metricsServer.Close()
+	}
+
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
@@ -455,7 +521,12 @@ func startLedger(ctx *cli.Context) error {
 	return nil
 }
 
-func createApp(db *badger.DB, ledgerValidator *account.LedgerValidator, privKeys map[string]config.Key, cfg config.LedgerConfig, genesisCfg config.Genesis, bootstrap string, localHost string, ctx context.Context) (*app.GHApplication, error) {
+func createApp(db *badger.DB, snapshots *snapshot.Store, ledgerValidator *account.LedgerValidator, privKeys map[string]config.Key, cfg config.LedgerConfig, genesisCfg config.Genesis, bootstrap string, localHost string, appMetrics *app.Metrics, schedulerMetrics *scheduler.Metrics, ctx context.Context) (*app.GHApplication, error) {
+	err := config.RegisterChainTypes(genesisCfg.ChainTypes)
+	if err != nil {
+		return nil, err
+	}
+
 	bAdaptors := make(map[account.ChainType]adaptors.IBlockchainAdaptor)
 	for k, v := range cfg.Adapters {
 		chainType, err := account.ParseChainType(k)
@@ -468,24 +539,17 @@ func createApp(db *badger.DB, ledgerValidator *account.LedgerValidator, privKeys
 			return nil, err
 		}
 
-		var adaptor adaptors.IBlockchainAdaptor
-
-		switch chainType {
-		case account.Binance:
-			adaptor, err = adaptors.NewBinanceAdaptor(privKey, v.NodeUrl, ctx, adaptors.WithBinanceGravityContract(v.GravityContractAddress))
-			if err != nil {
-				return nil, err
-			}
-		case account.Ethereum:
-			adaptor, err = adaptors.NewEthereumAdaptor(privKey, v.NodeUrl, ctx, adaptors.WithEthereumGravityContract(v.GravityContractAddress))
-			if err != nil {
-				return nil, err
-			}
-		case account.Waves:
-			adaptor, err = adaptors.NewWavesAdapter(privKey, v.NodeUrl, v.ChainId[0], adaptors.WithWavesGravityContract(v.GravityContractAddress))
-			if err != nil {
-				return nil, err
-			}
+		adaptor, err := adaptors.New(adaptors.Config{
+			ChainType:              chainType,
+			PrivKey:                privKey,
+			NodeUrl:                v.NodeUrl,
+			ChainId:                v.ChainId,
+			GravityContractAddress: v.GravityContractAddress,
+			GasPrice:               v.GasPrice,
+			GasPriceMultiplier:     v.GasPriceMultiplier,
+		}, ctx)
+		if err != nil {
+			return nil, err
 		}
 
 		bAdaptors[chainType] = adaptor
@@ -496,12 +560,17 @@ func createApp(db *badger.DB, ledgerValidator *account.LedgerValidator, privKeys
 			}
 		}
 	}
-	blockScheduler, err := scheduler.New(bAdaptors, ledgerValidator, localHost, ctx)
+	localClient, err := gravity.New(localHost)
+	if err != nil {
+		return nil, err
+	}
+	blockScheduler, err := scheduler.New(bAdaptors, ledgerValidator, localClient, ctx, scheduler.WithMetrics(schedulerMetrics))
 	if err != nil {
 		return nil, err
 	}
 
 	genesis := app.Genesis{
+		ChainId:                   genesisCfg.ChainID,
 		ConsulsCount:              genesisCfg.ConsulsCount,
 		OraclesAddressByValidator: make(map[account.ConsulPubKey][]app.OraclesAddresses),
 	}
@@ -529,7 +598,7 @@ func createApp(db *badger.DB, ledgerValidator *account.LedgerValidator, privKeys
 		}
 	}
 
-	application, err := app.NewGHApplication(bAdaptors, blockScheduler, db, &genesis, ctx, &cfg)
+	application, err := app.NewGHApplication(blockScheduler, db, snapshots, &genesis, &cfg, app.WithMetrics(appMetrics))
 	if err != nil {
 		return nil, err
 	}
@@ -552,21 +621,12 @@ func setOraclePubKey(bootstrapUrl string, pubKey account.ConsulPubKey, privKey c
 		return nil
 	}
 
-	tx, err := transactions.New(pubKey, transactions.AddOracle, privKey)
-	if err != nil {
-		return err
-	}
-
-	tx.AddValues([]transactions.Value{
-		transactions.BytesValue{
-			Value: []byte{byte(chainType)},
-		},
-		transactions.BytesValue{
-			Value: oracle[:],
-		},
+	tx := transactions.New(pubKey, &transactions.AddOracleArgs{
+		ChainType:    chainType,
+		OraclePubKey: oracle,
 	})
 
-	err = gravityClient.SendTx(tx)
+	err = gravityClient.SendTx(tx, privKey)
 	if err != nil {
 		return err
 	}
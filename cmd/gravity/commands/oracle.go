@@ -5,23 +5,34 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"os/signal"
 	"path"
 	"syscall"
+	"time"
 
-	"github.com/ethereum/go-ethereum/common/hexutil"
-
-	"github.com/Gravity-Tech/gravity-core/common/account"
 	"github.com/Gravity-Tech/gravity-core/config"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor/multisource"
 	"github.com/Gravity-Tech/gravity-core/oracle/node"
+	"github.com/Gravity-Tech/gravity-core/oracle/supervisor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/urfave/cli/v2"
 )
 
 const (
-	ConfigFlag = "config"
+	ConfigFlag         = "config"
+	ReloadIntervalFlag = "reload-interval"
+	MetricsListenFlag  = "metrics-listen"
 
 	DefaultNebulaeDir = "nebulae"
+	DefaultJournalDir = "journal"
+
+	DefaultReloadInterval = time.Minute
+
+	// MetricsNamespace prefixes the prometheus metrics of the ledger and the
+	// oracle.
+	MetricsNamespace = "gravity"
 )
 
 var (
@@ -40,9 +51,20 @@ var (
 			{
 				Name:        "start",
 				Usage:       "Start oracle node",
-				Description: "",
+				Description: "Runs the nebulae of the nebulae dir, or only the given ones. The dir is reloaded every reload interval and on SIGHUP.",
 				Action:      startOracle,
-				ArgsUsage:   "<nebulaId>",
+				ArgsUsage:   "[nebulaId...]",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  ReloadIntervalFlag,
+						Value: DefaultReloadInterval,
+						Usage: "Interval of the nebulae dir reloads",
+					},
+					&cli.StringFlag{
+						Name:  MetricsListenFlag,
+						Usage: "Address of the listener of the prometheus /metrics and the /healthz check, none when empty",
+					},
+				},
 			},
 		},
 		Flags: []cli.Flag{
@@ -96,70 +118,66 @@ func initOracleConfig(ctx *cli.Context) error {
 
 func startOracle(ctx *cli.Context) error {
 	home := ctx.String(HomeFlag)
-	nebulaIdStr := ctx.Args().First()
-	var cfg config.OracleConfig
-	err := config.ParseConfig(path.Join(home, DefaultNebulaeDir, fmt.Sprintf("%s.json", nebulaIdStr)), &cfg)
-	if err != nil {
-		return err
-	}
 
 	var privKeysCfg config.Keys
-	err = config.ParseConfig(path.Join(home, PrivKeysConfigFileName), &privKeysCfg)
+	err := config.ParseConfig(path.Join(home, PrivKeysConfigFileName), &privKeysCfg)
 	if err != nil {
 		return err
 	}
 
-	validatorPrivKey, err := hexutil.Decode(privKeysCfg.Validator.PrivKey)
+	journal, err := node.OpenJournal(path.Join(home, DefaultJournalDir))
 	if err != nil {
 		return err
 	}
+	defer journal.Close()
 
-	chainType, err := account.ParseChainType(cfg.ChainType)
-	if err != nil {
-		return err
-	}
+	sysCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	nebulaId, err := account.StringToNebulaId(nebulaIdStr, chainType)
+	oracleSupervisor, err := supervisor.New(path.Join(home, DefaultNebulaeDir), &privKeysCfg, journal, sysCtx,
+		supervisor.WithNebulae(ctx.Args().Slice()...),
+		supervisor.WithExtractorMetrics(multisource.PrometheusMetrics(MetricsNamespace)),
+		supervisor.WithNodeMetrics(node.PrometheusMetrics(MetricsNamespace)))
 	if err != nil {
 		return err
 	}
+	defer oracleSupervisor.Stop()
 
-	oracleSecretKey, err := account.StringToPrivKey(privKeysCfg.TargetChains[chainType.String()].PrivKey, chainType)
+	err = oracleSupervisor.Reload()
 	if err != nil {
 		return err
 	}
-
-	var chainId byte
-	if len(cfg.ChainId) > 0 {
-		chainId = cfg.ChainId[0]
+	go oracleSupervisor.Watch(ctx.Duration(ReloadIntervalFlag))
+
+	if metricsListen := ctx.String(MetricsListenFlag); metricsListen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		mux.Handle("/healthz", oracleSupervisor.HealthHandler(supervisor.DefaultHealthTimeout))
+		metricsServer := &http.Server{
+			Addr:    metricsListen,
+			Handler: mux,
+		}
+		go func() {
+			err := metricsServer.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				fmt.Printf("Metrics listener: %s\n", err)
+			}
+		}()
+		defer metricsServer.Close()
 	}
-	sysCtx := context.Background()
-	oracleNode, err := node.New(
-		nebulaId,
-		chainType,
-		chainId,
-		oracleSecretKey,
-		node.NewValidator(validatorPrivKey),
-		cfg.ExtractorUrl,
-		cfg.GravityNodeUrl,
-		cfg.BlocksInterval,
-		cfg.TargetChainNodeUrl,
-		sysCtx)
 
-	if err != nil {
-		return err
-	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range c {
+		if sig != syscall.SIGHUP {
+			break
+		}
 
-	err = oracleNode.Init()
-	if err != nil {
-		return err
+		err := oracleSupervisor.Reload()
+		if err != nil {
+			fmt.Printf("Reload nebulae: %s\n", err)
+		}
 	}
 
-	go oracleNode.Start(sysCtx)
-
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	<-c
-
 	return nil
 }
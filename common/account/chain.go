@@ -2,43 +2,178 @@ package account
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
 )
 
 type ChainType byte
 
+// Ethereum and Waves are built in. Other chain types, bsc among them, are
+// registered from the genesis, see RegisterChainType.
 const (
 	Ethereum ChainType = iota
 	Waves
-	Binance
+)
+
+// ChainKind is the family of a target chain. Chains of the same kind share
+// codecs and adaptors, see RegisterChainKind.
+type ChainKind byte
+
+const (
+	EVMChain ChainKind = iota
+	WavesChain
+
+	UnknownChain ChainKind = 0xff
 )
 
 var (
 	ErrInvalidChainType = errors.New("invalid chain type")
 	ErrParseChainType   = errors.New("invalid parse chain type")
+	ErrParseChainKind   = errors.New("invalid parse chain kind")
+	ErrChainTypeIsExist = errors.New("chain type is exist")
+	ErrChainKindIsExist = errors.New("chain kind is exist")
+)
+
+type chainInfo struct {
+	name string
+	kind ChainKind
+}
+
+type kindInfo struct {
+	name   string
+	codecs Codecs
+}
+
+var (
+	chainsLock sync.RWMutex
+	chains     = map[ChainType]chainInfo{
+		Ethereum: {name: "ethereum", kind: EVMChain},
+		Waves:    {name: "waves", kind: WavesChain},
+	}
+	kinds = make(map[ChainKind]kindInfo)
 )
 
+// RegisterChainKind adds a chain family together with the codecs of its
+// keys, addresses and nebula ids. Chain packages call it from init.
+func RegisterChainKind(kind ChainKind, name string, codecs Codecs) error {
+	name = strings.ToLower(name)
+
+	chainsLock.Lock()
+	defer chainsLock.Unlock()
+
+	for k, v := range kinds {
+		if k == kind || v.name == name {
+			return ErrChainKindIsExist
+		}
+	}
+
+	kinds[kind] = kindInfo{name: name, codecs: codecs}
+	return nil
+}
+
+// RegisterChainType adds a target chain that is not built in. All ledger
+// nodes must register the same chain types, so they come from the genesis.
+func RegisterChainType(chainType ChainType, name string, kind ChainKind) error {
+	name = strings.ToLower(name)
+
+	chainsLock.Lock()
+	defer chainsLock.Unlock()
+
+	if _, ok := kinds[kind]; !ok {
+		return ErrParseChainKind
+	}
+
+	for k, v := range chains {
+		if k == chainType || v.name == name {
+			if k == chainType && v.name == name && v.kind == kind {
+				return nil
+			}
+			return ErrChainTypeIsExist
+		}
+	}
+
+	chains[chainType] = chainInfo{name: name, kind: kind}
+	return nil
+}
+
 func ParseChainType(chainType string) (ChainType, error) {
-	switch strings.ToLower(chainType) {
-	case "bsc":
-		return Binance, nil
-	case "ethereum":
-		return Ethereum, nil
-	case "waves":
-		return Waves, nil
-	default:
-		return 0, ErrParseChainType
+	chainType = strings.ToLower(chainType)
+
+	chainsLock.RLock()
+	defer chainsLock.RUnlock()
+
+	for k, v := range chains {
+		if v.name == chainType {
+			return k, nil
+		}
 	}
+
+	return 0, ErrParseChainType
 }
 func (ch ChainType) String() string {
-	switch ch {
-	case Ethereum:
-		return "ethereum"
-	case Waves:
-		return "waves"
-	case Binance:
-		return "bsc"
-	default:
-		return "ethereum"
+	chainsLock.RLock()
+	defer chainsLock.RUnlock()
+
+	info, ok := chains[ch]
+	if !ok {
+		return fmt.Sprintf("chain(%d)", byte(ch))
+	}
+
+	return info.name
+}
+
+func (ch ChainType) Kind() ChainKind {
+	chainsLock.RLock()
+	defer chainsLock.RUnlock()
+
+	info, ok := chains[ch]
+	if !ok {
+		return UnknownChain
+	}
+
+	return info.kind
+}
+
+// Codecs returns the codecs of the chain type's kind.
+func (ch ChainType) Codecs() (Codecs, error) {
+	chainsLock.RLock()
+	defer chainsLock.RUnlock()
+
+	info, ok := chains[ch]
+	if !ok {
+		return Codecs{}, ErrInvalidChainType
+	}
+	kind, ok := kinds[info.kind]
+	if !ok {
+		return Codecs{}, ErrInvalidChainType
 	}
+
+	return kind.codecs, nil
+}
+
+func ParseChainKind(kind string) (ChainKind, error) {
+	kind = strings.ToLower(kind)
+
+	chainsLock.RLock()
+	defer chainsLock.RUnlock()
+
+	for k, v := range kinds {
+		if v.name == kind {
+			return k, nil
+		}
+	}
+
+	return 0, ErrParseChainKind
+}
+func (kind ChainKind) String() string {
+	chainsLock.RLock()
+	defer chainsLock.RUnlock()
+
+	info, ok := kinds[kind]
+	if !ok {
+		return "unknown"
+	}
+
+	return info.name
 }
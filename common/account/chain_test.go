@@ -0,0 +1,97 @@
+package account
+
+import "testing"
+
+func TestRegisterChainType(t *testing.T) {
+	const polygon ChainType = 10
+
+	if _, err := ParseChainType("polygon"); err != ErrParseChainType {
+		t.Fatalf("unregistered chain type parsed: %v", err)
+	}
+	if polygon.Kind() != UnknownChain {
+		t.Fatalf("unexpected kind of unregistered chain type: %s", polygon.Kind())
+	}
+
+	if err := RegisterChainType(polygon, "Polygon", EVMChain); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterChainType(polygon, "polygon", EVMChain); err != nil {
+		t.Errorf("repeated registration: %v", err)
+	}
+	if err := RegisterChainType(polygon, "avalanche", EVMChain); err != ErrChainTypeIsExist {
+		t.Errorf("reused chain type: expected %v, got %v", ErrChainTypeIsExist, err)
+	}
+	if err := RegisterChainType(11, "ethereum", EVMChain); err != ErrChainTypeIsExist {
+		t.Errorf("reused name: expected %v, got %v", ErrChainTypeIsExist, err)
+	}
+
+	chainType, err := ParseChainType("POLYGON")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chainType != polygon || chainType.String() != "polygon" || chainType.Kind() != EVMChain {
+		t.Errorf("unexpected chain type: %d %s %s", chainType, chainType, chainType.Kind())
+	}
+
+	pubKey := OraclesPubKey{2, 1}
+	if pubKey.ToString(polygon) != pubKey.ToString(Ethereum) {
+		t.Error("EVM chain types format keys differently")
+	}
+}
+
+type testCodec struct{}
+
+func (testCodec) PrivKeyFromString(value string) ([]byte, error) { return []byte(value), nil }
+func (testCodec) PubKeyFromBytes(value []byte) (OraclesPubKey, error) {
+	var pubKey OraclesPubKey
+	copy(pubKey[:], value)
+	return pubKey, nil
+}
+func (testCodec) PubKeyToBytes(pubKey OraclesPubKey) []byte { return pubKey[:4] }
+func (testCodec) Encode(value []byte) string                { return string(value) }
+func (testCodec) Decode(value string) ([]byte, error)       { return []byte(value), nil }
+
+func TestRegisterChainKind(t *testing.T) {
+	const (
+		kind      ChainKind = 7
+		chainType ChainType = 20
+	)
+
+	if err := RegisterChainType(chainType, "test", kind); err != ErrParseChainKind {
+		t.Fatalf("chain type of unknown kind: expected %v, got %v", ErrParseChainKind, err)
+	}
+
+	codecs := Codecs{Key: testCodec{}, Address: testCodec{}, NebulaId: AddressNebulaId(4)}
+	if err := RegisterChainKind(kind, "test", codecs); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterChainKind(kind, "other", codecs); err != ErrChainKindIsExist {
+		t.Errorf("reused kind: expected %v, got %v", ErrChainKindIsExist, err)
+	}
+	if err := RegisterChainType(chainType, "test", kind); err != nil {
+		t.Fatal(err)
+	}
+
+	nebulaId, err := StringToNebulaId("abcd", chainType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nebulaId.ToString(chainType) != "abcd" || nebulaId[NebulaIdLength-1] != 'd' {
+		t.Errorf("unexpected nebula id: %x", nebulaId)
+	}
+
+	pubKey, err := StringToOraclePubKey("wxyz", chainType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pubKey.ToString(chainType) != "wxyz" {
+		t.Errorf("unexpected public key: %s", pubKey.ToString(chainType))
+	}
+	if Verify(chainType, pubKey, nil, nil) {
+		t.Error("chain kind without verifier accepted a signature")
+	}
+
+	if _, err := StringToNebulaId("abcd", 21); err != ErrInvalidChainType {
+		t.Errorf("unregistered chain type: expected %v, got %v", ErrInvalidChainType, err)
+	}
+}
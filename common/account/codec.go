@@ -0,0 +1,53 @@
+package account
+
+import "errors"
+
+var (
+	ErrInvalidAddress = errors.New("invalid address")
+	ErrInvalidPubKey  = errors.New("invalid public key")
+)
+
+// Codecs converts the keys, addresses and nebula ids of one chain kind.
+type Codecs struct {
+	Key      KeyCodec
+	Address  AddressCodec
+	NebulaId NebulaIdCodec
+	Verifier SignVerifier
+}
+
+// KeyCodec parses target chain keys and packs public keys into
+// OraclesPubKey.
+type KeyCodec interface {
+	PrivKeyFromString(value string) ([]byte, error)
+	PubKeyFromBytes(value []byte) (OraclesPubKey, error)
+	PubKeyToBytes(pubKey OraclesPubKey) []byte
+}
+
+// AddressCodec converts addresses and public keys between bytes and their
+// text form on the target chain.
+type AddressCodec interface {
+	Encode(value []byte) string
+	Decode(value string) ([]byte, error)
+}
+
+// NebulaIdCodec converts nebula contract addresses to and from NebulaId.
+type NebulaIdCodec interface {
+	FromBytes(value []byte) NebulaId
+	ToBytes(id NebulaId) []byte
+}
+
+// SignVerifier checks a signature made with an oracle's target chain key.
+type SignVerifier interface {
+	Verify(pubKey OraclesPubKey, msg []byte, sign []byte) bool
+}
+
+// AddressNebulaId keeps addresses of a fixed length right aligned in a
+// NebulaId.
+type AddressNebulaId int
+
+func (length AddressNebulaId) FromBytes(value []byte) NebulaId {
+	return BytesToNebulaId(value)
+}
+func (length AddressNebulaId) ToBytes(id NebulaId) []byte {
+	return id[NebulaIdLength-int(length):]
+}
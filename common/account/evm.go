@@ -0,0 +1,56 @@
+package account
+
+import (
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+const EvmPubKeyLength = 33
+
+func init() {
+	err := RegisterChainKind(EVMChain, "evm", Codecs{
+		Key:      evmKeyCodec{},
+		Address:  hexCodec{},
+		NebulaId: AddressNebulaId(EthereumAddressLength),
+		Verifier: evmVerifier{},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type evmKeyCodec struct{}
+
+func (evmKeyCodec) PrivKeyFromString(value string) ([]byte, error) {
+	return hexutil.Decode(value)
+}
+func (evmKeyCodec) PubKeyFromBytes(value []byte) (OraclesPubKey, error) {
+	var pubKey OraclesPubKey
+	if len(value) < EvmPubKeyLength {
+		return pubKey, ErrInvalidPubKey
+	}
+	copy(pubKey[:], value[:EvmPubKeyLength])
+	return pubKey, nil
+}
+func (evmKeyCodec) PubKeyToBytes(pubKey OraclesPubKey) []byte {
+	return pubKey[:EvmPubKeyLength]
+}
+
+type hexCodec struct{}
+
+func (hexCodec) Encode(value []byte) string {
+	return hexutil.Encode(value)
+}
+func (hexCodec) Decode(value string) ([]byte, error) {
+	return hexutil.Decode(value)
+}
+
+// evmVerifier checks 65 byte recoverable secp256k1 signatures.
+type evmVerifier struct{}
+
+func (evmVerifier) Verify(pubKey OraclesPubKey, msg []byte, sign []byte) bool {
+	if len(sign) != ethcrypto.SignatureLength || sign[ethcrypto.RecoveryIDOffset] > 1 {
+		return false
+	}
+	return ethcrypto.VerifySignature(pubKey[:EvmPubKeyLength], msg, sign[:ethcrypto.RecoveryIDOffset])
+}
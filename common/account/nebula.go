@@ -1,11 +1,5 @@
 package account
 
-import (
-	"github.com/btcsuite/btcutil/base58"
-	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/wavesplatform/gowaves/pkg/crypto"
-)
-
 const (
 	NebulaIdLength        = 32
 	EthereumAddressLength = 20
@@ -16,21 +10,17 @@ const (
 type NebulaId [NebulaIdLength]byte
 
 func StringToNebulaId(address string, chainType ChainType) (NebulaId, error) {
-	var nebula NebulaId
+	codecs, err := chainType.Codecs()
+	if err != nil {
+		return NebulaId{}, err
+	}
 
-	switch chainType {
-	case Ethereum, Binance:
-		nebulaBytes, err := hexutil.Decode(address)
-		if err != nil {
-			return NebulaId{}, err
-		}
-		nebula = BytesToNebulaId(nebulaBytes)
-	case Waves:
-		nebulaBytes := crypto.MustBytesFromBase58(address)
-		nebula = BytesToNebulaId(nebulaBytes)
+	nebulaBytes, err := codecs.Address.Decode(address)
+	if err != nil {
+		return NebulaId{}, err
 	}
 
-	return nebula, nil
+	return codecs.NebulaId.FromBytes(nebulaBytes), nil
 }
 func BytesToNebulaId(value []byte) NebulaId {
 	var idBytes []byte
@@ -45,25 +35,18 @@ func BytesToNebulaId(value []byte) NebulaId {
 }
 
 func (id NebulaId) ToString(chainType ChainType) string {
-	nebula := id.ToBytes(chainType)
-	switch chainType {
-	case Ethereum, Binance:
-		return hexutil.Encode(nebula[:])
-	case Waves:
-		return base58.Encode(nebula[:])
+	codecs, err := chainType.Codecs()
+	if err != nil {
+		return ""
 	}
 
-	return ""
+	return codecs.Address.Encode(codecs.NebulaId.ToBytes(id))
 }
 func (id NebulaId) ToBytes(chainType ChainType) []byte {
-	switch chainType {
-	case Binance:
-		return id[NebulaIdLength-BSCAddressLength:]
-	case Ethereum:
-		return id[NebulaIdLength-EthereumAddressLength:]
-	case Waves:
-		return id[NebulaIdLength-WavesAddressLength:]
+	codecs, err := chainType.Codecs()
+	if err != nil {
+		return nil
 	}
 
-	return nil
+	return codecs.NebulaId.ToBytes(id)
 }
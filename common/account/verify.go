@@ -0,0 +1,12 @@
+package account
+
+// Verify checks a signature made by an oracle with its target chain key,
+// using the verifier of the chain type's kind.
+func Verify(chainType ChainType, pubKey OraclesPubKey, msg []byte, sign []byte) bool {
+	codecs, err := chainType.Codecs()
+	if err != nil || codecs.Verifier == nil {
+		return false
+	}
+
+	return codecs.Verifier.Verify(pubKey, msg, sign)
+}
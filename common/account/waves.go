@@ -0,0 +1,71 @@
+package account
+
+import (
+	"github.com/btcsuite/btcutil/base58"
+	wavesplatform "github.com/wavesplatform/go-lib-crypto"
+	"github.com/wavesplatform/gowaves/pkg/crypto"
+)
+
+func init() {
+	err := RegisterChainKind(WavesChain, "waves", Codecs{
+		Key:      wavesKeyCodec{},
+		Address:  base58Codec{},
+		NebulaId: AddressNebulaId(WavesAddressLength),
+		Verifier: wavesVerifier{},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type wavesKeyCodec struct{}
+
+func (wavesKeyCodec) PrivKeyFromString(value string) ([]byte, error) {
+	wCrypto := wavesplatform.NewWavesCrypto()
+	seed := wavesplatform.Seed(value)
+	secret, err := crypto.NewSecretKeyFromBase58(string(wCrypto.PrivateKey(seed)))
+	if err != nil {
+		return nil, err
+	}
+
+	return secret.Bytes(), nil
+}
+func (wavesKeyCodec) PubKeyFromBytes(value []byte) (OraclesPubKey, error) {
+	var pubKey OraclesPubKey
+	if len(value) < crypto.PublicKeySize {
+		return pubKey, ErrInvalidPubKey
+	}
+	copy(pubKey[1:], value[:crypto.PublicKeySize])
+	return pubKey, nil
+}
+func (wavesKeyCodec) PubKeyToBytes(pubKey OraclesPubKey) []byte {
+	return pubKey[1:]
+}
+
+type base58Codec struct{}
+
+func (base58Codec) Encode(value []byte) string {
+	return base58.Encode(value)
+}
+func (base58Codec) Decode(value string) ([]byte, error) {
+	b := base58.Decode(value)
+	if len(b) == 0 {
+		return nil, ErrInvalidAddress
+	}
+	return b, nil
+}
+
+// wavesVerifier checks curve25519 signatures.
+type wavesVerifier struct{}
+
+func (wavesVerifier) Verify(pubKey OraclesPubKey, msg []byte, sign []byte) bool {
+	signature, err := crypto.NewSignatureFromBytes(sign)
+	if err != nil {
+		return false
+	}
+	publicKey, err := crypto.NewPublicKeyFromBytes(pubKey[1:])
+	if err != nil {
+		return false
+	}
+	return crypto.Verify(publicKey, signature, msg)
+}
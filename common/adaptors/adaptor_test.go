@@ -0,0 +1,105 @@
+package adaptors
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testTargetChain is a target chain with a Gravity contract of testKeyCount
+// consuls and a nebula of testKeyCount oracles, both with a bft value of
+// testBft. The scenarios of this file run on every adaptor through it.
+type testTargetChain interface {
+	// sender is the adaptor under test.
+	sender() IBlockchainAdaptor
+	nebula() account.NebulaId
+	// validators is the list that AddPulse looks the results up in.
+	validators(t *testing.T) []account.OraclesPubKey
+	// signResults makes the ledger serve the results of hash of the oracles
+	// at indexes, and no other.
+	signResults(t *testing.T, hash []byte, indexes ...int)
+	// forgeResult adds to the served results one of another hash by the
+	// oracle at index.
+	forgeResult(t *testing.T, index int)
+	// confirm waits for the tx and checks that it succeeded.
+	confirm(t *testing.T, txId string)
+	pulseHash(t *testing.T, pulseId uint64) []byte
+}
+
+func testTargetChains() []struct {
+	name     string
+	newChain func(t *testing.T) testTargetChain
+} {
+	return []struct {
+		name     string
+		newChain func(t *testing.T) testTargetChain
+	}{
+		{"evm", func(t *testing.T) testTargetChain { return newEvmTestChain(t) }},
+		{"waves", func(t *testing.T) testTargetChain { return newWavesTestChain(t) }},
+	}
+}
+
+// addTestPulse sends pulse 1 of hash with the results of three oracles.
+func addTestPulse(t *testing.T, chain testTargetChain, hash []byte) string {
+	chain.signResults(t, hash, 4, 0, 2)
+	txId, err := chain.sender().AddPulse(chain.nebula(), 1, chain.validators(t), hash, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txId == "" {
+		t.Fatal("pulse is not sent")
+	}
+	chain.confirm(t, txId)
+
+	return txId
+}
+
+func TestAddPulse(t *testing.T) {
+	for _, c := range testTargetChains() {
+		t.Run(c.name, func(t *testing.T) {
+			chain := c.newChain(t)
+			adaptor := chain.sender()
+			ctx := context.Background()
+			hash := crypto.Keccak256([]byte("value"))
+
+			chain.signResults(t, hash, 4, 1)
+			txId, err := adaptor.AddPulse(chain.nebula(), 1, chain.validators(t), hash, ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if txId != "" {
+				t.Fatal("pulse sent below the bft value")
+			}
+
+			// The adaptor does not check the signs, the nebula does.
+			chain.forgeResult(t, 3)
+			if _, err := adaptor.AddPulse(chain.nebula(), 1, chain.validators(t), hash, ctx); err == nil {
+				t.Fatal("nebula accepted a result of another hash")
+			}
+
+			addTestPulse(t, chain, hash)
+
+			lastPulseId, err := adaptor.LastPulseId(chain.nebula(), ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lastPulseId != 1 {
+				t.Fatalf("last pulse id: expected 1, got %d", lastPulseId)
+			}
+			if pulseHash := chain.pulseHash(t, 1); !bytes.Equal(pulseHash, hash) {
+				t.Errorf("pulse hash: expected %x, got %x", hash, pulseHash)
+			}
+
+			txId, err = adaptor.AddPulse(chain.nebula(), 1, chain.validators(t), hash, ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if txId != "" {
+				t.Error("existing pulse sent again")
+			}
+		})
+	}
+}
@@ -0,0 +1,795 @@
+package adaptors
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"github.com/Gravity-Tech/gravity-core/abi"
+	"github.com/Gravity-Tech/gravity-core/abi/ethereum"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/ethereum/go-ethereum/crypto/secp256k1"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	geth "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	Int64   SubType = 0
+	String  SubType = 1
+	Bytes   SubType = 2
+	Decimal SubType = 3
+	Uint256 SubType = 4
+	Array   SubType = 5
+	Record  SubType = 6
+
+	waitTimeout = 240
+
+	DefaultGasPriceMultiplier = 2
+)
+
+var (
+	ErrInvalidChainId  = errors.New("invalid chain id")
+	ErrInvalidGasPrice = errors.New("invalid gas price")
+	ErrHeadsNotPushed  = errors.New("backend does not push new heads")
+)
+
+type SubType uint8
+
+// GasStrategy returns the gas price of an outgoing transaction.
+type GasStrategy func(ctx context.Context, backend bind.ContractTransactor) (*big.Int, error)
+
+// SuggestedGasPrice pays the price suggested by the node times multiplier.
+func SuggestedGasPrice(multiplier int64) GasStrategy {
+	return func(ctx context.Context, backend bind.ContractTransactor) (*big.Int, error) {
+		gasPrice, err := backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return gasPrice.Mul(gasPrice, big.NewInt(multiplier)), nil
+	}
+}
+
+// FixedGasPrice always pays gasPrice.
+func FixedGasPrice(gasPrice *big.Int) GasStrategy {
+	return func(ctx context.Context, backend bind.ContractTransactor) (*big.Int, error) {
+		return new(big.Int).Set(gasPrice), nil
+	}
+}
+
+// EvmBackend is the part of an EVM node the adaptor uses. It is implemented
+// by ethclient.Client and by the simulated backend of go-ethereum.
+type EvmBackend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// headSubscriber is implemented by the backends that push new heads, e.g.
+// ethclient over a websocket and the simulated backend.
+type headSubscriber interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (geth.Subscription, error)
+}
+
+// txReader is implemented by the backends that return a tx by its hash, e.g.
+// ethclient and the simulated backend.
+type txReader interface {
+	TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error)
+}
+
+// EvmAdaptor works with any EVM compatible chain. The chain type selects the
+// ledger records it reads, the chain id is used for EIP-155 signing.
+type EvmAdaptor struct {
+	privKey   *ecdsa.PrivateKey
+	chainType account.ChainType
+	chainId   *big.Int
+
+	ghClient    *gravity.Client
+	backend     EvmBackend
+	gasStrategy GasStrategy
+
+	gravityContract *ethereum.Gravity
+
+	nonces *evmNonces
+}
+
+// evmNonces keeps the next nonce of the key of an adaptor. The nodes of all
+// the nebulae of a chain send their txs through one adaptor.
+type evmNonces struct {
+	sync.Mutex
+	next  uint64
+	known bool
+}
+
+type EvmAdapterOption func(*EvmAdaptor) error
+
+func WithEvmGravityContract(address string) EvmAdapterOption {
+	return func(h *EvmAdaptor) error {
+		hexAddress, err := hexutil.Decode(address)
+		if err != nil {
+			return err
+		}
+		ethContractAddress := common.Address{}
+		ethContractAddress.SetBytes(hexAddress)
+		h.gravityContract, err = ethereum.NewGravity(ethContractAddress, h.backend)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+}
+func EvmAdapterWithGhClient(ghClient *gravity.Client) EvmAdapterOption {
+	return func(h *EvmAdaptor) error {
+		h.ghClient = ghClient
+		return nil
+	}
+}
+
+// WithEvmChainId enables EIP-155 signing. An empty chain id keeps unprotected
+// transactions.
+func WithEvmChainId(chainId string) EvmAdapterOption {
+	return func(h *EvmAdaptor) error {
+		if chainId == "" {
+			return nil
+		}
+
+		id, ok := new(big.Int).SetString(chainId, 10)
+		if !ok || id.Sign() <= 0 {
+			return ErrInvalidChainId
+		}
+		h.chainId = id
+
+		return nil
+	}
+}
+func WithEvmGasStrategy(gasStrategy GasStrategy) EvmAdapterOption {
+	return func(h *EvmAdaptor) error {
+		h.gasStrategy = gasStrategy
+		return nil
+	}
+}
+
+// WithEvmGasPrice pays a fixed gas price in wei if gasPrice is set and the
+// suggested price times multiplier otherwise.
+func WithEvmGasPrice(gasPrice string, multiplier int64) EvmAdapterOption {
+	return func(h *EvmAdaptor) error {
+		if gasPrice != "" {
+			price, ok := new(big.Int).SetString(gasPrice, 10)
+			if !ok || price.Sign() <= 0 {
+				return ErrInvalidGasPrice
+			}
+			h.gasStrategy = FixedGasPrice(price)
+			return nil
+		}
+
+		if multiplier < 0 {
+			return ErrInvalidGasPrice
+		} else if multiplier > 0 {
+			h.gasStrategy = SuggestedGasPrice(multiplier)
+		}
+
+		return nil
+	}
+}
+
+func NewEvmAdaptor(privKey []byte, nodeUrl string, chainType account.ChainType, ctx context.Context, opts ...EvmAdapterOption) (*EvmAdaptor, error) {
+	if chainType.Kind() != account.EVMChain {
+		return nil, account.ErrInvalidChainType
+	}
+
+	ethClient, err := ethclient.DialContext(ctx, nodeUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEvmAdaptorWithBackend(privKey, ethClient, chainType, ctx, opts...)
+}
+
+// NewEvmAdaptorWithBackend returns an adaptor that sends transactions and
+// calls through backend instead of dialing a node.
+func NewEvmAdaptorWithBackend(privKey []byte, backend EvmBackend, chainType account.ChainType, ctx context.Context, opts ...EvmAdapterOption) (*EvmAdaptor, error) {
+	if chainType.Kind() != account.EVMChain {
+		return nil, account.ErrInvalidChainType
+	}
+
+	ethPrivKey := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: secp256k1.S256(),
+		},
+		D: new(big.Int),
+	}
+	ethPrivKey.D.SetBytes(privKey)
+	ethPrivKey.PublicKey.X, ethPrivKey.PublicKey.Y = ethPrivKey.PublicKey.Curve.ScalarBaseMult(privKey)
+
+	adapter := &EvmAdaptor{
+		privKey:     ethPrivKey,
+		chainType:   chainType,
+		backend:     backend,
+		gasStrategy: SuggestedGasPrice(DefaultGasPriceMultiplier),
+		nonces:      &evmNonces{},
+	}
+	for _, opt := range opts {
+		err := opt(adapter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return adapter, nil
+}
+
+func init() {
+	err := Register(account.EVMChain, func(cfg Config, ctx context.Context) (IBlockchainAdaptor, error) {
+		opts := []EvmAdapterOption{
+			WithEvmChainId(cfg.ChainId),
+			WithEvmGasPrice(cfg.GasPrice, cfg.GasPriceMultiplier),
+		}
+		if cfg.GhClient != nil {
+			opts = append(opts, EvmAdapterWithGhClient(cfg.GhClient))
+		}
+		if cfg.GravityContractAddress != "" {
+			opts = append(opts, WithEvmGravityContract(cfg.GravityContractAddress))
+		}
+
+		return NewEvmAdaptor(cfg.PrivKey, cfg.NodeUrl, cfg.ChainType, ctx, opts...)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// transactOpts signs with the chain id if one is configured and prices gas
+// with the adaptor's gas strategy.
+func (adaptor *EvmAdaptor) transactOpts(ctx context.Context) (*bind.TransactOpts, error) {
+	opts := bind.NewKeyedTransactor(adaptor.privKey)
+	if adaptor.chainId != nil {
+		signer := types.NewEIP155Signer(adaptor.chainId)
+		opts.Signer = func(_ types.Signer, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != opts.From {
+				return nil, errors.New("not authorized to sign this account")
+			}
+			signature, err := crypto.Sign(signer.Hash(tx).Bytes(), adaptor.privKey)
+			if err != nil {
+				return nil, err
+			}
+			return tx.WithSignature(signer, signature)
+		}
+	}
+
+	gasPrice, err := adaptor.gasStrategy(ctx, adaptor.backend)
+	if err != nil {
+		return nil, err
+	}
+	opts.GasPrice = gasPrice
+	opts.Context = ctx
+
+	return opts, nil
+}
+
+// transact sends the tx built by send with the next nonce of the key. The txs
+// of an adaptor are sent one at a time, so concurrent pulses do not reuse a
+// nonce. The cached nonce is dropped and read from the pending state again
+// when a tx is not sent.
+func (adaptor *EvmAdaptor) transact(ctx context.Context, send func(opts *bind.TransactOpts) (*types.Transaction, error)) (*types.Transaction, error) {
+	opts, err := adaptor.transactOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nonces := adaptor.nonces
+	nonces.Lock()
+	defer nonces.Unlock()
+
+	if !nonces.known {
+		nonce, err := adaptor.backend.PendingNonceAt(ctx, opts.From)
+		if err != nil {
+			return nil, err
+		}
+		nonces.next, nonces.known = nonce, true
+	}
+
+	opts.Nonce = new(big.Int).SetUint64(nonces.next)
+	tx, err := send(opts)
+	if err != nil {
+		nonces.known = false
+		return nil, err
+	}
+
+	nonces.next++
+	return tx, nil
+}
+
+func (adaptor *EvmAdaptor) GetHeight(ctx context.Context) (uint64, error) {
+	header, err := adaptor.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return header.Number.Uint64(), nil
+}
+
+// SubscribeHeight streams the heights of the new heads. It fails when the
+// backend does not push them, e.g. ethclient over http.
+func (adaptor *EvmAdaptor) SubscribeHeight(ctx context.Context) (<-chan uint64, error) {
+	backend, ok := adaptor.backend.(headSubscriber)
+	if !ok {
+		return nil, ErrHeadsNotPushed
+	}
+
+	headers := make(chan *types.Header)
+	sub, err := backend.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	heights := make(chan uint64, 1)
+	go func() {
+		defer close(heights)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.Err():
+				return
+			case header := <-headers:
+				select {
+				case <-heights:
+				default:
+				}
+				heights <- header.Number.Uint64()
+			}
+		}
+	}()
+
+	return heights, nil
+}
+func (adaptor *EvmAdaptor) Sign(msg []byte) ([]byte, error) {
+	sig, err := crypto.Sign(msg, adaptor.privKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return sig, nil
+}
+func (adaptor *EvmAdaptor) WaitTx(id string, ctx context.Context) error {
+	nCtx, cancel := context.WithTimeout(ctx, waitTimeout*time.Second)
+	defer cancel()
+	queryTicker := time.NewTicker(time.Second * 3)
+	defer queryTicker.Stop()
+
+	hash, err := hexutil.Decode(id)
+	if err != nil {
+		return err
+	}
+
+	var txHash common.Hash
+	copy(txHash[:], hash)
+
+	for {
+		select {
+		case <-nCtx.Done():
+			return errors.New("tx not found")
+		case <-queryTicker.C:
+		}
+		receipt, _ := adaptor.backend.TransactionReceipt(nCtx, txHash)
+		if receipt != nil {
+			return nil
+		}
+	}
+
+}
+
+// TxFee returns the gas used by the tx and its fee in wei, or a nil fee when
+// the backend can not return the tx.
+func (adaptor *EvmAdaptor) TxFee(id string, ctx context.Context) (uint64, *big.Int, error) {
+	hash, err := hexutil.Decode(id)
+	if err != nil {
+		return 0, nil, err
+	}
+	txHash := common.BytesToHash(hash)
+
+	receipt, err := adaptor.backend.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	reader, ok := adaptor.backend.(txReader)
+	if !ok {
+		return receipt.GasUsed, nil, nil
+	}
+	tx, _, err := reader.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return receipt.GasUsed, new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), tx.GasPrice()), nil
+}
+func (adaptor *EvmAdaptor) PubKey() account.OraclesPubKey {
+	pubKey := crypto.CompressPubkey(&adaptor.privKey.PublicKey)
+	oraclePubKey := account.BytesToOraclePubKey(pubKey[:], adaptor.chainType)
+	return oraclePubKey
+}
+func (adaptor *EvmAdaptor) ValueType(nebulaId account.NebulaId, ctx context.Context) (abi.ExtractorType, error) {
+	nebula, err := ethereum.NewNebula(common.BytesToAddress(nebulaId.ToBytes(adaptor.chainType)), adaptor.backend)
+	if err != nil {
+		return 0, err
+	}
+
+	exType, err := nebula.DataType(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return abi.ExtractorType(exType), nil
+}
+
+func (adaptor *EvmAdaptor) AddPulse(nebulaId account.NebulaId, pulseId uint64, validators []account.OraclesPubKey, hash []byte, ctx context.Context) (string, error) {
+	nebula, err := ethereum.NewNebula(common.BytesToAddress(nebulaId.ToBytes(adaptor.chainType)), adaptor.backend)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := nebula.Pulses(nil, big.NewInt(int64(pulseId)))
+	if err != nil {
+		return "", err
+	}
+
+	if bytes.Equal(data.DataHash[:], make([]byte, 32, 32)) != true {
+		return "", nil
+	}
+
+	bft, err := nebula.BftValue(nil)
+	if err != nil {
+		return "", err
+	}
+
+	realSignCount := 0
+
+	oracles, err := nebula.GetOracles(nil)
+	if err != nil {
+		return "", err
+	}
+	var r [5][32]byte
+	var s [5][32]byte
+	var v [5]uint8
+	for _, validator := range validators {
+		pubKey, err := crypto.DecompressPubkey(validator.ToBytes(adaptor.chainType))
+		if err != nil {
+			return "", err
+		}
+		validatorAddress := crypto.PubkeyToAddress(*pubKey)
+		position := 0
+		isExist := false
+		for i, address := range oracles {
+			if validatorAddress == address {
+				position = i
+				isExist = true
+				break
+			}
+		}
+		if !isExist {
+			continue
+		}
+
+		sign, err := adaptor.ghClient.Result(adaptor.chainType, nebulaId, int64(pulseId), validator)
+		if err != nil {
+			r[position] = [32]byte{}
+			s[position] = [32]byte{}
+			v[position] = byte(0)
+			continue
+		}
+		copy(r[position][:], sign[:32])
+		copy(s[position][:], sign[32:64])
+		v[position] = sign[64] + 27
+
+		realSignCount++
+	}
+
+	if realSignCount < int(bft.Uint64()) {
+		return "", nil
+	}
+
+	var resultBytes32 [32]byte
+	copy(resultBytes32[:], hash)
+
+	tx, err := adaptor.transact(ctx, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return nebula.SendHashValue(opts, resultBytes32, v[:], r[:], s[:])
+	})
+	if err != nil {
+		return "", err
+	}
+	return tx.Hash().String(), nil
+}
+func (adaptor *EvmAdaptor) SendValueToSubs(nebulaId account.NebulaId, pulseId uint64, value *extractor.Data, ctx context.Context) error {
+	var err error
+
+	nebula, err := ethereum.NewNebula(common.BytesToAddress(nebulaId.ToBytes(adaptor.chainType)), adaptor.backend)
+	if err != nil {
+		return err
+	}
+
+	ids, err := nebula.GetSubscribersIds(nil)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		t, err := nebula.DataType(nil)
+		if err != nil {
+			return err
+		}
+
+		var send func(opts *bind.TransactOpts) (*types.Transaction, error)
+		switch SubType(t) {
+		case Int64:
+			v, err := strconv.ParseInt(value.Value, 10, 64)
+			if err != nil {
+				return err
+			}
+			send = func(opts *bind.TransactOpts) (*types.Transaction, error) {
+				return nebula.SendValueToSubInt(opts, v, big.NewInt(int64(pulseId)), id)
+			}
+		case String:
+			send = func(opts *bind.TransactOpts) (*types.Transaction, error) {
+				return nebula.SendValueToSubString(opts, value.Value, big.NewInt(int64(pulseId)), id)
+			}
+		default:
+			// The bytes and the composite types reach the subscribers in
+			// their canonical encoding.
+			v, err := abi.Encode(value, abi.ExtractorType(t))
+			if err != nil {
+				return err
+			}
+			send = func(opts *bind.TransactOpts) (*types.Transaction, error) {
+				return nebula.SendValueToSubByte(opts, v, big.NewInt(int64(pulseId)), id)
+			}
+		}
+
+		_, err = adaptor.transact(ctx, send)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (adaptor *EvmAdaptor) SetOraclesToNebula(nebulaId account.NebulaId, oracles []*account.OraclesPubKey, signs map[account.OraclesPubKey][]byte, round int64, ctx context.Context) (string, error) {
+	nebula, err := ethereum.NewNebula(common.BytesToAddress(nebulaId.ToBytes(adaptor.chainType)), adaptor.backend)
+	if err != nil {
+		return "", err
+	}
+
+	lastRound, err := nebula.Rounds(nil, big.NewInt(round))
+	if err != nil {
+		return "", err
+	}
+
+	if lastRound {
+		return "", err
+	}
+
+	var oraclesAddresses []common.Address
+	for _, v := range oracles {
+		if v == nil {
+			oraclesAddresses = append(oraclesAddresses, common.Address{})
+			continue
+		}
+
+		pubKey, err := crypto.DecompressPubkey(v.ToBytes(adaptor.chainType))
+		if err != nil {
+			return "", err
+		}
+		oraclesAddresses = append(oraclesAddresses, crypto.PubkeyToAddress(*pubKey))
+	}
+
+	consuls, err := adaptor.gravityContract.GetConsuls(nil)
+	if err != nil {
+		return "", err
+	}
+
+	var r [5][32]byte
+	var s [5][32]byte
+	var v [5]uint8
+	for pubKey, sign := range signs {
+		index := -1
+		ethPubKey, err := crypto.DecompressPubkey(pubKey.ToBytes(adaptor.chainType))
+		if err != nil {
+			return "", err
+		}
+		validatorAddress := crypto.PubkeyToAddress(*ethPubKey)
+		for i, v := range consuls {
+			if v == validatorAddress {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			continue
+		}
+
+		var bytes32R [32]byte
+		copy(bytes32R[:], sign[:32])
+		var bytes32S [32]byte
+		copy(bytes32S[:], sign[32:64])
+
+		r[index] = bytes32R
+		s[index] = bytes32S
+		v[index] = sign[64:][0] + 27
+	}
+
+	tx, err := adaptor.transact(ctx, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return nebula.UpdateOracles(opts, oraclesAddresses, v[:], r[:], s[:], big.NewInt(round))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return tx.Hash().Hex(), nil
+}
+func (adaptor *EvmAdaptor) SendConsulsToGravityContract(newConsulsAddresses []*account.OraclesPubKey, signs map[account.OraclesPubKey][]byte, round int64, ctx context.Context) (string, error) {
+	consuls, err := adaptor.gravityContract.GetConsuls(nil)
+	if err != nil {
+		return "", err
+	}
+
+	var consulsAddress []common.Address
+	for _, v := range newConsulsAddresses {
+		if v == nil {
+			consulsAddress = append(consulsAddress, common.Address{})
+			continue
+		}
+		pubKey, err := crypto.DecompressPubkey(v.ToBytes(adaptor.chainType))
+		if err != nil {
+			return "", err
+		}
+		consulsAddress = append(consulsAddress, crypto.PubkeyToAddress(*pubKey))
+	}
+
+	var r [5][32]byte
+	var s [5][32]byte
+	var v [5]uint8
+	for pubKey, sign := range signs {
+		index := -1
+		ethPubKey, err := crypto.DecompressPubkey(pubKey.ToBytes(adaptor.chainType))
+		if err != nil {
+			return "", err
+		}
+		validatorAddress := crypto.PubkeyToAddress(*ethPubKey)
+		for i, v := range consuls {
+			if v == validatorAddress {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			continue
+		}
+
+		var bytes32R [32]byte
+		copy(bytes32R[:], sign[:32])
+		var bytes32S [32]byte
+		copy(bytes32S[:], sign[32:64])
+
+		r[index] = bytes32R
+		s[index] = bytes32S
+		v[index] = sign[64:][0] + 27
+	}
+
+	tx, err := adaptor.transact(ctx, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return adaptor.gravityContract.UpdateConsuls(opts, consulsAddress, v[:], r[:], s[:], big.NewInt(round))
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return tx.Hash().Hex(), nil
+}
+func (adaptor *EvmAdaptor) SignConsuls(consulsAddresses []*account.OraclesPubKey, roundId int64) ([]byte, error) {
+	var oraclesAddresses []common.Address
+	for _, v := range consulsAddresses {
+		if v == nil {
+			oraclesAddresses = append(oraclesAddresses, common.Address{})
+			continue
+		}
+		pubKey, err := crypto.DecompressPubkey(v.ToBytes(adaptor.chainType))
+		if err != nil {
+			return nil, err
+		}
+		oraclesAddresses = append(oraclesAddresses, crypto.PubkeyToAddress(*pubKey))
+	}
+	hash, err := adaptor.gravityContract.HashNewConsuls(nil, oraclesAddresses, big.NewInt(roundId))
+	if err != nil {
+		return nil, err
+	}
+
+	sign, err := adaptor.Sign(hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return sign, nil
+}
+func (adaptor *EvmAdaptor) SignOracles(nebulaId account.NebulaId, oracles []*account.OraclesPubKey) ([]byte, error) {
+	nebula, err := ethereum.NewNebula(common.BytesToAddress(nebulaId.ToBytes(adaptor.chainType)), adaptor.backend)
+	if err != nil {
+		return nil, err
+	}
+
+	var oraclesAddresses []common.Address
+	for _, v := range oracles {
+		if v == nil {
+			oraclesAddresses = append(oraclesAddresses, common.Address{})
+			continue
+		}
+		pubKey, err := crypto.DecompressPubkey(v.ToBytes(adaptor.chainType))
+		if err != nil {
+			return nil, err
+		}
+		oraclesAddresses = append(oraclesAddresses, crypto.PubkeyToAddress(*pubKey))
+	}
+
+	hash, err := nebula.HashNewOracles(nil, oraclesAddresses)
+	if err != nil {
+		return nil, err
+	}
+
+	sign, err := adaptor.Sign(hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return sign, nil
+}
+
+func (adaptor *EvmAdaptor) LastPulseId(nebulaId account.NebulaId, ctx context.Context) (uint64, error) {
+	nebula, err := ethereum.NewNebula(common.BytesToAddress(nebulaId.ToBytes(adaptor.chainType)), adaptor.backend)
+	if err != nil {
+		return 0, err
+	}
+
+	lastId, err := nebula.LastPulseId(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return lastId.Uint64(), nil
+}
+func (adaptor *EvmAdaptor) LastRound(ctx context.Context) (uint64, error) {
+	lastRound, err := adaptor.gravityContract.LastRound(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return lastRound.Uint64(), nil
+}
+func (adaptor *EvmAdaptor) RoundExist(roundId int64, ctx context.Context) (bool, error) {
+	consuls, err := adaptor.gravityContract.GetConsulsByRoundId(nil, big.NewInt(roundId))
+	if err != nil {
+		return false, err
+	}
+
+	if len(consuls) > 0 {
+		return true, nil
+	} else {
+		return false, nil
+	}
+}
@@ -0,0 +1,486 @@
+package adaptors
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/abi/ethereum"
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/ledger/query"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	ethabi "github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+const (
+	testEvmChainId = "1337"
+	testKeyCount   = 5
+	testBft        = 3
+)
+
+// testSubscriberBin deploys a contract that stores the first argument of any
+// call in slot 0.
+var testSubscriberBin = common.FromHex("0x6007600c60003960076000f3" + "60043560005500")
+
+// testResults answers the result queries of AddPulse with the signs set by
+// the test.
+type testResults struct {
+	gravity.RPCClient
+	signs map[string][]byte
+}
+
+func (rpc *testResults) ABCIQueryWithOptions(ctx context.Context, path string, data tmbytes.HexBytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	var rq query.ResultRq
+	err := json.Unmarshal(data, &rq)
+	if err != nil {
+		return nil, err
+	}
+
+	sign, ok := rpc.signs[rq.OraclePubKey]
+	if !ok {
+		return &ctypes.ResultABCIQuery{Response: abcitypes.ResponseQuery{Code: gravity.NotFoundCode}}, nil
+	}
+
+	return &ctypes.ResultABCIQuery{Response: abcitypes.ResponseQuery{Value: sign}}, nil
+}
+
+// evmTestChain is the testTargetChain of a simulated backend with the
+// Gravity and Nebula contracts deployed.
+type evmTestChain struct {
+	backend        *backends.SimulatedBackend
+	consuls        []*ecdsa.PrivateKey
+	oracles        []*ecdsa.PrivateKey
+	gravityAddress common.Address
+	gravity        *ethereum.Gravity
+	nebulaContract *ethereum.Nebula
+	nebulaId       account.NebulaId
+	results        *testResults
+	adaptor        *EvmAdaptor
+	deployer       *bind.TransactOpts
+}
+
+func newTestKeys(t *testing.T, count int) []*ecdsa.PrivateKey {
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < count; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func testPubKey(key *ecdsa.PrivateKey) account.OraclesPubKey {
+	return account.BytesToOraclePubKey(crypto.CompressPubkey(&key.PublicKey), account.Ethereum)
+}
+
+func testAddresses(keys []*ecdsa.PrivateKey) []common.Address {
+	var addresses []common.Address
+	for _, key := range keys {
+		addresses = append(addresses, crypto.PubkeyToAddress(key.PublicKey))
+	}
+
+	return addresses
+}
+
+func newEvmTestChain(t *testing.T) *evmTestChain {
+	chain := &evmTestChain{
+		consuls: newTestKeys(t, testKeyCount),
+		oracles: newTestKeys(t, testKeyCount),
+		results: &testResults{signs: make(map[string][]byte)},
+	}
+
+	// DeployNebula links its bytecode to the first QueueLib it deploys, so
+	// the library must get the same address on every chain.
+	deployerKey, err := crypto.ToECDSA(crypto.Keccak256([]byte("deployer")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alloc := make(core.GenesisAlloc)
+	for _, address := range testAddresses(append(chain.consuls, deployerKey)) {
+		alloc[address] = core.GenesisAccount{Balance: big.NewInt(1e18)}
+	}
+	chain.backend = backends.NewSimulatedBackend(alloc, 10000000)
+	t.Cleanup(func() { chain.backend.Close() })
+
+	chain.deployer = bind.NewKeyedTransactor(deployerKey)
+	chain.gravityAddress, _, chain.gravity, err = ethereum.DeployGravity(chain.deployer, chain.backend, testAddresses(chain.consuls), big.NewInt(testBft))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.backend.Commit()
+
+	chain.nebulaId, chain.nebulaContract = chain.deployNebula(t)
+
+	ghClient, err := gravity.NewWithRPC(chain.results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.adaptor, err = NewEvmAdaptorWithBackend(crypto.FromECDSA(chain.consuls[1]), chain.backend, account.Ethereum, context.Background(),
+		WithEvmChainId(testEvmChainId),
+		WithEvmGravityContract(chain.gravityAddress.Hex()),
+		EvmAdapterWithGhClient(ghClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return chain
+}
+
+// deployNebula deploys a nebula of the oracles of the chain.
+func (chain *evmTestChain) deployNebula(t *testing.T) (account.NebulaId, *ethereum.Nebula) {
+	address, _, nebula, err := ethereum.DeployNebula(chain.deployer, chain.backend, uint8(Int64), chain.gravityAddress, testAddresses(chain.oracles), big.NewInt(testBft))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.backend.Commit()
+
+	return account.BytesToNebulaId(address.Bytes()), nebula
+}
+
+// adaptorOf returns an adaptor that signs with key.
+func (chain *evmTestChain) adaptorOf(t *testing.T, key *ecdsa.PrivateKey) *EvmAdaptor {
+	adaptor, err := NewEvmAdaptorWithBackend(crypto.FromECDSA(key), chain.backend, account.Ethereum, context.Background(),
+		WithEvmGravityContract(chain.gravityAddress.Hex()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return adaptor
+}
+
+func (chain *evmTestChain) sender() IBlockchainAdaptor {
+	return chain.adaptor
+}
+
+func (chain *evmTestChain) nebula() account.NebulaId {
+	return chain.nebulaId
+}
+
+// confirm mines the pending transactions.
+func (chain *evmTestChain) confirm(t *testing.T, txId string) {
+	chain.backend.Commit()
+
+	receipt, err := chain.backend.TransactionReceipt(context.Background(), common.HexToHash(txId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("tx %s failed", txId)
+	}
+}
+
+func (chain *evmTestChain) signResults(t *testing.T, hash []byte, indexes ...int) {
+	chain.results.signs = make(map[string][]byte)
+	for _, i := range indexes {
+		chain.signResult(t, hash, i)
+	}
+}
+
+func (chain *evmTestChain) forgeResult(t *testing.T, index int) {
+	chain.signResult(t, crypto.Keccak256([]byte("other value")), index)
+}
+
+func (chain *evmTestChain) signResult(t *testing.T, hash []byte, index int) {
+	sign, err := crypto.Sign(hash, chain.oracles[index])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey := testPubKey(chain.oracles[index])
+	chain.results.signs[pubKey.ToString(account.Ethereum)] = sign
+}
+
+func (chain *evmTestChain) pulseHash(t *testing.T, pulseId uint64) []byte {
+	pulse, err := chain.nebulaContract.Pulses(nil, new(big.Int).SetUint64(pulseId))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pulse.DataHash[:]
+}
+
+// validators lists the oracles in reverse order with a foreign key in front.
+func (chain *evmTestChain) validators(t *testing.T) []account.OraclesPubKey {
+	validators := []account.OraclesPubKey{testPubKey(newTestKeys(t, 1)[0])}
+	for i := len(chain.oracles) - 1; i >= 0; i-- {
+		validators = append(validators, testPubKey(chain.oracles[i]))
+	}
+
+	return validators
+}
+
+// consulSigns signs with the consuls at indexes.
+func (chain *evmTestChain) consulSigns(t *testing.T, sign func(adaptor *EvmAdaptor) ([]byte, error), indexes ...int) map[account.OraclesPubKey][]byte {
+	signs := make(map[account.OraclesPubKey][]byte)
+	for _, i := range indexes {
+		adaptor := chain.adaptorOf(t, chain.consuls[i])
+		s, err := sign(adaptor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signs[adaptor.PubKey()] = s
+	}
+
+	return signs
+}
+
+func testPubKeyPointers(keys []*ecdsa.PrivateKey) []*account.OraclesPubKey {
+	var pubKeys []*account.OraclesPubKey
+	for _, key := range keys {
+		pubKey := testPubKey(key)
+		pubKeys = append(pubKeys, &pubKey)
+	}
+
+	return pubKeys
+}
+
+func TestEvmTxFee(t *testing.T) {
+	chain := newEvmTestChain(t)
+	ctx := context.Background()
+	txId := addTestPulse(t, chain, crypto.Keccak256([]byte("value")))
+
+	gas, fee, err := chain.adaptor.TxFee(txId, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, _, err := chain.backend.TransactionByHash(ctx, common.HexToHash(txId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gas == 0 || fee.Cmp(new(big.Int).Mul(new(big.Int).SetUint64(gas), tx.GasPrice())) != 0 {
+		t.Errorf("unexpected gas %d and fee %s", gas, fee)
+	}
+}
+
+// racingBackend waits after it reads a nonce, so txs sent at once read the
+// same pending nonce unless the adaptor keeps the nonces.
+type racingBackend struct {
+	*backends.SimulatedBackend
+}
+
+func (backend racingBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	nonce, err := backend.SimulatedBackend.PendingNonceAt(ctx, account)
+	time.Sleep(10 * time.Millisecond)
+	return nonce, err
+}
+
+func TestEvmConcurrentPulses(t *testing.T) {
+	chain := newEvmTestChain(t)
+	ctx := context.Background()
+	ghClient, err := gravity.NewWithRPC(chain.results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adaptor, err := NewEvmAdaptorWithBackend(crypto.FromECDSA(chain.consuls[1]), racingBackend{chain.backend}, account.Ethereum, ctx,
+		WithEvmChainId(testEvmChainId),
+		EvmAdapterWithGhClient(ghClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := crypto.Keccak256([]byte("value"))
+	chain.signResults(t, hash, 4, 0, 2)
+	validators := chain.validators(t)
+
+	nebulae := []account.NebulaId{chain.nebulaId}
+	for len(nebulae) < 4 {
+		nebulaId, _ := chain.deployNebula(t)
+		nebulae = append(nebulae, nebulaId)
+	}
+
+	// The nodes of the nebulae send their pulses through the one adaptor of
+	// the chain at once.
+	txIds := make([]string, len(nebulae))
+	errs := make([]error, len(nebulae))
+	var wg sync.WaitGroup
+	for i, nebulaId := range nebulae {
+		wg.Add(1)
+		go func(i int, nebulaId account.NebulaId) {
+			defer wg.Done()
+			txIds[i], errs[i] = adaptor.AddPulse(nebulaId, 1, validators, hash, ctx)
+		}(i, nebulaId)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("nebula %d: %v", i, err)
+		}
+		chain.confirm(t, txIds[i])
+	}
+}
+
+func TestEvmSendConsulsToGravityContract(t *testing.T) {
+	chain := newEvmTestChain(t)
+	ctx := context.Background()
+	newConsuls := newTestKeys(t, testKeyCount-1)
+	newConsulsPubKeys := append(testPubKeyPointers(newConsuls), nil)
+	signConsuls := func(adaptor *EvmAdaptor) ([]byte, error) {
+		return adaptor.SignConsuls(newConsulsPubKeys, 1)
+	}
+
+	signs := chain.consulSigns(t, signConsuls, 3, 0)
+	if _, err := chain.adaptor.SendConsulsToGravityContract(newConsulsPubKeys, signs, 1, ctx); err == nil {
+		t.Fatal("consuls updated below the bft value")
+	}
+
+	signs = chain.consulSigns(t, signConsuls, 4, 2, 0)
+	txId, err := chain.adaptor.SendConsulsToGravityContract(newConsulsPubKeys, signs, 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.confirm(t, txId)
+
+	lastRound, err := chain.adaptor.LastRound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastRound != 1 {
+		t.Fatalf("last round: expected 1, got %d", lastRound)
+	}
+	exist, err := chain.adaptor.RoundExist(1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exist {
+		t.Fatal("round 1 does not exist")
+	}
+
+	consuls, err := chain.gravity.GetConsuls(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := append(testAddresses(newConsuls), common.Address{})
+	for i := range expected {
+		if i >= len(consuls) || consuls[i] != expected[i] {
+			t.Fatalf("consuls: expected %v, got %v", expected, consuls)
+		}
+	}
+
+	// The old consuls can't sign the next round.
+	signs = chain.consulSigns(t, func(adaptor *EvmAdaptor) ([]byte, error) {
+		return adaptor.SignConsuls(newConsulsPubKeys, 2)
+	}, 0, 1, 2, 3, 4)
+	if _, err := chain.adaptor.SendConsulsToGravityContract(newConsulsPubKeys, signs, 2, ctx); err == nil {
+		t.Fatal("consuls updated by the previous round")
+	}
+}
+
+func TestEvmSetOraclesToNebula(t *testing.T) {
+	chain := newEvmTestChain(t)
+	ctx := context.Background()
+	newOracles := newTestKeys(t, testKeyCount)
+	newOraclesPubKeys := testPubKeyPointers(newOracles)
+	signOracles := func(adaptor *EvmAdaptor) ([]byte, error) {
+		return adaptor.SignOracles(chain.nebulaId, newOraclesPubKeys)
+	}
+
+	signs := chain.consulSigns(t, signOracles, 1, 4)
+	if _, err := chain.adaptor.SetOraclesToNebula(chain.nebulaId, newOraclesPubKeys, signs, 1, ctx); err == nil {
+		t.Fatal("oracles updated below the bft value")
+	}
+
+	signs = chain.consulSigns(t, signOracles, 3, 1, 4)
+	txId, err := chain.adaptor.SetOraclesToNebula(chain.nebulaId, newOraclesPubKeys, signs, 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.confirm(t, txId)
+
+	oracles, err := chain.nebulaContract.GetOracles(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := testAddresses(newOracles)
+	for i := range expected {
+		if i >= len(oracles) || oracles[i] != expected[i] {
+			t.Fatalf("oracles: expected %v, got %v", expected, oracles)
+		}
+	}
+
+	txId, err = chain.adaptor.SetOraclesToNebula(chain.nebulaId, newOraclesPubKeys, signs, 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txId != "" {
+		t.Error("oracles of an existing round sent again")
+	}
+}
+
+func TestEvmSendValueToSubs(t *testing.T) {
+	chain := newEvmTestChain(t)
+	ctx := context.Background()
+	owner := bind.NewKeyedTransactor(chain.consuls[0])
+
+	subscriber, _, _, err := bind.DeployContract(owner, ethabi.ABI{}, testSubscriberBin, chain.backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.backend.Commit()
+
+	tx, err := chain.nebulaContract.Subscribe(owner, subscriber, 1, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.confirm(t, tx.Hash().Hex())
+
+	addTestPulse(t, chain, crypto.Keccak256([]byte("value")))
+
+	value := &extractor.Data{Type: extractor.Int64, Value: "42"}
+	if err := chain.adaptor.SendValueToSubs(chain.nebulaId, 1, value, ctx); err != nil {
+		t.Fatal(err)
+	}
+	chain.backend.Commit()
+
+	stored, err := chain.backend.StorageAt(ctx, subscriber, common.Hash{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if new(big.Int).SetBytes(stored).Int64() != 42 {
+		t.Errorf("subscriber value: expected 42, got %x", stored)
+	}
+
+	if err := chain.adaptor.SendValueToSubs(chain.nebulaId, 1, value, ctx); err == nil {
+		t.Error("value sent to a subscriber twice")
+	}
+}
+
+func TestEvmSubscribeHeight(t *testing.T) {
+	chain := newEvmTestChain(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	heights, err := chain.adaptor.SubscribeHeight(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain.backend.Commit()
+	expected, err := chain.adaptor.GetHeight(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height := <-heights; height != expected {
+		t.Errorf("height: expected %d, got %d", expected, height)
+	}
+
+	cancel()
+	for range heights {
+	}
+}
@@ -2,6 +2,8 @@ package adaptors
 
 import (
 	"context"
+	"math/big"
+
 	"github.com/Gravity-Tech/gravity-core/abi"
 	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
 
@@ -27,3 +29,17 @@ type IBlockchainAdaptor interface {
 	LastRound(ctx context.Context) (uint64, error)
 	RoundExist(roundId int64, ctx context.Context) (bool, error)
 }
+
+// IHeightSubscriber is implemented by the adaptors whose target chain can
+// push its new blocks. The channel keeps only the latest height and is closed
+// when ctx is done or the subscription fails.
+type IHeightSubscriber interface {
+	SubscribeHeight(ctx context.Context) (<-chan uint64, error)
+}
+
+// ITxFeeReader is implemented by the adaptors that can tell what a confirmed
+// transaction cost: the gas it used, zero on chains without gas, and the fee
+// it paid in the smallest unit of the target chain.
+type ITxFeeReader interface {
+	TxFee(id string, ctx context.Context) (uint64, *big.Int, error)
+}
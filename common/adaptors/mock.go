@@ -0,0 +1,440 @@
+package adaptors
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Gravity-Tech/gravity-core/abi"
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	ErrMockNebulaNotFound = errors.New("nebula is not found")
+	ErrMockPulseNotFound  = errors.New("pulse is not found")
+	ErrMockTxNotFound     = errors.New("tx not found")
+)
+
+// MockChain is an in-memory target chain with one Gravity contract and its
+// Nebula contracts. It is shared by the MockAdaptor of every participant and
+// signs with EVM keys, so its chain type must be of the EVM kind.
+type MockChain struct {
+	mu sync.Mutex
+
+	chainType account.ChainType
+	height    uint64
+	bft       int
+	lastRound uint64
+	consuls   map[uint64][]account.OraclesPubKey
+	nebulae   map[account.NebulaId]*mockNebula
+	txs       map[string]bool
+	heads     []chan uint64
+}
+
+type mockNebula struct {
+	valueType   abi.ExtractorType
+	bft         int
+	oracles     []account.OraclesPubKey
+	lastPulseId uint64
+	pulses      map[uint64][]byte
+	values      map[uint64]extractor.Data
+}
+
+// NewMockChain returns a chain at height 1 whose Gravity contract accepts a
+// new round when bft of the consuls of round 0 sign it.
+func NewMockChain(chainType account.ChainType, consuls []account.OraclesPubKey, bft int) (*MockChain, error) {
+	if chainType.Kind() != account.EVMChain {
+		return nil, account.ErrInvalidChainType
+	}
+
+	return &MockChain{
+		chainType: chainType,
+		height:    1,
+		bft:       bft,
+		consuls:   map[uint64][]account.OraclesPubKey{0: consuls},
+		nebulae:   make(map[account.NebulaId]*mockNebula),
+		txs:       make(map[string]bool),
+	}, nil
+}
+
+func (chain *MockChain) ChainType() account.ChainType {
+	return chain.chainType
+}
+
+// Mine advances the chain by one block.
+func (chain *MockChain) Mine() {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	chain.height++
+	for _, heads := range chain.heads {
+		select {
+		case <-heads:
+		default:
+		}
+		heads <- chain.height
+	}
+}
+
+func (chain *MockChain) Height() uint64 {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	return chain.height
+}
+
+// AddNebula deploys a nebula that accepts a pulse signed by bft of oracles.
+func (chain *MockChain) AddNebula(nebulaId account.NebulaId, valueType abi.ExtractorType, oracles []account.OraclesPubKey, bft int) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	chain.nebulae[nebulaId] = &mockNebula{
+		valueType: valueType,
+		bft:       bft,
+		oracles:   append([]account.OraclesPubKey{}, oracles...),
+		pulses:    make(map[uint64][]byte),
+		values:    make(map[uint64]extractor.Data),
+	}
+}
+
+func (chain *MockChain) LastRound() uint64 {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	return chain.lastRound
+}
+
+// Consuls returns the consuls set in the round.
+func (chain *MockChain) Consuls(round uint64) []account.OraclesPubKey {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	return append([]account.OraclesPubKey{}, chain.consuls[round]...)
+}
+
+func (chain *MockChain) NebulaOracles(nebulaId account.NebulaId) ([]account.OraclesPubKey, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	nebula, ok := chain.nebulae[nebulaId]
+	if !ok {
+		return nil, ErrMockNebulaNotFound
+	}
+
+	return append([]account.OraclesPubKey{}, nebula.oracles...), nil
+}
+
+func (chain *MockChain) LastPulseId(nebulaId account.NebulaId) (uint64, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	nebula, ok := chain.nebulae[nebulaId]
+	if !ok {
+		return 0, ErrMockNebulaNotFound
+	}
+
+	return nebula.lastPulseId, nil
+}
+
+// Pulse returns the hash stored by the pulse.
+func (chain *MockChain) Pulse(nebulaId account.NebulaId, pulseId uint64) ([]byte, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	nebula, ok := chain.nebulae[nebulaId]
+	if !ok {
+		return nil, ErrMockNebulaNotFound
+	}
+
+	hash, ok := nebula.pulses[pulseId]
+	if !ok {
+		return nil, ErrMockPulseNotFound
+	}
+
+	return hash, nil
+}
+
+// Value returns the value sent to the subscribers of the nebula in the pulse.
+func (chain *MockChain) Value(nebulaId account.NebulaId, pulseId uint64) (*extractor.Data, error) {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+
+	nebula, ok := chain.nebulae[nebulaId]
+	if !ok {
+		return nil, ErrMockNebulaNotFound
+	}
+
+	value, ok := nebula.values[pulseId]
+	if !ok {
+		return nil, ErrMockPulseNotFound
+	}
+
+	return &value, nil
+}
+
+// newTx records a transaction and returns its id. The caller holds the lock.
+func (chain *MockChain) newTx() string {
+	id := fmt.Sprintf("0x%064x", len(chain.txs)+1)
+	chain.txs[id] = true
+	return id
+}
+
+// signCount counts the signs made by the keys in signers.
+func (chain *MockChain) signCount(signers []account.OraclesPubKey, signs map[account.OraclesPubKey][]byte, hash []byte) int {
+	count := 0
+	for _, signer := range signers {
+		sign, ok := signs[signer]
+		if ok && account.Verify(chain.chainType, signer, hash, sign) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func mockConsulsHash(consuls []*account.OraclesPubKey, roundId int64) []byte {
+	var round [8]byte
+	binary.BigEndian.PutUint64(round[:], uint64(roundId))
+
+	return crypto.Keccak256(append(round[:], mockPubKeysBytes(consuls)...))
+}
+
+func mockOraclesHash(nebulaId account.NebulaId, oracles []*account.OraclesPubKey) []byte {
+	return crypto.Keccak256(nebulaId[:], mockPubKeysBytes(oracles))
+}
+
+func mockPubKeysBytes(pubKeys []*account.OraclesPubKey) []byte {
+	var b []byte
+	for _, v := range pubKeys {
+		if v == nil {
+			b = append(b, make([]byte, len(account.OraclesPubKey{}))...)
+			continue
+		}
+		b = append(b, v[:]...)
+	}
+
+	return b
+}
+
+func nonNilPubKeys(pubKeys []*account.OraclesPubKey) []account.OraclesPubKey {
+	var result []account.OraclesPubKey
+	for _, v := range pubKeys {
+		if v != nil {
+			result = append(result, *v)
+		}
+	}
+
+	return result
+}
+
+// MockAdaptor sends the transactions of one participant to a MockChain.
+type MockAdaptor struct {
+	chain    *MockChain
+	privKey  *ecdsa.PrivateKey
+	ghClient *gravity.Client
+}
+type MockAdaptorOption func(*MockAdaptor) error
+
+func MockAdaptorWithGhClient(ghClient *gravity.Client) MockAdaptorOption {
+	return func(h *MockAdaptor) error {
+		h.ghClient = ghClient
+		return nil
+	}
+}
+
+func NewMockAdaptor(privKey []byte, chain *MockChain, opts ...MockAdaptorOption) (*MockAdaptor, error) {
+	ethPrivKey, err := crypto.ToECDSA(privKey)
+	if err != nil {
+		return nil, err
+	}
+
+	adaptor := &MockAdaptor{
+		chain:   chain,
+		privKey: ethPrivKey,
+	}
+	for _, opt := range opts {
+		err := opt(adaptor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return adaptor, nil
+}
+
+func (adaptor *MockAdaptor) GetHeight(ctx context.Context) (uint64, error) {
+	return adaptor.chain.Height(), nil
+}
+
+// SubscribeHeight streams the heights mined after the call.
+func (adaptor *MockAdaptor) SubscribeHeight(ctx context.Context) (<-chan uint64, error) {
+	chain := adaptor.chain
+	heights := make(chan uint64, 1)
+
+	chain.mu.Lock()
+	chain.heads = append(chain.heads, heights)
+	chain.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		chain.mu.Lock()
+		defer chain.mu.Unlock()
+
+		for i, v := range chain.heads {
+			if v == heights {
+				chain.heads = append(chain.heads[:i], chain.heads[i+1:]...)
+				break
+			}
+		}
+		close(heights)
+	}()
+
+	return heights, nil
+}
+func (adaptor *MockAdaptor) WaitTx(id string, ctx context.Context) error {
+	adaptor.chain.mu.Lock()
+	defer adaptor.chain.mu.Unlock()
+
+	if !adaptor.chain.txs[id] {
+		return ErrMockTxNotFound
+	}
+
+	return nil
+}
+func (adaptor *MockAdaptor) Sign(msg []byte) ([]byte, error) {
+	return crypto.Sign(msg, adaptor.privKey)
+}
+func (adaptor *MockAdaptor) PubKey() account.OraclesPubKey {
+	pubKey := crypto.CompressPubkey(&adaptor.privKey.PublicKey)
+	return account.BytesToOraclePubKey(pubKey, adaptor.chain.chainType)
+}
+func (adaptor *MockAdaptor) ValueType(nebulaId account.NebulaId, ctx context.Context) (abi.ExtractorType, error) {
+	adaptor.chain.mu.Lock()
+	defer adaptor.chain.mu.Unlock()
+
+	nebula, ok := adaptor.chain.nebulae[nebulaId]
+	if !ok {
+		return 0, ErrMockNebulaNotFound
+	}
+
+	return nebula.valueType, nil
+}
+
+// AddPulse collects the result signs of validators from the ledger like the
+// EVM adaptor does and stores the pulse once bft of the nebula oracles
+// signed hash.
+func (adaptor *MockAdaptor) AddPulse(nebulaId account.NebulaId, pulseId uint64, validators []account.OraclesPubKey, hash []byte, ctx context.Context) (string, error) {
+	chainType := adaptor.chain.chainType
+	signs := make(map[account.OraclesPubKey][]byte)
+	for _, validator := range validators {
+		sign, err := adaptor.ghClient.Result(chainType, nebulaId, int64(pulseId), validator)
+		if err != nil {
+			continue
+		}
+		signs[validator] = sign
+	}
+
+	adaptor.chain.mu.Lock()
+	defer adaptor.chain.mu.Unlock()
+
+	nebula, ok := adaptor.chain.nebulae[nebulaId]
+	if !ok {
+		return "", ErrMockNebulaNotFound
+	}
+
+	if pulseId != nebula.lastPulseId+1 {
+		return "", nil
+	}
+	if adaptor.chain.signCount(nebula.oracles, signs, hash) < nebula.bft {
+		return "", nil
+	}
+
+	nebula.pulses[pulseId] = append([]byte{}, hash...)
+	nebula.lastPulseId = pulseId
+
+	return adaptor.chain.newTx(), nil
+}
+func (adaptor *MockAdaptor) SendValueToSubs(nebulaId account.NebulaId, pulseId uint64, value *extractor.Data, ctx context.Context) error {
+	adaptor.chain.mu.Lock()
+	defer adaptor.chain.mu.Unlock()
+
+	nebula, ok := adaptor.chain.nebulae[nebulaId]
+	if !ok {
+		return ErrMockNebulaNotFound
+	}
+	if _, ok := nebula.pulses[pulseId]; !ok {
+		return ErrMockPulseNotFound
+	}
+	if _, err := abi.Encode(value, nebula.valueType); err != nil {
+		return err
+	}
+
+	nebula.values[pulseId] = *value
+	adaptor.chain.newTx()
+
+	return nil
+}
+
+func (adaptor *MockAdaptor) SetOraclesToNebula(nebulaId account.NebulaId, oracles []*account.OraclesPubKey, signs map[account.OraclesPubKey][]byte, round int64, ctx context.Context) (string, error) {
+	adaptor.chain.mu.Lock()
+	defer adaptor.chain.mu.Unlock()
+
+	nebula, ok := adaptor.chain.nebulae[nebulaId]
+	if !ok {
+		return "", ErrMockNebulaNotFound
+	}
+
+	consuls := adaptor.chain.consuls[adaptor.chain.lastRound]
+	if adaptor.chain.signCount(consuls, signs, mockOraclesHash(nebulaId, oracles)) < adaptor.chain.bft {
+		return "", nil
+	}
+
+	nebula.oracles = nonNilPubKeys(oracles)
+
+	return adaptor.chain.newTx(), nil
+}
+func (adaptor *MockAdaptor) SendConsulsToGravityContract(newConsulsAddresses []*account.OraclesPubKey, signs map[account.OraclesPubKey][]byte, round int64, ctx context.Context) (string, error) {
+	adaptor.chain.mu.Lock()
+	defer adaptor.chain.mu.Unlock()
+
+	if round <= int64(adaptor.chain.lastRound) {
+		return "", nil
+	}
+
+	consuls := adaptor.chain.consuls[adaptor.chain.lastRound]
+	if adaptor.chain.signCount(consuls, signs, mockConsulsHash(newConsulsAddresses, round)) < adaptor.chain.bft {
+		return "", nil
+	}
+
+	adaptor.chain.consuls[uint64(round)] = nonNilPubKeys(newConsulsAddresses)
+	adaptor.chain.lastRound = uint64(round)
+
+	return adaptor.chain.newTx(), nil
+}
+func (adaptor *MockAdaptor) SignConsuls(consulsAddresses []*account.OraclesPubKey, roundId int64) ([]byte, error) {
+	return adaptor.Sign(mockConsulsHash(consulsAddresses, roundId))
+}
+func (adaptor *MockAdaptor) SignOracles(nebulaId account.NebulaId, oracles []*account.OraclesPubKey) ([]byte, error) {
+	return adaptor.Sign(mockOraclesHash(nebulaId, oracles))
+}
+
+func (adaptor *MockAdaptor) LastPulseId(nebulaId account.NebulaId, ctx context.Context) (uint64, error) {
+	return adaptor.chain.LastPulseId(nebulaId)
+}
+func (adaptor *MockAdaptor) LastRound(ctx context.Context) (uint64, error) {
+	return adaptor.chain.LastRound(), nil
+}
+func (adaptor *MockAdaptor) RoundExist(roundId int64, ctx context.Context) (bool, error) {
+	adaptor.chain.mu.Lock()
+	defer adaptor.chain.mu.Unlock()
+
+	_, ok := adaptor.chain.consuls[uint64(roundId)]
+	return ok, nil
+}
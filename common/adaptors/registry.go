@@ -0,0 +1,61 @@
+package adaptors
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+)
+
+var (
+	ErrAdaptorNotFound = errors.New("adaptor is not found")
+	ErrAdaptorIsExist  = errors.New("adaptor is exist")
+)
+
+// Config is everything a Factory gets to connect to a target chain. Empty
+// fields are left to the adaptor's defaults.
+type Config struct {
+	ChainType              account.ChainType
+	PrivKey                []byte
+	NodeUrl                string
+	ChainId                string
+	GravityContractAddress string
+	GasPrice               string
+	GasPriceMultiplier     int64
+	GhClient               *gravity.Client
+}
+
+type Factory func(cfg Config, ctx context.Context) (IBlockchainAdaptor, error)
+
+var (
+	factoriesLock sync.RWMutex
+	factories     = make(map[account.ChainKind]Factory)
+)
+
+// Register adds the adaptor factory of a chain kind. Chain packages call it
+// from init.
+func Register(kind account.ChainKind, factory Factory) error {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+
+	if _, ok := factories[kind]; ok {
+		return ErrAdaptorIsExist
+	}
+
+	factories[kind] = factory
+	return nil
+}
+
+// New creates the adaptor registered for the kind of cfg.ChainType.
+func New(cfg Config, ctx context.Context) (IBlockchainAdaptor, error) {
+	factoriesLock.RLock()
+	factory, ok := factories[cfg.ChainType.Kind()]
+	factoriesLock.RUnlock()
+	if !ok {
+		return nil, ErrAdaptorNotFound
+	}
+
+	return factory(cfg, ctx)
+}
@@ -2,10 +2,10 @@ package adaptors
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"github.com/Gravity-Tech/gravity-core/abi"
 	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"math/big"
 	"strconv"
 	"strings"
 	"time"
@@ -52,6 +52,28 @@ func WavesAdapterWithGhClient(ghClient *gravity.Client) WavesAdapterOption {
 	}
 }
 
+func init() {
+	err := Register(account.WavesChain, func(cfg Config, ctx context.Context) (IBlockchainAdaptor, error) {
+		var chainId byte
+		if len(cfg.ChainId) > 0 {
+			chainId = cfg.ChainId[0]
+		}
+
+		var opts []WavesAdapterOption
+		if cfg.GhClient != nil {
+			opts = append(opts, WavesAdapterWithGhClient(cfg.GhClient))
+		}
+		if cfg.GravityContractAddress != "" {
+			opts = append(opts, WithWavesGravityContract(cfg.GravityContractAddress))
+		}
+
+		return NewWavesAdapter(cfg.PrivKey, cfg.NodeUrl, chainId, opts...)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
 func NewWavesAdapter(seed []byte, nodeUrl string, chainId byte, opts ...WavesAdapterOption) (*WavesAdaptor, error) {
 	wClient, err := wclient.NewClient(wclient.Options{ApiKey: "", BaseUrl: nodeUrl})
 	if err != nil {
@@ -85,6 +107,21 @@ func (adaptor *WavesAdaptor) GetHeight(ctx context.Context) (uint64, error) {
 func (adaptor *WavesAdaptor) WaitTx(id string, ctx context.Context) error {
 	return <-adaptor.helper.WaitTx(id, ctx)
 }
+
+// TxFee returns the fee of the tx in wavelets, Waves has no gas.
+func (adaptor *WavesAdaptor) TxFee(id string, ctx context.Context) (uint64, *big.Int, error) {
+	txId, err := crypto.NewDigestFromBase58(id)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	tx, _, err := adaptor.wavesClient.Transactions.Info(ctx, txId)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return 0, new(big.Int).SetUint64(tx.GetFee()), nil
+}
 func (adaptor *WavesAdaptor) Sign(msg []byte) ([]byte, error) {
 	sig, err := crypto.Sign(adaptor.secret, msg)
 	if err != nil {
@@ -251,8 +288,8 @@ func (adaptor *WavesAdaptor) SendValueToSubs(nebulaId account.NebulaId, pulseId
 			proto.StringArgument{
 				Value: value.Value,
 			})
-	case Bytes:
-		v, err := base64.StdEncoding.DecodeString(value.Value)
+	default:
+		v, err := abi.Encode(value, abi.ExtractorType(int8(nebulaType.Value.(float64))))
 		if err != nil {
 			return err
 		}
@@ -309,7 +346,10 @@ func (adaptor *WavesAdaptor) SetOraclesToNebula(nebulaId account.NebulaId, oracl
 	var newOracles []string
 	var stringSigns [5]string
 
-	lastRound := uint64(lastRoundState.Value.(float64))
+	lastRound, err := adaptor.LastRound(ctx)
+	if err != nil {
+		return "", err
+	}
 	consulsState, _, err := adaptor.helper.GetStateByAddressAndKey(adaptor.gravityContract, fmt.Sprintf("consuls_%d", lastRound), ctx)
 	if err != nil {
 		return "", err
@@ -399,12 +439,11 @@ func (adaptor *WavesAdaptor) SetOraclesToNebula(nebulaId account.NebulaId, oracl
 }
 func (adaptor *WavesAdaptor) SendConsulsToGravityContract(newConsulsAddresses []*account.OraclesPubKey, signs map[account.OraclesPubKey][]byte, round int64, ctx context.Context) (string, error) {
 	var stringSigns [5]string
-	lastRoundState, _, err := adaptor.helper.GetStateByAddressAndKey(adaptor.gravityContract, "last_round", ctx)
+	lastRound, err := adaptor.LastRound(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	lastRound := uint64(lastRoundState.Value.(float64))
 	consulsState, _, err := adaptor.helper.GetStateByAddressAndKey(adaptor.gravityContract, fmt.Sprintf("consuls_%d", lastRound), ctx)
 	if err != nil {
 		return "", err
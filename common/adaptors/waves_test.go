@@ -0,0 +1,377 @@
+package adaptors
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/abi"
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/adaptors/wavesnode"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/wavesplatform/gowaves/pkg/crypto"
+	"github.com/wavesplatform/gowaves/pkg/proto"
+)
+
+const testWavesChainId = 'T'
+
+// wavesTestChain is the testTargetChain of a local Waves node that runs the
+// scripts of the contracts in Go.
+type wavesTestChain struct {
+	node              *wavesnode.Node
+	consuls           []crypto.SecretKey
+	oracles           []crypto.SecretKey
+	gravityAddress    string
+	nebulaAddress     string
+	subscriberAddress string
+	nebulaId          account.NebulaId
+	results           *testResults
+	adaptor           *WavesAdaptor
+}
+
+func newTestWavesKeys(t *testing.T, name string, count int) []crypto.SecretKey {
+	var keys []crypto.SecretKey
+	for i := 0; i < count; i++ {
+		secret, _, err := crypto.GenerateKeyPair([]byte(fmt.Sprintf("%s %d", name, i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, secret)
+	}
+
+	return keys
+}
+
+func testWavesAddress(t *testing.T, secret crypto.SecretKey) proto.Address {
+	address, err := proto.NewAddressFromPublicKey(testWavesChainId, crypto.GeneratePublicKey(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return address
+}
+
+func testWavesPubKey(secret crypto.SecretKey) account.OraclesPubKey {
+	pubKey := crypto.GeneratePublicKey(secret)
+	return account.BytesToOraclePubKey(pubKey[:], account.Waves)
+}
+
+func testWavesPubKeys(keys []crypto.SecretKey) string {
+	var pubKeys []string
+	for _, key := range keys {
+		pubKeys = append(pubKeys, crypto.GeneratePublicKey(key).String())
+	}
+
+	return strings.Join(pubKeys, ",")
+}
+
+func testWavesPubKeyPointers(keys []crypto.SecretKey) []*account.OraclesPubKey {
+	var pubKeys []*account.OraclesPubKey
+	for _, key := range keys {
+		pubKey := testWavesPubKey(key)
+		pubKeys = append(pubKeys, &pubKey)
+	}
+
+	return pubKeys
+}
+
+func newWavesTestChain(t *testing.T) *wavesTestChain {
+	chain := &wavesTestChain{
+		node:    wavesnode.New(testWavesChainId),
+		consuls: newTestWavesKeys(t, "consul", testKeyCount),
+		oracles: newTestWavesKeys(t, "oracle", testKeyCount),
+		results: &testResults{signs: make(map[string][]byte)},
+	}
+	t.Cleanup(chain.node.Close)
+
+	contracts := newTestWavesKeys(t, "contract", 3)
+	chain.gravityAddress = testWavesAddress(t, contracts[0]).String()
+	chain.node.SetScript(chain.gravityAddress, wavesnode.GravityScript)
+	chain.node.SetData(chain.gravityAddress,
+		&proto.StringDataEntry{Key: "consuls_0", Value: testWavesPubKeys(chain.consuls)},
+		&proto.IntegerDataEntry{Key: "bft_coefficient", Value: testBft})
+
+	chain.subscriberAddress = testWavesAddress(t, contracts[2]).String()
+	chain.node.SetScript(chain.subscriberAddress, wavesnode.SubscriberScript)
+
+	nebulaAddress := testWavesAddress(t, contracts[1])
+	chain.nebulaAddress = nebulaAddress.String()
+	chain.nebulaId = account.BytesToNebulaId(nebulaAddress[:])
+	chain.node.SetScript(chain.nebulaAddress, wavesnode.NebulaScript)
+	chain.node.SetData(chain.nebulaAddress,
+		&proto.StringDataEntry{Key: "oracles", Value: testWavesPubKeys(chain.oracles)},
+		&proto.IntegerDataEntry{Key: "bft_coefficient", Value: testBft},
+		&proto.IntegerDataEntry{Key: "type", Value: int64(Int64)},
+		&proto.StringDataEntry{Key: "gravity_contract", Value: chain.gravityAddress},
+		&proto.StringDataEntry{Key: "subscriber_address", Value: chain.subscriberAddress},
+		&proto.StringDataEntry{Key: "contract_pubkey", Value: crypto.GeneratePublicKey(contracts[1]).String()})
+
+	ghClient, err := gravity.NewWithRPC(chain.results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.adaptor, err = NewWavesAdapter(chain.consuls[1].Bytes(), chain.node.URL(), testWavesChainId,
+		WithWavesGravityContract(chain.gravityAddress),
+		WavesAdapterWithGhClient(ghClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return chain
+}
+
+// adaptorOf returns an adaptor without a ledger client that signs with
+// secret.
+func (chain *wavesTestChain) adaptorOf(t *testing.T, secret crypto.SecretKey) *WavesAdaptor {
+	adaptor, err := NewWavesAdapter(secret.Bytes(), chain.node.URL(), testWavesChainId,
+		WithWavesGravityContract(chain.gravityAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return adaptor
+}
+
+func (chain *wavesTestChain) sender() IBlockchainAdaptor {
+	return chain.adaptor
+}
+
+func (chain *wavesTestChain) nebula() account.NebulaId {
+	return chain.nebulaId
+}
+
+// validators is nil: the Waves adaptor takes the oracles from the "oracles"
+// entry of the nebula.
+func (chain *wavesTestChain) validators(t *testing.T) []account.OraclesPubKey {
+	return nil
+}
+
+func (chain *wavesTestChain) signResults(t *testing.T, hash []byte, indexes ...int) {
+	chain.results.signs = make(map[string][]byte)
+	for _, i := range indexes {
+		chain.signResult(t, hash, i)
+	}
+}
+
+func (chain *wavesTestChain) forgeResult(t *testing.T, index int) {
+	chain.signResult(t, []byte("other value"), index)
+}
+
+func (chain *wavesTestChain) signResult(t *testing.T, hash []byte, index int) {
+	sign, err := crypto.Sign(chain.oracles[index], hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey := testWavesPubKey(chain.oracles[index])
+	chain.results.signs[pubKey.ToString(account.Waves)] = sign.Bytes()
+}
+
+func (chain *wavesTestChain) confirm(t *testing.T, txId string) {
+	if err := chain.adaptor.WaitTx(txId, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (chain *wavesTestChain) pulseHash(t *testing.T, pulseId uint64) []byte {
+	return chain.data(t, chain.nebulaAddress, fmt.Sprintf("data_hash_%d", pulseId)).(*proto.BinaryDataEntry).Value
+}
+
+// consulSigns maps the keys of the consuls at indexes to what sign returns
+// for their adaptors.
+func (chain *wavesTestChain) consulSigns(t *testing.T, sign func(adaptor *WavesAdaptor) ([]byte, error), indexes ...int) map[account.OraclesPubKey][]byte {
+	signs := make(map[account.OraclesPubKey][]byte)
+	for _, i := range indexes {
+		adaptor := chain.adaptorOf(t, chain.consuls[i])
+		s, err := sign(adaptor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signs[adaptor.PubKey()] = s
+	}
+
+	return signs
+}
+
+func (chain *wavesTestChain) data(t *testing.T, address string, key string) proto.DataEntry {
+	entry := chain.node.Data(address, key)
+	if entry == nil {
+		t.Fatalf("%s: key %s is not found", address, key)
+	}
+
+	return entry
+}
+
+// The signs of a pulse are a base58 list in the order of the "oracles" entry,
+// with "nil" for a slot without a result or without an oracle.
+func TestWavesAddPulseSignList(t *testing.T) {
+	chain := newWavesTestChain(t)
+	ctx := context.Background()
+	hash, err := crypto.Keccak256([]byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oracles := strings.Split(testWavesPubKeys(chain.oracles), ",")
+	oracles[3] = base58.Encode([]byte{1})
+	chain.node.SetData(chain.nebulaAddress, &proto.StringDataEntry{Key: "oracles", Value: strings.Join(oracles, ",")})
+
+	// The result of an oracle that lost its slot does not count.
+	chain.signResults(t, hash[:], 3, 1, 4)
+	txId, err := chain.adaptor.AddPulse(chain.nebulaId, 1, nil, hash[:], ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txId != "" {
+		t.Fatal("pulse sent with the result of a removed oracle")
+	}
+
+	chain.signResults(t, hash[:], 0, 1, 4)
+	txId, err = chain.adaptor.AddPulse(chain.nebulaId, 1, nil, hash[:], ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.confirm(t, txId)
+	if pulseHash := chain.pulseHash(t, 1); string(pulseHash) != string(hash[:]) {
+		t.Errorf("pulse hash: expected %x, got %x", hash, pulseHash)
+	}
+}
+
+// A consul slot without a key is base58 of a zero byte in the consuls entry
+// and in the signed message.
+func TestWavesSendConsulsToGravityContract(t *testing.T) {
+	chain := newWavesTestChain(t)
+	ctx := context.Background()
+	newConsuls := newTestWavesKeys(t, "new consul", testKeyCount-1)
+	newConsulsPubKeys := append(testWavesPubKeyPointers(newConsuls), nil)
+	signConsuls := func(adaptor *WavesAdaptor) ([]byte, error) {
+		return adaptor.SignConsuls(newConsulsPubKeys, 1)
+	}
+
+	signs := chain.consulSigns(t, signConsuls, 0, 3)
+	if _, err := chain.adaptor.SendConsulsToGravityContract(newConsulsPubKeys, signs, 1, ctx); err == nil {
+		t.Fatal("consuls sent below the bft value")
+	}
+
+	signs = chain.consulSigns(t, signConsuls, 0, 2, 3)
+	txId, err := chain.adaptor.SendConsulsToGravityContract(newConsulsPubKeys, signs, 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.confirm(t, txId)
+
+	exist, err := chain.adaptor.RoundExist(1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exist {
+		t.Fatal("round 1 does not exist")
+	}
+	expected := testWavesPubKeys(newConsuls) + "," + base58.Encode([]byte{0})
+	consuls := chain.data(t, chain.gravityAddress, "consuls_1").(*proto.StringDataEntry)
+	if consuls.Value != expected {
+		t.Errorf("consuls: expected %s, got %s", expected, consuls.Value)
+	}
+	lastRound := chain.data(t, chain.gravityAddress, "last_round").(*proto.IntegerDataEntry)
+	if lastRound.Value != 1 {
+		t.Errorf("last round: expected 1, got %d", lastRound.Value)
+	}
+}
+
+// An oracle slot without a key is base58 of the byte 1 in the oracles entry.
+func TestWavesSetOraclesToNebula(t *testing.T) {
+	chain := newWavesTestChain(t)
+	ctx := context.Background()
+	newOracles := newTestWavesKeys(t, "new oracle", testKeyCount-1)
+	newOraclesPubKeys := append(testWavesPubKeyPointers(newOracles), nil)
+
+	signs := chain.consulSigns(t, func(adaptor *WavesAdaptor) ([]byte, error) {
+		return adaptor.SignOracles(chain.nebulaId, newOraclesPubKeys)
+	}, 4, 1, 0)
+	txId, err := chain.adaptor.SetOraclesToNebula(chain.nebulaId, newOraclesPubKeys, signs, 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.confirm(t, txId)
+
+	expected := testWavesPubKeys(newOracles) + "," + base58.Encode([]byte{1})
+	oracles := chain.data(t, chain.nebulaAddress, "oracles").(*proto.StringDataEntry)
+	if oracles.Value != expected {
+		t.Errorf("oracles: expected %s, got %s", expected, oracles.Value)
+	}
+	round := chain.data(t, chain.nebulaAddress, "last_round_1").(*proto.IntegerDataEntry)
+	if round.Value != 1 {
+		t.Errorf("round: expected 1, got %d", round.Value)
+	}
+
+	txId, err = chain.adaptor.SetOraclesToNebula(chain.nebulaId, newOraclesPubKeys, signs, 1, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txId != "" {
+		t.Fatal("oracles of round 1 are set twice")
+	}
+}
+
+func TestWavesSendValueToSubs(t *testing.T) {
+	chain := newWavesTestChain(t)
+	ctx := context.Background()
+
+	var value [8]byte
+	binary.BigEndian.PutUint64(value[:], 42)
+	hash, err := crypto.Keccak256(value[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	addTestPulse(t, chain, hash[:])
+
+	err = chain.adaptor.SendValueToSubs(chain.nebulaId, 1, &extractor.Data{Type: extractor.Int64, Value: "43"}, ctx)
+	if err == nil {
+		t.Fatal("nebula accepted a value of another hash")
+	}
+
+	err = chain.adaptor.SendValueToSubs(chain.nebulaId, 1, &extractor.Data{Type: extractor.Int64, Value: "42"}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := chain.data(t, chain.subscriberAddress, "1").(*proto.IntegerDataEntry)
+	if stored.Value != 42 {
+		t.Errorf("subscriber value: expected 42, got %d", stored.Value)
+	}
+
+	// The value must be attached in the block of its pulse.
+	chain.node.Mine()
+	err = chain.adaptor.SendValueToSubs(chain.nebulaId, 1, &extractor.Data{Type: extractor.Int64, Value: "42"}, ctx)
+	if err == nil {
+		t.Fatal("nebula accepted a value after the block of the pulse")
+	}
+}
+
+func TestWavesSendDecimalToSubs(t *testing.T) {
+	chain := newWavesTestChain(t)
+	ctx := context.Background()
+	chain.node.SetData(chain.nebulaAddress, &proto.IntegerDataEntry{Key: "type", Value: int64(Decimal)})
+
+	value := &extractor.Data{Type: extractor.Decimal, Value: "30125.55", Precision: 2}
+	encoded, err := abi.Encode(value, abi.DecimalType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := crypto.Keccak256(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addTestPulse(t, chain, hash[:])
+
+	err = chain.adaptor.SendValueToSubs(chain.nebulaId, 1, value, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := chain.data(t, chain.subscriberAddress, "1").(*proto.BinaryDataEntry)
+	if string(stored.Value) != string(encoded) {
+		t.Errorf("subscriber value: expected %x, got %x", encoded, stored.Value)
+	}
+}
@@ -0,0 +1,354 @@
+// Package wavesnode is an in-process stand-in for the REST API of a Waves node.
+// It serves the endpoints used by the Waves adaptor and executes invoke
+// script transactions with the semantics of the gravity.ride, nebula.ride and
+// subMockInt.ride contracts.
+package wavesnode
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/wavesplatform/gowaves/pkg/crypto"
+	"github.com/wavesplatform/gowaves/pkg/proto"
+)
+
+var (
+	ErrTxNotFound       = errors.New("transactions does not exist")
+	ErrUnsupportedTx    = errors.New("only invoke script transactions are supported")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrFunctionNotFound = errors.New("function is not found")
+)
+
+// Script is the contract set to an account.
+type Script int
+
+const (
+	NoScript Script = iota
+	GravityScript
+	NebulaScript
+	SubscriberScript
+)
+
+type accountState struct {
+	script Script
+	data   map[string]proto.DataEntry
+}
+
+// Node keeps the state of the accounts and executes every broadcast
+// transaction at once in the block at the current height.
+type Node struct {
+	mu       sync.Mutex
+	scheme   proto.Scheme
+	height   uint64
+	accounts map[string]*accountState
+	txs      map[string][]byte
+
+	server *httptest.Server
+}
+
+// New starts a node at height 1 for the chain scheme.
+func New(scheme proto.Scheme) *Node {
+	node := &Node{
+		scheme:   scheme,
+		height:   1,
+		accounts: make(map[string]*accountState),
+		txs:      make(map[string][]byte),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blocks/height", node.handleHeight)
+	mux.HandleFunc("/addresses/data/", node.handleData)
+	mux.HandleFunc("/transactions/broadcast", node.handleBroadcast)
+	mux.HandleFunc("/transactions/info/", node.handleTxInfo)
+	mux.HandleFunc("/transactions/unconfirmed/info/", node.handleUnconfirmedTxInfo)
+	node.server = httptest.NewServer(mux)
+
+	return node
+}
+
+// URL is the base url of the node API.
+func (node *Node) URL() string {
+	return node.server.URL
+}
+
+func (node *Node) Close() {
+	node.server.Close()
+}
+
+// Mine advances the node by one block.
+func (node *Node) Mine() {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.height++
+}
+
+func (node *Node) Height() uint64 {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return node.height
+}
+
+// SetScript sets the contract executed by the invoke calls to address.
+func (node *Node) SetScript(address string, script Script) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.account(address).script = script
+}
+
+// SetData writes entries to the state of address.
+func (node *Node) SetData(address string, entries ...proto.DataEntry) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	node.write(address, entries)
+}
+
+// Data returns the entry of address by key or nil.
+func (node *Node) Data(address string, key string) proto.DataEntry {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	return node.data(address, key)
+}
+
+func (node *Node) account(address string) *accountState {
+	state, ok := node.accounts[address]
+	if !ok {
+		state = &accountState{data: make(map[string]proto.DataEntry)}
+		node.accounts[address] = state
+	}
+
+	return state
+}
+
+func (node *Node) write(address string, entries []proto.DataEntry) {
+	state := node.account(address)
+	for _, v := range entries {
+		state.data[v.GetKey()] = v
+	}
+}
+
+func (node *Node) data(address string, key string) proto.DataEntry {
+	state, ok := node.accounts[address]
+	if !ok {
+		return nil
+	}
+
+	return state.data[key]
+}
+
+func (node *Node) getInteger(address string, key string) int64 {
+	entry, ok := node.data(address, key).(*proto.IntegerDataEntry)
+	if !ok {
+		return 0
+	}
+
+	return entry.Value
+}
+
+func (node *Node) getString(address string, key string) string {
+	entry, ok := node.data(address, key).(*proto.StringDataEntry)
+	if !ok {
+		return ""
+	}
+
+	return entry.Value
+}
+
+func (node *Node) getBinary(address string, key string) ([]byte, bool) {
+	entry, ok := node.data(address, key).(*proto.BinaryDataEntry)
+	if !ok {
+		return nil, false
+	}
+
+	return entry.Value, true
+}
+
+// invoke executes tx against the script of its dApp. The caller holds the
+// lock.
+func (node *Node) invoke(tx *proto.InvokeScriptWithProofs) error {
+	sender, err := proto.NewAddressFromPublicKey(node.scheme, tx.SenderPK)
+	if err != nil {
+		return err
+	}
+
+	// The verifier of a nebula account replaces the signature check of its
+	// invoke transactions.
+	if node.account(sender.String()).script == NebulaScript {
+		err := node.verifyValueTx(sender.String(), tx)
+		if err != nil {
+			return err
+		}
+	} else {
+		ok, err := tx.Verify(node.scheme, tx.SenderPK)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidSignature
+		}
+	}
+
+	if tx.ScriptRecipient.Address == nil {
+		return ErrUnsupportedTx
+	}
+	dApp := tx.ScriptRecipient.Address.String()
+	args := tx.FunctionCall.Arguments
+
+	var entries []proto.DataEntry
+	switch script, name := node.account(dApp).script, tx.FunctionCall.Name; {
+	case script == GravityScript && name == "updateConsuls":
+		entries, err = node.updateConsuls(dApp, args)
+	case script == NebulaScript && name == "sendHashValue":
+		entries, err = node.sendHashValue(dApp, args)
+	case script == NebulaScript && name == "updateOracles":
+		entries, err = node.updateOracles(dApp, args)
+	case script == SubscriberScript && name == "attachValue":
+		entries, err = attachValue(args)
+	default:
+		return ErrFunctionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	node.write(dApp, entries)
+	return nil
+}
+
+func (node *Node) handleHeight(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, struct {
+		Height uint64 `json:"height"`
+	}{node.Height()})
+}
+
+// handleData serves /addresses/data/{address} with an optional key query.
+func (node *Node) handleData(w http.ResponseWriter, r *http.Request) {
+	address := strings.TrimPrefix(r.URL.Path, "/addresses/data/")
+	key := r.URL.Query().Get("key")
+
+	node.mu.Lock()
+	entries := proto.DataEntries{}
+	if state, ok := node.accounts[address]; ok {
+		for k, v := range state.data {
+			if key == "" || key == k {
+				entries = append(entries, v)
+			}
+		}
+	}
+	node.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].GetKey() < entries[j].GetKey() })
+	writeJSON(w, entries)
+}
+
+func (node *Node) handleBroadcast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, ErrFunctionNotFound)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var txType struct {
+		Type proto.TransactionType `json:"type"`
+	}
+	err = json.Unmarshal(body, &txType)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if txType.Type != proto.InvokeScriptTransaction {
+		writeError(w, http.StatusBadRequest, ErrUnsupportedTx)
+		return
+	}
+
+	tx := new(proto.InvokeScriptWithProofs)
+	err = json.Unmarshal(body, tx)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tx.ChainID = node.scheme
+	tx.ID = nil
+	err = tx.GenerateID(node.scheme)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+
+	err = node.invoke(tx)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	node.txs[tx.ID.String()] = body
+	w.Write(body)
+}
+
+func (node *Node) handleTxInfo(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/transactions/info/")
+
+	node.mu.Lock()
+	tx, ok := node.txs[id]
+	node.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrTxNotFound)
+		return
+	}
+
+	w.Write(tx)
+}
+
+// handleUnconfirmedTxInfo finds nothing since every transaction is executed
+// when it is broadcast.
+func (node *Node) handleUnconfirmedTxInfo(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, ErrTxNotFound)
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error   int    `json:"error"`
+		Message string `json:"message"`
+	}{status, err.Error()})
+}
+
+// sigVerify follows sigVerify of Ride with base58 arguments and fails for
+// malformed ones.
+func sigVerify(msg []byte, sign string, pubKey string) bool {
+	signature, err := crypto.NewSignatureFromBase58(sign)
+	if err != nil {
+		return false
+	}
+	publicKey, err := crypto.NewPublicKeyFromBase58(pubKey)
+	if err != nil {
+		return false
+	}
+
+	return crypto.Verify(publicKey, signature, msg)
+}
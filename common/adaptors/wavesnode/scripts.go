@@ -0,0 +1,315 @@
+package wavesnode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/wavesplatform/gowaves/pkg/crypto"
+	"github.com/wavesplatform/gowaves/pkg/proto"
+)
+
+// SignsCount is the number of keys whose signs the contracts check.
+const SignsCount = 5
+
+// The value types of a nebula.
+const (
+	IntType     = 0
+	StringType  = 1
+	BytesType   = 2
+	DecimalType = 3
+	Uint256Type = 4
+	ArrayType   = 5
+	RecordType  = 6
+)
+
+// Errors thrown by the contracts.
+var (
+	ErrIndexOutOfBounds    = errors.New("index out of bounds")
+	ErrInvalidArgs         = errors.New("invalid arguments")
+	ErrRoundLessLastRound  = errors.New("round less last round")
+	ErrInvalidBftCount     = errors.New("invalid bft count")
+	ErrDataExist           = errors.New("data is exist")
+	ErrInvalidValueType    = errors.New("invalid value type")
+	ErrInvalidHeightType   = errors.New("invalid height type")
+	ErrInvalidFunctionName = errors.New("invalid function name")
+	ErrInvalidArgsSize     = errors.New("invalid args size")
+	ErrInvalidDApp         = errors.New("invalid dapp address")
+	ErrInvalidHeight       = errors.New("invalid height")
+	ErrInvalidPulseId      = errors.New("invalid pulse id")
+	ErrInvalidHash         = errors.New("invalid keccak256(value)")
+)
+
+func consulsKey(round int64) string {
+	return "consuls_" + strconv.FormatInt(round, 10)
+}
+
+// signCount counts the signs verified by validateSign of the contracts.
+func signCount(msg []byte, signs []string, pubKeys []string) (int, error) {
+	if len(signs) < SignsCount || len(pubKeys) < SignsCount {
+		return 0, ErrIndexOutOfBounds
+	}
+
+	count := 0
+	for i := 0; i < SignsCount; i++ {
+		if signs[i] != "nil" && sigVerify(msg, signs[i], pubKeys[i]) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// updateConsuls of gravity.ride.
+func (node *Node) updateConsuls(dApp string, args proto.Arguments) ([]proto.DataEntry, error) {
+	if len(args) != 3 {
+		return nil, ErrInvalidArgs
+	}
+	newConsuls, err := stringArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	stringSigns, err := stringArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	round, err := integerArg(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	lastRound := node.getInteger(dApp, "last_round")
+	if round <= lastRound {
+		return nil, ErrRoundLessLastRound
+	}
+
+	consuls := strings.Split(node.getString(dApp, consulsKey(lastRound)), ",")
+	msg := []byte(newConsuls + "," + strconv.FormatInt(round, 10))
+	count, err := signCount(msg, strings.Split(stringSigns, ","), consuls)
+	if err != nil {
+		return nil, err
+	}
+	if int64(count) < node.getInteger(dApp, "bft_coefficient") {
+		return nil, ErrInvalidBftCount
+	}
+
+	return []proto.DataEntry{
+		&proto.StringDataEntry{Key: consulsKey(round), Value: newConsuls},
+		&proto.IntegerDataEntry{Key: "last_round", Value: round},
+	}, nil
+}
+
+// sendHashValue of nebula.ride.
+func (node *Node) sendHashValue(dApp string, args proto.Arguments) ([]proto.DataEntry, error) {
+	if len(args) != 2 {
+		return nil, ErrInvalidArgs
+	}
+	hash, err := binaryArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	signs, err := stringArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	oracles := strings.Split(node.getString(dApp, "oracles"), ",")
+	count, err := signCount(hash, strings.Split(signs, ","), oracles)
+	if err != nil {
+		return nil, err
+	}
+	if int64(count) < node.getInteger(dApp, "bft_coefficient") {
+		return nil, ErrInvalidBftCount
+	}
+
+	height := int64(node.height)
+	if _, ok := node.getBinary(dApp, strconv.FormatInt(height, 10)); ok {
+		return nil, ErrDataExist
+	}
+
+	pulseId := node.getInteger(dApp, "last_pulse_id") + 1
+	return []proto.DataEntry{
+		&proto.BinaryDataEntry{Key: "data_hash_" + strconv.FormatInt(pulseId, 10), Value: hash},
+		&proto.IntegerDataEntry{Key: "height_" + strconv.FormatInt(pulseId, 10), Value: height},
+		&proto.IntegerDataEntry{Key: "last_height", Value: height},
+		&proto.IntegerDataEntry{Key: "last_pulse_id", Value: pulseId},
+	}, nil
+}
+
+// updateOracles of nebula.ride, which checks the signs of the consuls of the
+// last round of its gravity contract.
+func (node *Node) updateOracles(dApp string, args proto.Arguments) ([]proto.DataEntry, error) {
+	if len(args) != 3 {
+		return nil, ErrInvalidArgs
+	}
+	newOracles, err := stringArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	stringSigns, err := stringArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	round, err := integerArg(args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	gravityContract, err := proto.NewAddressFromString(node.getString(dApp, "gravity_contract"))
+	if err != nil {
+		return nil, err
+	}
+	lastRound := node.getInteger(gravityContract.String(), "last_round")
+	consuls := strings.Split(node.getString(gravityContract.String(), consulsKey(lastRound)), ",")
+
+	count, err := signCount([]byte(newOracles), strings.Split(stringSigns, ","), consuls)
+	if err != nil {
+		return nil, err
+	}
+	// The contract throws when the count is above the bft coefficient, not
+	// below it.
+	if int64(count) > node.getInteger(dApp, "bft_coefficient") {
+		return nil, ErrInvalidBftCount
+	}
+
+	return []proto.DataEntry{
+		&proto.StringDataEntry{Key: "oracles", Value: newOracles},
+		&proto.IntegerDataEntry{Key: "last_round_" + strconv.FormatInt(round, 10), Value: round},
+	}, nil
+}
+
+// verifyValueTx is the verifier of nebula.ride for the attachValue call a
+// nebula sends to its subscriber.
+func (node *Node) verifyValueTx(nebula string, tx *proto.InvokeScriptWithProofs) error {
+	args := tx.FunctionCall.Arguments
+	if tx.FunctionCall.Name != "attachValue" {
+		return ErrInvalidFunctionName
+	}
+	if len(args) != 2 {
+		return ErrInvalidArgsSize
+	}
+
+	subscriber, err := proto.NewAddressFromString(node.getString(nebula, "subscriber_address"))
+	if err != nil {
+		return err
+	}
+	if tx.ScriptRecipient.Address == nil || *tx.ScriptRecipient.Address != subscriber {
+		return ErrInvalidDApp
+	}
+
+	pulseId, err := integerArg(args[1])
+	if err != nil {
+		return ErrInvalidHeightType
+	}
+	if node.getInteger(nebula, "height_"+strconv.FormatInt(pulseId, 10)) != int64(node.height) {
+		return ErrInvalidHeight
+	}
+
+	hash, ok := node.getBinary(nebula, "data_hash_"+strconv.FormatInt(pulseId, 10))
+	if !ok {
+		return ErrInvalidPulseId
+	}
+
+	value, err := valueBytes(node.getInteger(nebula, "type"), args[0])
+	if err != nil {
+		return err
+	}
+	valueHash, err := crypto.Keccak256(value)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(valueHash.Bytes(), hash) {
+		return ErrInvalidHash
+	}
+
+	return nil
+}
+
+// valueBytes is toBytes of Ride for a value of the nebula type.
+func valueBytes(valueType int64, arg proto.Argument) ([]byte, error) {
+	switch valueType {
+	case IntType:
+		v, err := integerArg(arg)
+		if err != nil {
+			return nil, ErrInvalidValueType
+		}
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], uint64(v))
+		return b[:], nil
+	case StringType:
+		v, err := stringArg(arg)
+		if err != nil {
+			return nil, ErrInvalidValueType
+		}
+		return []byte(v), nil
+	case BytesType, DecimalType, Uint256Type, ArrayType, RecordType:
+		v, err := binaryArg(arg)
+		if err != nil {
+			return nil, ErrInvalidValueType
+		}
+		return v, nil
+	default:
+		return nil, ErrInvalidValueType
+	}
+}
+
+// attachValue of subMockInt.ride, which stores the value by its pulse id.
+// Values of every nebula type are accepted.
+func attachValue(args proto.Arguments) ([]proto.DataEntry, error) {
+	if len(args) != 2 {
+		return nil, ErrInvalidArgs
+	}
+	pulseId, err := integerArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := strconv.FormatInt(pulseId, 10)
+	if v, err := integerArg(args[0]); err == nil {
+		return []proto.DataEntry{&proto.IntegerDataEntry{Key: key, Value: v}}, nil
+	}
+	if v, err := stringArg(args[0]); err == nil {
+		return []proto.DataEntry{&proto.StringDataEntry{Key: key, Value: v}}, nil
+	}
+	v, err := binaryArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return []proto.DataEntry{&proto.BinaryDataEntry{Key: key, Value: v}}, nil
+}
+
+func integerArg(arg proto.Argument) (int64, error) {
+	switch v := arg.(type) {
+	case *proto.IntegerArgument:
+		return v.Value, nil
+	case proto.IntegerArgument:
+		return v.Value, nil
+	default:
+		return 0, ErrInvalidArgs
+	}
+}
+
+func stringArg(arg proto.Argument) (string, error) {
+	switch v := arg.(type) {
+	case *proto.StringArgument:
+		return v.Value, nil
+	case proto.StringArgument:
+		return v.Value, nil
+	default:
+		return "", ErrInvalidArgs
+	}
+}
+
+func binaryArg(arg proto.Argument) ([]byte, error) {
+	switch v := arg.(type) {
+	case *proto.BinaryArgument:
+		return v.Value, nil
+	case proto.BinaryArgument:
+		return v.Value, nil
+	default:
+		return nil, ErrInvalidArgs
+	}
+}
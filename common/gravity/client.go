@@ -1,57 +1,258 @@
 package gravity
 
 import (
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sync"
+	"time"
 
 	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/state"
 	"github.com/Gravity-Tech/gravity-core/common/storage"
 	"github.com/Gravity-Tech/gravity-core/common/transactions"
 	"github.com/Gravity-Tech/gravity-core/ledger/query"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/light"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
 	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 )
 
 const (
 	InternalServerErrCode = 500
 	NotFoundCode          = 404
+	// InvalidNonceCode is the CheckTx code of a transaction with another
+	// nonce than the next one of the sender. Its data is the expected nonce.
+	InvalidNonceCode = 409
+
+	// MaxNonceRetries bounds the times a transaction is signed again with
+	// the nonce the ledger expects.
+	MaxNonceRetries = 3
+	// DefaultTxTimeout is how long SendTx waits for a transaction accepted
+	// by CheckTx to be committed, as long as BroadcastTxCommit of tendermint
+	// waits by default.
+	DefaultTxTimeout = 10 * time.Second
+	// DefaultTxPollInterval is the interval of the queries of a transaction
+	// that is not committed yet.
+	DefaultTxPollInterval = 500 * time.Millisecond
 )
 
 var (
 	ErrValueNotFound  = errors.New("value not found")
 	ErrInternalServer = errors.New("internal server error")
+	ErrTxTimeout      = errors.New("transaction is not committed")
 )
 
+// RPCClient is the part of the tendermint rpc client used by Client.
+type RPCClient interface {
+	rpcclient.ABCIClient
+	rpcclient.StatusClient
+	rpcclient.EventsClient
+
+	// Tx is the method of rpcclient.SignClient that returns a committed
+	// transaction.
+	Tx(ctx context.Context, hash []byte, prove bool) (*ctypes.ResultTx, error)
+}
+
 type Client struct {
-	Host       string
-	HttpClient *rpchttp.HTTP
+	Host      string
+	RPCClient RPCClient
+
+	verifier *verifier
+	height   int64
+	nonces   *nonces
+	blocks   *blockFeed
+
+	txTimeout      time.Duration
+	txPollInterval time.Duration
+}
+
+// nonces caches the chain id and the next nonce of every sender. It is shared
+// by the copies returned from AtHeight. Other clients may send transactions
+// of the same sender, e.g. the ledger and the oracle processes of a consul,
+// so the ledger corrects the cached nonce when it rejects one.
+type nonces struct {
+	sync.Mutex
+	chainId string
+	next    map[account.ConsulPubKey]uint64
 }
 
-func New(host string) (*Client, error) {
-	client, err := rpchttp.New(host, "/websocket")
+type Option func(*Client) error
+
+func WithLightClient(lightClient *light.Client) Option {
+	return func(client *Client) error {
+		client.verifier = newVerifier(lightClient)
+		return nil
+	}
+}
+
+// WithTxPolling sets how long SendTx waits for a transaction to be committed
+// and how often it queries the transaction meanwhile.
+func WithTxPolling(timeout time.Duration, pollInterval time.Duration) Option {
+	return func(client *Client) error {
+		client.txTimeout = timeout
+		client.txPollInterval = pollInterval
+		return nil
+	}
+}
+
+func New(host string, opts ...Option) (*Client, error) {
+	httpClient, err := rpchttp.New(host, "/websocket")
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewWithRPC(httpClient, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return &Client{Host: host, HttpClient: client}, nil
+	client.Host = host
+
+	return client, nil
 }
 
-func (client *Client) SendTx(transaction *transactions.Transaction) error {
-	txBytes, err := json.Marshal(transaction)
+// NewWithRPC returns a client that talks to the ledger through rpcClient, e.g.
+// an in-process node.
+func NewWithRPC(rpcClient RPCClient, opts ...Option) (*Client, error) {
+	client := &Client{
+		RPCClient: rpcClient,
+		nonces: &nonces{
+			next: make(map[account.ConsulPubKey]uint64),
+		},
+		blocks: &blockFeed{
+			subscribers:  make(map[chan int64]struct{}),
+			timeout:      DefaultBlockTimeout,
+			pollInterval: DefaultBlockPollInterval,
+		},
+		txTimeout:      DefaultTxTimeout,
+		txPollInterval: DefaultTxPollInterval,
+	}
+	for _, opt := range opts {
+		err := opt(client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+// AtHeight returns a client whose queries read the ledger state committed at
+// the given height. Zero means the latest state.
+func (client *Client) AtHeight(height int64) *Client {
+	c := *client
+	c.height = height
+	return &c
+}
+
+// SendTx sets the next nonce of the sender, signs the transaction for the
+// ledger chain and broadcasts it, then waits for it to be committed. When
+// CheckTx rejects the nonce, the transaction is signed again with the nonce
+// the ledger expects. The nonces are locked only until CheckTx returns, so
+// the transactions of several senders of a client share a block.
+func (client *Client) SendTx(transaction *transactions.Transaction, privKey crypto.PrivKey) error {
+	hash, err := client.broadcastTx(transaction, privKey)
 	if err != nil {
 		return err
 	}
 
-	rs, err := client.HttpClient.BroadcastTxCommit(txBytes)
+	rs, err := client.waitTx(hash)
 	if err != nil {
 		return err
 	}
-	if rs.CheckTx.Code == InternalServerErrCode {
-		return errors.New(rs.CheckTx.Info)
-	} else if rs.DeliverTx.Code == InternalServerErrCode {
-		return errors.New(rs.DeliverTx.Info)
+	if rs.TxResult.Code != 0 {
+		return errors.New(rs.TxResult.Info)
+	}
+	return nil
+}
+
+// broadcastTx signs the transaction with the next nonce of the sender and
+// returns its hash once CheckTx accepts it.
+func (client *Client) broadcastTx(transaction *transactions.Transaction, privKey crypto.PrivKey) ([]byte, error) {
+	client.nonces.Lock()
+	defer client.nonces.Unlock()
+
+	if client.nonces.chainId == "" {
+		status, err := client.RPCClient.Status(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		client.nonces.chainId = status.NodeInfo.Network
+	}
+
+	sender := transaction.SenderPubKey
+	nonce, ok := client.nonces.next[sender]
+	if !ok {
+		var err error
+		nonce, err = client.AtHeight(0).Nonce(sender)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for retries := 0; ; retries++ {
+		transaction.Nonce = nonce
+		err := transaction.Sign(client.nonces.chainId, privKey)
+		if err != nil {
+			return nil, err
+		}
+
+		rs, err := client.RPCClient.BroadcastTxSync(context.Background(), transaction.Marshal())
+		if err != nil {
+			delete(client.nonces.next, sender)
+			return nil, err
+		}
+		if rs.Code == InvalidNonceCode && len(rs.Data) == 8 && retries < MaxNonceRetries {
+			nonce = binary.BigEndian.Uint64(rs.Data)
+			continue
+		}
+		if rs.Code != 0 {
+			delete(client.nonces.next, sender)
+			return nil, errors.New(rs.Log)
+		}
+
+		client.nonces.next[sender] = nonce + 1
+		return rs.Hash, nil
+	}
+}
+
+// waitTx queries the transaction until it is committed or the timeout of the
+// client is over.
+func (client *Client) waitTx(hash []byte) (*ctypes.ResultTx, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), client.txTimeout)
+	defer cancel()
+
+	for {
+		rs, err := client.RPCClient.Tx(ctx, hash, false)
+		if err == nil {
+			return rs, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %X: %s", ErrTxTimeout, hash, err)
+		case <-time.After(client.txPollInterval):
+		}
+	}
+}
+
+func (client *Client) Nonce(pubKey account.ConsulPubKey) (uint64, error) {
+	rq := query.ByValidatorRq{
+		PubKey: hexutil.Encode(pubKey[:]),
 	}
-	return err
+
+	rs, err := client.do(query.NoncePath, rq)
+	if err == ErrValueNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint64(rs), nil
 }
 
 func (client *Client) OraclesByValidator(pubKey account.ConsulPubKey) (storage.OraclesByTypeMap, error) {
@@ -187,7 +388,7 @@ func (client *Client) CommitHash(chainType account.ChainType, nebulaId account.N
 
 	return rs, nil
 }
-func (client *Client) Reveal(chainType account.ChainType, oraclePubKey account.OraclesPubKey, nebulaId account.NebulaId, height int64, pulseId int64, commitHash []byte) ([]byte, error) {
+func (client *Client) Reveal(chainType account.ChainType, oraclePubKey account.OraclesPubKey, nebulaId account.NebulaId, height int64, pulseId int64, commitHash []byte) (*storage.RevealValue, error) {
 	rq := query.RevealRq{
 		ChainType:     chainType,
 		NebulaAddress: nebulaId.ToString(chainType),
@@ -202,9 +403,15 @@ func (client *Client) Reveal(chainType account.ChainType, oraclePubKey account.O
 		return nil, err
 	}
 
-	return rs, nil
+	var reveal storage.RevealValue
+	err = json.Unmarshal(rs, &reveal)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reveal, nil
 }
-func (client *Client) Reveals(chainType account.ChainType, nebulaId account.NebulaId, height int64, pulseId int64) ([]string, error) {
+func (client *Client) Reveals(chainType account.ChainType, nebulaId account.NebulaId, height int64, pulseId int64) ([]storage.RevealValue, error) {
 	rq := query.RevealRq{
 		ChainType:     chainType,
 		NebulaAddress: nebulaId.ToString(chainType),
@@ -217,7 +424,7 @@ func (client *Client) Reveals(chainType account.ChainType, nebulaId account.Nebu
 		return nil, err
 	}
 
-	var reveals []string
+	var reveals []storage.RevealValue
 	if err == ErrValueNotFound {
 		return reveals, nil
 	}
@@ -229,6 +436,32 @@ func (client *Client) Reveals(chainType account.ChainType, nebulaId account.Nebu
 
 	return reveals, nil
 }
+
+// ScoredReveals returns the reveals of the pulse with the scores of their
+// oracles, sorted by oracle.
+func (client *Client) ScoredReveals(chainType account.ChainType, nebulaId account.NebulaId, height int64, pulseId int64) ([]query.ScoredReveal, error) {
+	rq := query.RevealRq{
+		ChainType:     chainType,
+		NebulaAddress: nebulaId.ToString(chainType),
+		Height:        height,
+		PulseId:       pulseId,
+	}
+
+	rs, err := client.do(query.ScoredRevealsPath, rq)
+	if err == ErrValueNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var reveals []query.ScoredReveal
+	err = json.Unmarshal(rs, &reveals)
+	if err != nil {
+		return nil, err
+	}
+
+	return reveals, nil
+}
 func (client *Client) Result(chainType account.ChainType, nebulaId account.NebulaId, height int64, oraclePubKey account.OraclesPubKey) ([]byte, error) {
 	rq := query.ResultRq{
 		ChainType:     chainType,
@@ -262,6 +495,24 @@ func (client *Client) NebulaInfo(id account.NebulaId, chainType account.ChainTyp
 
 	return &nebulaInfo, nil
 }
+func (client *Client) PulseWindow(id account.NebulaId, chainType account.ChainType) (*state.PulseWindow, error) {
+	rq := query.ByNebulaRq{
+		ChainType:     chainType,
+		NebulaAddress: id.ToString(chainType),
+	}
+
+	rs, err := client.do(query.PulseWindowPath, rq)
+	if err != nil {
+		return nil, err
+	}
+	var window state.PulseWindow
+	err = json.Unmarshal(rs, &window)
+	if err != nil {
+		return nil, err
+	}
+
+	return &window, nil
+}
 func (client *Client) Nebulae() (storage.NebulaMap, error) {
 	rs, err := client.do(query.NebulaePath, nil)
 	if err != nil && err != ErrValueNotFound {
@@ -369,7 +620,11 @@ func (client *Client) do(path query.Path, rq interface{}) ([]byte, error) {
 		}
 	}
 
-	rs, err := client.HttpClient.ABCIQuery(string(path), b)
+	if client.verifier != nil {
+		return client.doVerified(path, b)
+	}
+
+	rs, err := client.RPCClient.ABCIQueryWithOptions(context.Background(), string(path), b, rpcclient.ABCIQueryOptions{Height: client.height})
 	if err != nil {
 		return nil, err
 	} else if rs.Response.Code == InternalServerErrCode {
@@ -380,3 +635,15 @@ func (client *Client) do(path query.Path, rq interface{}) ([]byte, error) {
 
 	return rs.Response.Value, nil
 }
+
+func (client *Client) doVerified(path query.Path, rq []byte) ([]byte, error) {
+	ctx := context.Background()
+	rs, err := client.RPCClient.ABCIQueryWithOptions(ctx, string(path), rq, rpcclient.ABCIQueryOptions{Height: client.height, Prove: true})
+	if err != nil {
+		return nil, err
+	} else if rs.Response.Code == InternalServerErrCode {
+		return nil, ErrInternalServer
+	}
+
+	return client.verifier.verify(ctx, path, rq, rs.Response)
+}
@@ -0,0 +1,345 @@
+package gravity_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/abi"
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/common/state"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/Gravity-Tech/gravity-core/common/transactions"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"github.com/Gravity-Tech/gravity-core/simulation"
+	"github.com/ethereum/go-ethereum/crypto"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// forgingRPC answers queries with a value that differs from the proven one.
+type forgingRPC struct {
+	gravity.RPCClient
+}
+
+func (rpc *forgingRPC) ABCIQueryWithOptions(ctx context.Context, path string, data tmbytes.HexBytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	rs, err := rpc.RPCClient.ABCIQueryWithOptions(ctx, path, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	rs.Response.Value = []byte("[]")
+
+	return rs, nil
+}
+
+// startPulses runs an oracle for every validator of the network until the
+// nebula has its first pulse.
+func startPulses(t *testing.T, ctx context.Context, network *simulation.Network, nebulaId account.NebulaId) {
+	value := extractor.Data{Type: extractor.Int64, Value: "42"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(value)
+	}))
+	t.Cleanup(server.Close)
+
+	network.Chain().AddNebula(nebulaId, abi.Int64Type, network.Chain().Consuls(0), simulation.Bft(len(network.Validators())))
+	network.Start(time.Millisecond)
+	if err := network.WaitHeight(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := network.Validators()[0]
+	tx := transactions.New(owner.PubKey, &transactions.SetNebulaArgs{
+		NebulaId: nebulaId,
+		Info: storage.NebulaInfo{
+			MaxPulseCountInBlock: 1,
+			ChainType:            account.Ethereum,
+			Owner:                owner.PubKey,
+			Aggregator:           "median",
+		},
+	})
+	if err := owner.Client.SendTx(tx, owner.PrivKey); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range network.Validators() {
+		oracle, err := network.NewOracle(i, nebulaId, server.URL, 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := oracle.Init(); err != nil {
+			t.Fatal(err)
+		}
+		oracle.PollInterval = 10 * time.Millisecond
+		go oracle.Start(ctx)
+	}
+
+	if err := network.WaitHeight(ctx, state.CalculateScoreInterval); err != nil {
+		t.Fatal(err)
+	}
+	network.SetBlockInterval(50 * time.Millisecond)
+
+	for {
+		lastPulseId, err := network.Chain().LastPulseId(nebulaId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lastPulseId >= 1 {
+			return
+		}
+		if err := network.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("no pulse")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func TestLightClientQueries(t *testing.T) {
+	network, err := simulation.New(simulation.Config{Validators: 4, ChainType: account.Ethereum})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	nebulaId := account.BytesToNebulaId(crypto.Keccak256([]byte("nebula"))[:account.EthereumAddressLength])
+	startPulses(t, ctx, network, nebulaId)
+
+	lightClient, err := network.NewLightClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, err := gravity.NewWithRPC(network.RPC(0), gravity.WithLightClient(lightClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	height := network.Height() - 1
+	plain := network.Validators()[0].Client.AtHeight(height)
+	verified = verified.AtHeight(height)
+
+	reveals, err := verified.Reveals(account.Ethereum, nebulaId, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reveals) == 0 {
+		t.Fatal("no proven reveals")
+	}
+	expectedReveals, err := plain.Reveals(account.Ethereum, nebulaId, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reveals, expectedReveals) {
+		t.Errorf("reveals: expected %v, got %v", expectedReveals, reveals)
+	}
+
+	scored, err := verified.ScoredReveals(account.Ethereum, nebulaId, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedScored, err := plain.ScoredReveals(account.Ethereum, nebulaId, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(scored, expectedScored) {
+		t.Errorf("scored reveals: expected %v, got %v", expectedScored, scored)
+	}
+
+	window, err := verified.PulseWindow(nebulaId, account.Ethereum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedWindow, err := plain.PulseWindow(nebulaId, account.Ethereum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *window != *expectedWindow {
+		t.Errorf("pulse window: expected %v, got %v", expectedWindow, window)
+	}
+
+	nebulae, err := verified.Nebulae()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := nebulae[nebulaId.ToString(account.Ethereum)]; !ok {
+		t.Error("proven nebulae miss the nebula")
+	}
+
+	if _, err := verified.CommitHash(account.Ethereum, nebulaId, 0, 1<<20, account.OraclesPubKey{}); err != gravity.ErrValueNotFound {
+		t.Errorf("absent commit: expected %v, got %v", gravity.ErrValueNotFound, err)
+	}
+
+	forged, err := gravity.NewWithRPC(&forgingRPC{RPCClient: network.RPC(0)}, gravity.WithLightClient(lightClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = forged.AtHeight(height).Reveals(account.Ethereum, nebulaId, 0, 1)
+	if !errors.Is(err, gravity.ErrInvalidProof) {
+		t.Errorf("forged reveals: expected %v, got %v", gravity.ErrInvalidProof, err)
+	}
+}
+
+// eventsRPC serves NewBlock events through the channels of its subscriptions
+// and the height of the ledger through Status.
+type eventsRPC struct {
+	gravity.RPCClient
+	sync.Mutex
+	height        int64
+	subscriptions []chan ctypes.ResultEvent
+}
+
+func (rpc *eventsRPC) Status(ctx context.Context) (*ctypes.ResultStatus, error) {
+	rpc.Lock()
+	defer rpc.Unlock()
+
+	return &ctypes.ResultStatus{SyncInfo: ctypes.SyncInfo{LatestBlockHeight: rpc.height}}, nil
+}
+
+func (rpc *eventsRPC) Subscribe(ctx context.Context, subscriber, query string, outCapacity ...int) (<-chan ctypes.ResultEvent, error) {
+	rpc.Lock()
+	defer rpc.Unlock()
+
+	events := make(chan ctypes.ResultEvent, 1)
+	rpc.subscriptions = append(rpc.subscriptions, events)
+	return events, nil
+}
+
+func (rpc *eventsRPC) Unsubscribe(ctx context.Context, subscriber, query string) error {
+	return nil
+}
+
+// commit sets the height of the ledger and sends it to the latest
+// subscription unless silent.
+func (rpc *eventsRPC) commit(height int64, silent bool) {
+	rpc.Lock()
+	defer rpc.Unlock()
+
+	rpc.height = height
+	if !silent {
+		rpc.subscriptions[len(rpc.subscriptions)-1] <- ctypes.ResultEvent{
+			Data: types.EventDataNewBlock{Block: &types.Block{Header: types.Header{Height: height}}},
+		}
+	}
+}
+
+func (rpc *eventsRPC) subscriptionCount() int {
+	rpc.Lock()
+	defer rpc.Unlock()
+
+	return len(rpc.subscriptions)
+}
+
+func TestBlockFeedResubscribes(t *testing.T) {
+	rpc := &eventsRPC{}
+	client, err := gravity.NewWithRPC(rpc, gravity.WithBlockPolling(20*time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	heights, err := client.SubscribeNewBlocks(ctx, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitHeight := func(expected int64) {
+		t.Helper()
+		for {
+			select {
+			case height := <-heights:
+				if height == expected {
+					return
+				}
+			case <-ctx.Done():
+				t.Fatalf("height %d is not sent", expected)
+			}
+		}
+	}
+
+	rpc.commit(1, false)
+	waitHeight(1)
+
+	// The ledger goes on without events, so the heights are polled and the
+	// feed subscribes again.
+	rpc.commit(2, true)
+	waitHeight(2)
+	for rpc.subscriptionCount() < 2 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("feed does not subscribe again")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	rpc.commit(3, false)
+	waitHeight(3)
+}
+
+// TestSharedSender sends the transactions of one consul through two clients,
+// like its ledger and oracle processes do, while both have some in the
+// mempool.
+func TestSharedSender(t *testing.T) {
+	network, err := simulation.New(simulation.Config{Validators: 1, ChainType: account.Ethereum})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	network.Start(20 * time.Millisecond)
+	if err := network.WaitHeight(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := network.Validators()[0]
+	var clients []*gravity.Client
+	for i := 0; i < 2; i++ {
+		client, err := network.NewClient(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, client)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			nebulaId := account.BytesToNebulaId(crypto.Keccak256([]byte{byte(i)})[:account.EthereumAddressLength])
+			tx := transactions.New(owner.PubKey, &transactions.SetNebulaArgs{
+				NebulaId: nebulaId,
+				Info: storage.NebulaInfo{
+					MaxPulseCountInBlock: 1,
+					ChainType:            account.Ethereum,
+					Owner:                owner.PubKey,
+				},
+			})
+			errs <- clients[i%len(clients)].SendTx(tx, owner.PrivKey)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package gravity
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/service"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// DefaultBlockTimeout is how long the feed waits for a NewBlock event
+	// before it asks the ledger whether blocks were committed without one.
+	DefaultBlockTimeout = 10 * time.Second
+	// DefaultBlockPollInterval is the interval of the heights polled while
+	// the subscription is lost.
+	DefaultBlockPollInterval = time.Second
+	// MaxResubscribeInterval bounds the wait between two attempts to
+	// subscribe again.
+	MaxResubscribeInterval = time.Minute
+)
+
+// blockFeed shares one NewBlock subscription between the subscribers of a
+// client and its copies.
+type blockFeed struct {
+	sync.Mutex
+	name        string
+	cancel      context.CancelFunc
+	subscribers map[chan int64]struct{}
+
+	timeout      time.Duration
+	pollInterval time.Duration
+}
+
+// WithBlockPolling sets how long the block feed waits for an event before it
+// checks the subscription, and how often it polls the height of the ledger
+// while the subscription is lost.
+func WithBlockPolling(timeout time.Duration, pollInterval time.Duration) Option {
+	return func(client *Client) error {
+		client.blocks.timeout = timeout
+		client.blocks.pollInterval = pollInterval
+		return nil
+	}
+}
+
+// SubscribeNewBlocks streams the heights of the blocks committed by the ledger
+// from its NewBlock events. The websocket of the rpc client is started when it
+// is not running yet. All subscribers share one subscription named after the
+// first of them. When the events stop while the ledger commits blocks, e.g.
+// the websocket reconnected without the subscription, the heights are polled
+// and the feed subscribes again with a backoff. The channel keeps only the
+// latest height, so a slow reader skips blocks, and it is closed when ctx is
+// done.
+func (client *Client) SubscribeNewBlocks(ctx context.Context, subscriber string) (<-chan int64, error) {
+	feed := client.blocks
+	feed.Lock()
+	defer feed.Unlock()
+
+	if feed.cancel == nil {
+		err := client.subscribeBlockFeed(subscriber)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	heights := make(chan int64, 1)
+	feed.subscribers[heights] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+
+		feed.Lock()
+		defer feed.Unlock()
+
+		delete(feed.subscribers, heights)
+		close(heights)
+
+		if len(feed.subscribers) == 0 && feed.cancel != nil {
+			feed.cancel()
+			feed.cancel = nil
+			client.RPCClient.Unsubscribe(context.Background(), feed.name, types.EventQueryNewBlock.String())
+		}
+	}()
+
+	return heights, nil
+}
+
+// subscribeBlockFeed starts the subscription of the feed. The caller holds
+// the lock of the feed.
+func (client *Client) subscribeBlockFeed(subscriber string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := client.subscribeEvents(ctx, subscriber)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	feed := client.blocks
+	feed.name = subscriber
+	feed.cancel = cancel
+
+	go client.runBlockFeed(ctx, subscriber, events)
+
+	return nil
+}
+
+func (client *Client) subscribeEvents(ctx context.Context, subscriber string) (<-chan ctypes.ResultEvent, error) {
+	if s, ok := client.RPCClient.(service.Service); ok && !s.IsRunning() {
+		err := s.Start()
+		if err != nil && err != service.ErrAlreadyStarted {
+			return nil, err
+		}
+	}
+
+	return client.RPCClient.Subscribe(ctx, subscriber, types.EventQueryNewBlock.String())
+}
+
+// runBlockFeed sends the heights of the events to the subscribers until ctx
+// is done. The subscription is lost when the events are closed or when the
+// ledger is higher than the last event after a timeout of the feed. The
+// heights are polled then, and the feed subscribes again right away and after
+// every backoff, which doubles up to MaxResubscribeInterval, until an event
+// arrives.
+func (client *Client) runBlockFeed(ctx context.Context, subscriber string, events <-chan ctypes.ResultEvent) {
+	feed := client.blocks
+
+	var lastHeight int64
+	var lost bool
+	var backoff time.Duration
+	var resubscribeAt time.Time
+	for {
+		wait := feed.timeout
+		if lost {
+			wait = feed.pollInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				if !lost {
+					lost, backoff, resubscribeAt = true, feed.timeout, time.Now()
+				}
+				continue
+			}
+			data, ok := event.Data.(types.EventDataNewBlock)
+			if !ok || data.Block == nil {
+				continue
+			}
+			lost = false
+			if data.Block.Height > lastHeight {
+				lastHeight = data.Block.Height
+				feed.send(ctx, lastHeight)
+			}
+			continue
+		case <-time.After(wait):
+		}
+
+		status, err := client.RPCClient.Status(ctx)
+		if err != nil {
+			continue
+		}
+		if height := status.SyncInfo.LatestBlockHeight; height > lastHeight {
+			lastHeight = height
+			feed.send(ctx, lastHeight)
+			if !lost {
+				lost, backoff, resubscribeAt = true, feed.timeout, time.Now()
+			}
+		}
+		if !lost || time.Now().Before(resubscribeAt) {
+			continue
+		}
+
+		fmt.Printf("Block feed of %s is lost, subscribing again\n", subscriber)
+		client.RPCClient.Unsubscribe(ctx, subscriber, types.EventQueryNewBlock.String())
+		events, err = client.subscribeEvents(ctx, subscriber)
+		if err != nil {
+			fmt.Printf("Block feed subscription: %s\n", err)
+		}
+		resubscribeAt = time.Now().Add(backoff)
+		backoff *= 2
+		if backoff > MaxResubscribeInterval {
+			backoff = MaxResubscribeInterval
+		}
+	}
+}
+
+// send replaces the height the subscribers have not taken yet.
+func (feed *blockFeed) send(ctx context.Context, height int64) {
+	feed.Lock()
+	defer feed.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+	for heights := range feed.subscribers {
+		select {
+		case <-heights:
+		default:
+		}
+		heights <- height
+	}
+}
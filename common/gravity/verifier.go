@@ -0,0 +1,106 @@
+package gravity
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/Gravity-Tech/gravity-core/ledger/query"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/light"
+	"github.com/tendermint/tendermint/light/store/db"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+const (
+	verifyAttempts = 5
+	verifyInterval = time.Second
+)
+
+var (
+	ErrNoProof        = errors.New("response has no proof")
+	ErrInvalidProof   = errors.New("invalid proof")
+	ErrUnprovablePath = errors.New("query path is not read from the ledger state")
+)
+
+// verifier checks query proofs against the app hash of headers verified by
+// the light client. The app hash of height H is committed in header H+1.
+type verifier struct {
+	lightClient *light.Client
+}
+
+func NewLightClient(ctx context.Context, host string, chainId string, trustOptions light.TrustOptions, witnesses []string) (*light.Client, error) {
+	return light.NewHTTPClient(ctx, chainId, trustOptions, host, witnesses, db.New(dbm.NewMemDB(), chainId))
+}
+
+func newVerifier(lightClient *light.Client) *verifier {
+	return &verifier{
+		lightClient: lightClient,
+	}
+}
+
+// verify checks the proofs of the response against the app hash of the
+// queried height and computes the answer of the query again from the proven
+// state, so a proven answer has the same format as an unproven one.
+func (v *verifier) verify(ctx context.Context, path query.Path, rq []byte, rs abcitypes.ResponseQuery) ([]byte, error) {
+	if path == query.ValidatorDetailsPath {
+		return nil, ErrUnprovablePath
+	}
+	if rs.ProofOps == nil {
+		return nil, ErrNoProof
+	}
+	if rs.Height <= 0 {
+		return nil, ErrInvalidProof
+	}
+
+	header, err := v.header(ctx, rs.Height+1)
+	if err != nil {
+		return nil, err
+	}
+
+	store := storage.New()
+	err = store.NewProvenTransaction(header.AppHash, rs.ProofOps)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidProof, err.Error())
+	}
+
+	value, err := query.Query(store, string(path), rq, nil)
+	if provenErr := store.Proven(); provenErr != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidProof, provenErr.Error())
+	}
+
+	notFound := rs.Code == NotFoundCode
+	if err == query.ErrValueNotFound {
+		if !notFound {
+			return nil, fmt.Errorf("%w: value is proven absent", ErrInvalidProof)
+		}
+		return nil, ErrValueNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if notFound || !bytes.Equal(value, rs.Value) {
+		return nil, fmt.Errorf("%w: value does not match the proven state", ErrInvalidProof)
+	}
+
+	return value, nil
+}
+
+// header waits for the next block when the queried height is the latest one.
+func (v *verifier) header(ctx context.Context, height int64) (*types.LightBlock, error) {
+	var err error
+	for i := 0; i < verifyAttempts; i++ {
+		var lightBlock *types.LightBlock
+		lightBlock, err = v.lightClient.VerifyLightBlockAtHeight(ctx, height, time.Now())
+		if err == nil {
+			return lightBlock, nil
+		}
+
+		time.Sleep(verifyInterval)
+	}
+
+	return nil, err
+}
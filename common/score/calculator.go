@@ -1,6 +1,9 @@
 package score
 
 import (
+	"bytes"
+	"sort"
+
 	"github.com/Gravity-Tech/gravity-core/common/account"
 	"github.com/Gravity-Tech/gravity-core/common/score/trustgraph"
 	"github.com/Gravity-Tech/gravity-core/common/storage"
@@ -29,18 +32,20 @@ func Calculate(initScores storage.ScoresByConsulMap, votes storage.VoteByConsulM
 	idByValidator := make(map[account.ConsulPubKey]int)
 	validatorById := make(map[int]account.ConsulPubKey)
 
+	validators := sortedValidators(initScores)
+
 	index := 0
-	for k, v := range initScores {
+	for _, k := range validators {
 		idByValidator[k] = index
 		validatorById[index] = k
-		err := group.InitialTrust(idByValidator[k], UInt64ToFloat32Score(v))
+		err := group.InitialTrust(idByValidator[k], UInt64ToFloat32Score(initScores[k]))
 		if err != nil {
 			return nil, err
 		}
 		index++
 	}
 
-	for voter, _ := range initScores {
+	for _, voter := range validators {
 		existVote := make(map[account.ConsulPubKey]bool)
 		for _, vote := range votes[voter] {
 			if voter == vote.PubKey {
@@ -62,7 +67,7 @@ func Calculate(initScores storage.ScoresByConsulMap, votes storage.VoteByConsulM
 			}
 			existVote[vote.PubKey] = true
 		}
-		for validator, _ := range initScores {
+		for _, validator := range validators {
 			if existVote[validator] || voter == validator {
 				continue
 			}
@@ -74,7 +79,7 @@ func Calculate(initScores storage.ScoresByConsulMap, votes storage.VoteByConsulM
 		}
 	}
 	for _, v := range newValidators {
-		for validator, _ := range initScores {
+		for _, validator := range validators {
 			err := group.Add(v, idByValidator[validator], UInt64ToFloat32Score(initScores[validator]))
 			if err != nil {
 				return nil, err
@@ -90,3 +95,15 @@ func Calculate(initScores storage.ScoresByConsulMap, votes storage.VoteByConsulM
 	}
 	return score, nil
 }
+
+func sortedValidators(scores storage.ScoresByConsulMap) []account.ConsulPubKey {
+	var validators []account.ConsulPubKey
+	for k := range scores {
+		validators = append(validators, k)
+	}
+	sort.Slice(validators, func(i, j int) bool {
+		return bytes.Compare(validators[i][:], validators[j][:]) < 0
+	})
+
+	return validators
+}
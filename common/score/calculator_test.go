@@ -46,34 +46,34 @@ func TestCalculateDropValidator(t *testing.T) {
 
 	votes := storage.VoteByConsulMap{
 		consuls[0]: []storage.Vote{
-			{consuls[1], Accuracy},
-			{consuls[2], Accuracy},
-			{consuls[3], Accuracy},
-			{consuls[4], 0},
+			{PubKey: consuls[1], Score: Accuracy},
+			{PubKey: consuls[2], Score: Accuracy},
+			{PubKey: consuls[3], Score: Accuracy},
+			{PubKey: consuls[4], Score: 0},
 		},
 		consuls[1]: []storage.Vote{
-			{consuls[0], Accuracy},
-			{consuls[2], Accuracy},
-			{consuls[3], Accuracy},
-			{consuls[4], 0},
+			{PubKey: consuls[0], Score: Accuracy},
+			{PubKey: consuls[2], Score: Accuracy},
+			{PubKey: consuls[3], Score: Accuracy},
+			{PubKey: consuls[4], Score: 0},
 		},
 		consuls[2]: []storage.Vote{
-			{consuls[0], Accuracy},
-			{consuls[1], Accuracy},
-			{consuls[3], Accuracy},
-			{consuls[4], 0},
+			{PubKey: consuls[0], Score: Accuracy},
+			{PubKey: consuls[1], Score: Accuracy},
+			{PubKey: consuls[3], Score: Accuracy},
+			{PubKey: consuls[4], Score: 0},
 		},
 		consuls[3]: []storage.Vote{
-			{consuls[0], Accuracy},
-			{consuls[1], Accuracy},
-			{consuls[2], Accuracy},
-			{consuls[4], 0},
+			{PubKey: consuls[0], Score: Accuracy},
+			{PubKey: consuls[1], Score: Accuracy},
+			{PubKey: consuls[2], Score: Accuracy},
+			{PubKey: consuls[4], Score: 0},
 		},
 		consuls[4]: []storage.Vote{
-			{consuls[0], Accuracy},
-			{consuls[1], Accuracy},
-			{consuls[2], Accuracy},
-			{consuls[3], Accuracy},
+			{PubKey: consuls[0], Score: Accuracy},
+			{PubKey: consuls[1], Score: Accuracy},
+			{PubKey: consuls[2], Score: Accuracy},
+			{PubKey: consuls[3], Score: Accuracy},
 		},
 	}
 
@@ -4,6 +4,7 @@ package trustgraph
 
 import (
 	"errors"
+	"sort"
 )
 
 // Group represents a group of peers. Peers need to be given unique, int IDs.
@@ -95,11 +96,14 @@ func (g Group) Compute() map[int]float32 {
 // product of the direct trust and indirect trust
 func (g Group) computeIteration(t0 *map[int]float32) *map[int]float32 {
 
+	// float sums depend on the order of addition, so peers are visited in
+	// ascending order to get the same result on every node
 	t1 := map[int]float32{}
-	for truster, directTrust := range *t0 {
-		for trusted, indirectTrust := range g.trustGrid[truster] {
+	for _, truster := range sortedPeers(*t0) {
+		directTrust := (*t0)[truster]
+		for _, trusted := range sortedPeers(g.trustGrid[truster]) {
 			if trusted != truster {
-				t1[trusted] += directTrust * indirectTrust
+				t1[trusted] += directTrust * g.trustGrid[truster][trusted]
 			}
 		}
 	}
@@ -134,9 +138,19 @@ func abs(x float32) float32 {
 // difference between them
 func avgD(t0, t1 *map[int]float32) float32 {
 	d := float32(0)
-	for i, v := range *t1 {
-		d += abs(v - (*t0)[i])
+	for _, i := range sortedPeers(*t1) {
+		d += abs((*t1)[i] - (*t0)[i])
 	}
 	d = d / float32(len(*t0))
 	return d
 }
+
+// sortedPeers is helper to get the ids of a trust map in ascending order
+func sortedPeers(m map[int]float32) []int {
+	peers := make([]int, 0, len(m))
+	for k := range m {
+		peers = append(peers, k)
+	}
+	sort.Ints(peers)
+	return peers
+}
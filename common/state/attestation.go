@@ -0,0 +1,55 @@
+package state
+
+import (
+	"sort"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+)
+
+// attest appends the sender's observation to the stored ones and reports
+// whether at least 2/3 of the consuls of the round have attested. The consuls
+// are the ones the round started with, so a rotation does not move the quorum.
+func attest(store *storage.Storage, roundId uint64, sender account.ConsulPubKey, attestations []storage.Attestation, value uint64) ([]storage.Attestation, bool, error) {
+	consuls, err := store.RoundConsuls(roundId)
+	if err == storage.ErrKeyNotFound {
+		consuls, err = store.Consuls()
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	isConsul := false
+	for _, v := range consuls {
+		if v.PubKey == sender {
+			isConsul = true
+			break
+		}
+	}
+	if !isConsul {
+		return nil, false, ErrNotConsul
+	}
+
+	for _, v := range attestations {
+		if v.PubKey == sender {
+			return nil, false, ErrAttestationIsExist
+		}
+	}
+
+	attestations = append(attestations, storage.Attestation{
+		PubKey: sender,
+		Value:  value,
+	})
+
+	return attestations, len(attestations)*3 >= len(consuls)*2, nil
+}
+
+func median(attestations []storage.Attestation) uint64 {
+	values := make([]uint64, len(attestations))
+	for i, v := range attestations {
+		values[i] = v.Value
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	return values[(len(values)-1)/2]
+}
@@ -0,0 +1,383 @@
+package state
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/Gravity-Tech/gravity-core/common/transactions"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	wavescrypto "github.com/wavesplatform/gowaves/pkg/crypto"
+)
+
+const testChainId = "gravity-test"
+
+type testSigner struct {
+	privKey ed25519.PrivKey
+	pubKey  account.ConsulPubKey
+}
+
+func newTestSigner(secret string) testSigner {
+	privKey := ed25519.GenPrivKeyFromSecret([]byte(secret))
+	var pubKey account.ConsulPubKey
+	copy(pubKey[:], privKey.PubKey().Bytes())
+
+	return testSigner{privKey: privKey, pubKey: pubKey}
+}
+
+func (signer testSigner) sign(t *testing.T, payload transactions.Payload, nonce uint64) *transactions.Transaction {
+	tx := transactions.New(signer.pubKey, payload)
+	tx.Nonce = nonce
+	if err := tx.Sign(testChainId, signer.privKey); err != nil {
+		t.Fatal(err)
+	}
+
+	return tx
+}
+
+// testPayloads returns a payload for every TxFunc together with a mutation
+// of it.
+func testPayloads() [][2]transactions.Payload {
+	hash := make([]byte, transactions.HashLength)
+	otherHash := make([]byte, transactions.HashLength)
+	otherHash[0] = 1
+
+	return [][2]transactions.Payload{
+		{
+			&transactions.CommitArgs{NebulaId: account.NebulaId{1}, PulseId: 1, Height: 1, Commit: hash},
+			&transactions.CommitArgs{NebulaId: account.NebulaId{1}, PulseId: 1, Height: 1, Commit: otherHash},
+		},
+		{
+			&transactions.RevealArgs{Commit: hash, NebulaId: account.NebulaId{1}, PulseId: 1, Reveal: []byte{1}, Salt: hash},
+			&transactions.RevealArgs{Commit: hash, NebulaId: account.NebulaId{1}, PulseId: 1, Reveal: []byte{2}, Salt: hash},
+		},
+		{
+			&transactions.ResultArgs{NebulaId: account.NebulaId{1}, PulseId: 1, Sign: []byte{1}, Hash: hash},
+			&transactions.ResultArgs{NebulaId: account.NebulaId{1}, PulseId: 2, Sign: []byte{1}, Hash: hash},
+		},
+		{
+			&transactions.AddOracleArgs{ChainType: account.Ethereum, OraclePubKey: account.OraclesPubKey{1}},
+			&transactions.AddOracleArgs{ChainType: account.Ethereum, OraclePubKey: account.OraclesPubKey{2}},
+		},
+		{
+			&transactions.AddOracleInNebulaArgs{NebulaId: account.NebulaId{1}, OraclePubKey: account.OraclesPubKey{1}},
+			&transactions.AddOracleInNebulaArgs{NebulaId: account.NebulaId{2}, OraclePubKey: account.OraclesPubKey{1}},
+		},
+		{
+			&transactions.NewRoundArgs{ChainType: account.Ethereum, LedgerHeight: 1, TcHeight: 100},
+			&transactions.NewRoundArgs{ChainType: account.Ethereum, LedgerHeight: 1, TcHeight: 101},
+		},
+		{
+			&transactions.VoteArgs{Votes: []storage.Vote{{PubKey: account.ConsulPubKey{1}, Score: 10}}},
+			&transactions.VoteArgs{Votes: []storage.Vote{{PubKey: account.ConsulPubKey{1}, Score: 100}}},
+		},
+		{
+			&transactions.SetNebulaArgs{NebulaId: account.NebulaId{1}, Info: storage.NebulaInfo{MinScore: 1}},
+			&transactions.SetNebulaArgs{NebulaId: account.NebulaId{1}, Info: storage.NebulaInfo{MinScore: 0}},
+		},
+		{
+			&transactions.SignNewConsulsArgs{ChainType: account.Ethereum, RoundId: 1, Sign: []byte{1}},
+			&transactions.SignNewConsulsArgs{ChainType: account.Waves, RoundId: 1, Sign: []byte{1}},
+		},
+		{
+			&transactions.SignNewOraclesArgs{RoundId: 1, Sign: []byte{1}, NebulaId: account.NebulaId{1}},
+			&transactions.SignNewOraclesArgs{RoundId: 1, Sign: []byte{2}, NebulaId: account.NebulaId{1}},
+		},
+		{
+			&transactions.ApproveLastRoundArgs{RoundId: 0},
+			&transactions.ApproveLastRoundArgs{RoundId: 1},
+		},
+	}
+}
+
+func newSignatureTestStorage(t *testing.T, signer testSigner) (*storage.Storage, func()) {
+	store, closeStore := newTestStorage(t, []account.ConsulPubKey{signer.pubKey})
+	if err := store.SetScore(signer.pubKey, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetLastHeight(1); err != nil {
+		t.Fatal(err)
+	}
+
+	return store, closeStore
+}
+
+func isAuthError(err error) bool {
+	switch err {
+	case ErrInvalidTxId, ErrInvalidSign, ErrInvalidNonce, ErrNotRegistered:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestValidTransactions(t *testing.T) {
+	signer := newTestSigner("consul")
+	store, closeStore := newSignatureTestStorage(t, signer)
+	defer closeStore()
+
+	for i, payloads := range testPayloads() {
+		tx := signer.sign(t, payloads[0], uint64(i))
+		if err := SetState(tx, store, testChainId); isAuthError(err) {
+			t.Errorf("%s: valid transaction rejected: %v", tx.Func, err)
+		}
+	}
+}
+
+func TestForgedTransactions(t *testing.T) {
+	signer := newTestSigner("consul")
+	forger := newTestSigner("forger")
+	store, closeStore := newSignatureTestStorage(t, signer)
+	defer closeStore()
+
+	for _, payloads := range testPayloads() {
+		forged := forger.sign(t, payloads[0], 0)
+		forged.SenderPubKey = signer.pubKey
+		forged.Id = forged.Hash(testChainId)
+		if err := SetState(forged, store, testChainId); err != ErrInvalidSign {
+			t.Errorf("%s: foreign signature: expected %v, got %v", forged.Func, ErrInvalidSign, err)
+		}
+
+		flipped := signer.sign(t, payloads[0], 0)
+		flipped.Signature[0] ^= 1
+		if err := SetState(flipped, store, testChainId); err != ErrInvalidSign {
+			t.Errorf("%s: corrupted signature: expected %v, got %v", flipped.Func, ErrInvalidSign, err)
+		}
+
+		unregistered := forger.sign(t, payloads[0], 0)
+		if err := SetState(unregistered, store, testChainId); err != ErrNotRegistered {
+			t.Errorf("%s: unregistered sender: expected %v, got %v", unregistered.Func, ErrNotRegistered, err)
+		}
+	}
+}
+
+func TestMutatedTransactions(t *testing.T) {
+	signer := newTestSigner("consul")
+	store, closeStore := newSignatureTestStorage(t, signer)
+	defer closeStore()
+
+	mutations := map[string]func(tx *transactions.Transaction, mutated transactions.Payload){
+		"payload":   func(tx *transactions.Transaction, mutated transactions.Payload) { tx.Payload = mutated },
+		"nonce":     func(tx *transactions.Transaction, _ transactions.Payload) { tx.Nonce++ },
+		"timestamp": func(tx *transactions.Transaction, _ transactions.Payload) { tx.Timestamp++ },
+		"sender":    func(tx *transactions.Transaction, _ transactions.Payload) { tx.SenderPubKey[0] ^= 1 },
+	}
+
+	for _, payloads := range testPayloads() {
+		for name, mutate := range mutations {
+			tx := signer.sign(t, payloads[0], 0)
+			mutate(tx, payloads[1])
+			if err := SetState(tx, store, testChainId); err != ErrInvalidTxId {
+				t.Errorf("%s: mutated %s: expected %v, got %v", tx.Func, name, ErrInvalidTxId, err)
+			}
+
+			// Recomputing the id does not help without the private key.
+			tx.Id = tx.Hash(testChainId)
+			if err := SetState(tx, store, testChainId); err != ErrInvalidSign {
+				t.Errorf("%s: mutated %s with new id: expected %v, got %v", tx.Func, name, ErrInvalidSign, err)
+			}
+		}
+	}
+}
+
+func TestReplayedTransactions(t *testing.T) {
+	signer := newTestSigner("consul")
+	store, closeStore := newSignatureTestStorage(t, signer)
+	defer closeStore()
+
+	for i, payloads := range testPayloads() {
+		tx := signer.sign(t, payloads[0], uint64(i))
+		if err := SetState(tx, store, testChainId); isAuthError(err) {
+			t.Fatalf("%s: valid transaction rejected: %v", tx.Func, err)
+		}
+
+		decoded, err := transactions.Unmarshal(tx.Marshal())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := SetState(decoded, store, testChainId); err != ErrInvalidNonce {
+			t.Errorf("%s: replay: expected %v, got %v", tx.Func, ErrInvalidNonce, err)
+		}
+		if err := SetState(decoded, store, "gravity-other"); err != ErrInvalidTxId {
+			t.Errorf("%s: replay on another chain: expected %v, got %v", tx.Func, ErrInvalidTxId, err)
+		}
+	}
+}
+
+func TestResultSignatures(t *testing.T) {
+	signer := newTestSigner("consul")
+	store, closeStore := newSignatureTestStorage(t, signer)
+	defer closeStore()
+	if err := store.SetLastHeight(uint64(ResultSubRound)); err != nil {
+		t.Fatal(err)
+	}
+
+	ethKey, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherEthKey, err := ethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wavesSecret, wavesPubKey, err := wavescrypto.GenerateKeyPair([]byte("oracle"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oracles := storage.OraclesByTypeMap{
+		account.Ethereum: account.BytesToOraclePubKey(ethcrypto.CompressPubkey(&ethKey.PublicKey), account.Ethereum),
+		account.Waves:    account.BytesToOraclePubKey(wavesPubKey[:], account.Waves),
+	}
+	err = store.SetOraclesByConsul(signer.pubKey, oracles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nebulae := map[account.ChainType]account.NebulaId{
+		account.Ethereum: {1},
+		account.Waves:    {2},
+	}
+	for chainType, oracle := range oracles {
+		setTestNebula(t, store, nebulae[chainType], chainType, oracle)
+	}
+
+	hash := ethcrypto.Keccak256([]byte("value"))
+	otherHash := ethcrypto.Keccak256([]byte("other value"))
+	signEth := func(key *ecdsa.PrivateKey, msg []byte) []byte {
+		sign, err := ethcrypto.Sign(msg, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sign
+	}
+	signWaves := func(msg []byte) []byte {
+		sign, err := wavescrypto.Sign(wavesSecret, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sign.Bytes()
+	}
+
+	cases := []struct {
+		name      string
+		chainType account.ChainType
+		sign      []byte
+		err       error
+	}{
+		{"ethereum", account.Ethereum, signEth(ethKey, hash), nil},
+		{"ethereum foreign key", account.Ethereum, signEth(otherEthKey, hash), ErrInvalidResultSign},
+		{"ethereum other hash", account.Ethereum, signEth(ethKey, otherHash), ErrInvalidResultSign},
+		{"ethereum truncated", account.Ethereum, signEth(ethKey, hash)[:64], ErrInvalidResultSign},
+		{"waves", account.Waves, signWaves(hash), nil},
+		{"waves other hash", account.Waves, signWaves(otherHash), ErrInvalidResultSign},
+		{"waves with ethereum signature", account.Waves, signEth(ethKey, hash), ErrInvalidResultSign},
+		{"unregistered chain", account.ChainType(2), signEth(ethKey, hash), ErrOracleNotFound},
+	}
+
+	for i, c := range cases {
+		args := &transactions.ResultArgs{
+			NebulaId:  nebulae[c.chainType],
+			PulseId:   int64(i),
+			Sign:      c.sign,
+			ChainType: c.chainType,
+			Hash:      hash,
+		}
+		nonce, err := store.Nonce(signer.pubKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := SetState(signer.sign(t, args, nonce), store, testChainId); err != c.err {
+			t.Errorf("%s: expected %v, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+// setTestNebula stores a nebula of chainType whose BFT oracles are oracles.
+func setTestNebula(t *testing.T, store *storage.Storage, nebulaId account.NebulaId, chainType account.ChainType, oracles ...account.OraclesPubKey) {
+	if err := store.SetNebula(nebulaId, storage.NebulaInfo{ChainType: chainType}); err != nil {
+		t.Fatal(err)
+	}
+
+	bft := make(storage.OraclesMap)
+	for _, oracle := range oracles {
+		bft[oracle.ToString(chainType)] = chainType
+	}
+	if err := store.SetBftOraclesByNebula(nebulaId, bft); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResultBinding(t *testing.T) {
+	consuls := []account.ConsulPubKey{{1}, {2}, {3}, {4}}
+	store, closeStore := newTestStorage(t, consuls)
+	defer closeStore()
+
+	var keys []*ecdsa.PrivateKey
+	var oracles []account.OraclesPubKey
+	for _, consul := range consuls {
+		key, err := ethcrypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		oracle := account.BytesToOraclePubKey(ethcrypto.CompressPubkey(&key.PublicKey), account.Ethereum)
+		err = store.SetOraclesByConsul(consul, storage.OraclesByTypeMap{account.Ethereum: oracle})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+		oracles = append(oracles, oracle)
+	}
+
+	nebulaId := account.NebulaId{1}
+	setTestNebula(t, store, nebulaId, account.Ethereum, oracles[:3]...)
+	wavesNebulaId := account.NebulaId{2}
+	setTestNebula(t, store, wavesNebulaId, account.Waves)
+
+	hash := ethcrypto.Keccak256([]byte("value"))
+	otherHash := ethcrypto.Keccak256([]byte("other value"))
+	sendResult := func(index int, nebulaId account.NebulaId, chainType account.ChainType, hash []byte) error {
+		sign, err := ethcrypto.Sign(hash, keys[index])
+		if err != nil {
+			t.Fatal(err)
+		}
+		args := &transactions.ResultArgs{
+			NebulaId:  nebulaId,
+			PulseId:   1,
+			Sign:      sign,
+			ChainType: chainType,
+			Hash:      hash,
+		}
+
+		return result(store, newTestTx(consuls[index], args), args)
+	}
+
+	if err := sendResult(3, nebulaId, account.Ethereum, hash); err != ErrNotBftOracle {
+		t.Errorf("foreign oracle: expected %v, got %v", ErrNotBftOracle, err)
+	}
+	if err := sendResult(0, wavesNebulaId, account.Ethereum, hash); err != ErrInvalidChainType {
+		t.Errorf("mismatched chain type: expected %v, got %v", ErrInvalidChainType, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := sendResult(i, nebulaId, account.Ethereum, hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sendResult(0, nebulaId, account.Ethereum, hash); err != ErrSignIsExist {
+		t.Errorf("second result: expected %v, got %v", ErrSignIsExist, err)
+	}
+
+	pulseHash, err := store.PulseHash(nebulaId, 1)
+	if err != nil {
+		t.Fatalf("hash of 2 of 3 bft oracles is not agreed: %v", err)
+	}
+	if !bytes.Equal(pulseHash, hash) {
+		t.Errorf("agreed hash: expected %x, got %x", hash, pulseHash)
+	}
+	if err := sendResult(2, nebulaId, account.Ethereum, otherHash); err != ErrResultHashMismatch {
+		t.Errorf("result of another hash: expected %v, got %v", ErrResultHashMismatch, err)
+	}
+}
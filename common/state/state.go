@@ -2,17 +2,9 @@ package state
 
 import (
 	"bytes"
-	"context"
 	"crypto/ed25519"
-	"encoding/json"
 	"errors"
 
-	"github.com/Gravity-Tech/gravity-core/common/adaptors"
-
-	"github.com/Gravity-Tech/gravity-core/ledger/scheduler"
-
-	"github.com/ethereum/go-ethereum/crypto"
-
 	"github.com/Gravity-Tech/gravity-core/common/account"
 	"github.com/Gravity-Tech/gravity-core/common/storage"
 	"github.com/Gravity-Tech/gravity-core/common/transactions"
@@ -29,6 +21,8 @@ const (
 	SubRoundCount = 4
 
 	RoundInterval = 2
+
+	CalculateScoreInterval = 200
 )
 
 var (
@@ -48,14 +42,27 @@ var (
 	ErrNebulaNotFound     = errors.New("nebula not found")
 	ErrSignIsExist        = errors.New("sign is exist")
 	ErrRoundIsExist       = errors.New("round is exist")
+	ErrNotConsul          = errors.New("sender is not a consul")
+	ErrAttestationIsExist = errors.New("attestation is exist")
+	ErrInvalidTxId        = errors.New("invalid transaction id")
+	ErrInvalidNonce       = errors.New("invalid nonce")
+	ErrNotRegistered      = errors.New("sender is not registered")
+	ErrOracleNotFound     = errors.New("oracle is not found")
+	ErrInvalidResultSign  = errors.New("invalid result signature")
+	ErrNotBftOracle       = errors.New("oracle is not in the bft oracles of the nebula")
+	ErrResultHashMismatch = errors.New("result hash differs from the agreed one")
 )
 
 func CalculateSubRound(id uint64) SubRound {
 	return SubRound(id % SubRoundCount)
 }
 
-func SetState(tx *transactions.Transaction, store *storage.Storage, adaptors map[account.ChainType]adaptors.IBlockchainAdaptor, ctx context.Context) error {
-	if err := isValidSigns(store, tx); err != nil {
+func SetState(tx *transactions.Transaction, store *storage.Storage, chainId string) error {
+	if err := isValidSigns(store, tx, chainId); err != nil {
+		return err
+	}
+
+	if err := useNonce(store, tx); err != nil {
 		return err
 	}
 
@@ -64,42 +71,66 @@ func SetState(tx *transactions.Transaction, store *storage.Storage, adaptors map
 		return err
 	}
 
-	switch tx.Func {
-	case transactions.Commit:
-		return commit(store, tx)
-	case transactions.Reveal:
-		return reveal(store, tx)
-	case transactions.Result:
-		return result(store, tx)
-	case transactions.AddOracleInNebula:
-		return addOracleInNebula(store, tx)
-	case transactions.AddOracle:
-		return addOracle(store, tx)
-	case transactions.NewRound:
-		return newRound(store, tx, height, adaptors, ctx)
-	case transactions.Vote:
-		return vote(store, tx)
-	case transactions.SetNebula:
-		return setNebula(store, tx)
-	case transactions.SignNewConsuls:
-		return signNewConsuls(store, tx)
-	case transactions.SignNewOracles:
-		return signNewOracles(store, tx)
-	case transactions.ApproveLastRound:
-		return approveLastRound(store, adaptors, height, ctx)
+	// The nonce is used even if the transaction fails, but nothing else of
+	// a failed transaction reaches the state.
+	branch := store.Branch()
+	err = apply(branch, tx, height)
+	if err != nil {
+		return err
+	}
+	branch.Write()
+
+	return nil
+}
+
+func apply(store *storage.Storage, tx *transactions.Transaction, height uint64) error {
+	if tx.Payload == nil || tx.Payload.Func() != tx.Func {
+		return ErrFuncNotFound
+	}
+
+	switch args := tx.Payload.(type) {
+	case *transactions.CommitArgs:
+		if err := checkSubRound(tx.Func, CommitSubRound, height); err != nil {
+			return err
+		}
+		return commit(store, args)
+	case *transactions.RevealArgs:
+		if err := checkSubRound(tx.Func, RevealSubRound, height); err != nil {
+			return err
+		}
+		return reveal(store, args)
+	case *transactions.ResultArgs:
+		if err := checkSubRound(tx.Func, ResultSubRound, height); err != nil {
+			return err
+		}
+		return result(store, tx, args)
+	case *transactions.AddOracleInNebulaArgs:
+		return addOracleInNebula(store, tx, args)
+	case *transactions.AddOracleArgs:
+		return addOracle(store, tx, args)
+	case *transactions.NewRoundArgs:
+		return newRound(store, tx, args, height)
+	case *transactions.VoteArgs:
+		return vote(store, tx, args)
+	case *transactions.SetNebulaArgs:
+		return setNebula(store, tx, args)
+	case *transactions.SignNewConsulsArgs:
+		return signNewConsuls(store, tx, args)
+	case *transactions.SignNewOraclesArgs:
+		return signNewOracles(store, tx, args)
+	case *transactions.ApproveLastRoundArgs:
+		return approveLastRound(store, tx, args, height)
 	default:
 		return ErrFuncNotFound
 	}
 }
 
-func commit(store *storage.Storage, tx *transactions.Transaction) error {
-	nebula := account.BytesToNebulaId(tx.Value(0).([]byte))
-	pulseId := tx.Value(1).(int64)
-	tcHeight := tx.Value(2).(int64)
-	commit := tx.Value(3).([]byte)
-	pubKeyBytes := tx.Value(4).([]byte)
-	var pubKey account.OraclesPubKey
-	copy(pubKey[:], pubKeyBytes)
+func commit(store *storage.Storage, args *transactions.CommitArgs) error {
+	nebula := args.NebulaId
+	pulseId := args.PulseId
+	tcHeight := args.Height
+	commit := args.Commit
+	pubKey := args.OraclePubKey
 
 	_, err := store.CommitHash(nebula, tcHeight, pulseId, pubKey)
 	if err == storage.ErrKeyNotFound {
@@ -116,15 +147,13 @@ func commit(store *storage.Storage, tx *transactions.Transaction) error {
 	return nil
 }
 
-func reveal(store *storage.Storage, tx *transactions.Transaction) error {
-	commit := tx.Value(0).([]byte)
-	nebula := account.BytesToNebulaId(tx.Value(1).([]byte))
-	pulseId := tx.Value(2).(int64)
-	height := tx.Value(3).(int64)
-	reveal := tx.Value(4).([]byte)
-	pubKeyBytes := tx.Value(5).([]byte)
-	var pubKey account.OraclesPubKey
-	copy(pubKey[:], pubKeyBytes)
+func reveal(store *storage.Storage, args *transactions.RevealArgs) error {
+	commit := args.Commit
+	nebula := args.NebulaId
+	pulseId := args.PulseId
+	height := args.Height
+	reveal := args.Reveal
+	pubKey := args.OraclePubKey
 
 	_, err := store.Reveal(nebula, height, pulseId, commit, pubKey)
 	if err == storage.ErrKeyNotFound {
@@ -136,12 +165,15 @@ func reveal(store *storage.Storage, tx *transactions.Transaction) error {
 			return err
 		}
 
-		expectedHash := crypto.Keccak256(reveal)
-		if !bytes.Equal(commitBytes, expectedHash[:]) {
+		expectedHash := transactions.CommitHash(args.Salt, reveal)
+		if !bytes.Equal(commitBytes, expectedHash) {
 			return ErrInvalidReveal
 		}
 
-		return store.SetReveal(nebula, height, pulseId, commit, pubKey, reveal)
+		return store.SetReveal(nebula, height, pulseId, commit, pubKey, storage.RevealValue{
+			Salt:  args.Salt,
+			Value: reveal,
+		})
 	} else if err != nil {
 		return err
 	} else {
@@ -149,11 +181,9 @@ func reveal(store *storage.Storage, tx *transactions.Transaction) error {
 	}
 }
 
-func addOracleInNebula(store *storage.Storage, tx *transactions.Transaction) error {
-	nebulaAddress := account.BytesToNebulaId(tx.Value(0).([]byte))
-	pubKeyBytes := tx.Value(1).([]byte)
-	var pubKey account.OraclesPubKey
-	copy(pubKey[:], pubKeyBytes)
+func addOracleInNebula(store *storage.Storage, tx *transactions.Transaction, args *transactions.AddOracleInNebulaArgs) error {
+	nebulaAddress := args.NebulaId
+	pubKey := args.OraclePubKey
 
 	nebula, err := store.NebulaInfo(nebulaAddress)
 	if err != nil {
@@ -190,56 +220,139 @@ func addOracleInNebula(store *storage.Storage, tx *transactions.Transaction) err
 	return nil
 }
 
-func result(store *storage.Storage, tx *transactions.Transaction) error {
-	nebulaAddress := account.BytesToNebulaId(tx.Value(0).([]byte))
-	pulseId := tx.Value(1).(int64)
-	signBytes := tx.Value(2).([]byte)
-	chainType := account.ChainType(tx.Value(3).([]byte)[0])
+func result(store *storage.Storage, tx *transactions.Transaction, args *transactions.ResultArgs) error {
+	nebulaAddress := args.NebulaId
+	pulseId := args.PulseId
+	signBytes := args.Sign
+	chainType := args.ChainType
 
 	oracles, err := store.OraclesByConsul(tx.SenderPubKey)
+	if err == storage.ErrKeyNotFound {
+		return ErrOracleNotFound
+	} else if err != nil {
+		return err
+	}
+
+	oraclePubKey, ok := oracles[chainType]
+	if !ok {
+		return ErrOracleNotFound
+	}
+
+	nebula, err := store.NebulaInfo(nebulaAddress)
+	if err == storage.ErrKeyNotFound {
+		return ErrNebulaNotFound
+	} else if err != nil {
+		return err
+	}
+	if nebula.ChainType != chainType {
+		return ErrInvalidChainType
+	}
+
+	bftOracles, err := store.BftOraclesByNebula(nebulaAddress)
+	if err != nil && err != storage.ErrKeyNotFound {
+		return err
+	}
+	if _, ok := bftOracles[oraclePubKey.ToString(chainType)]; !ok {
+		return ErrNotBftOracle
+	}
+
+	if !account.Verify(chainType, oraclePubKey, args.Hash, signBytes) {
+		return ErrInvalidResultSign
+	}
+
+	_, err = store.Result(nebulaAddress, pulseId, oraclePubKey)
+	if err == nil {
+		return ErrSignIsExist
+	} else if err != storage.ErrKeyNotFound {
+		return err
+	}
+
+	pulseHash, err := store.PulseHash(nebulaAddress, pulseId)
+	if err == nil && !bytes.Equal(pulseHash, args.Hash) {
+		return ErrResultHashMismatch
+	} else if err != nil && err != storage.ErrKeyNotFound {
+		return err
+	}
+
+	err = store.SetResult(nebulaAddress, pulseId, oraclePubKey, signBytes)
+	if err != nil {
+		return err
+	}
+	err = store.SetResultHash(nebulaAddress, pulseId, oraclePubKey, args.Hash)
+	if err != nil {
+		return err
+	}
+	if pulseHash != nil {
+		return nil
+	}
+
+	return agreeOnResult(store, nebulaAddress, pulseId, len(bftOracles), args.Hash)
+}
+
+// agreeOnResult binds the pulse to hash once at least 2/3 of the BFT oracles
+// of the nebula have signed it. Results of other hashes are refused from then
+// on, so only a hash agreed on the ledger can collect the signs of a pulse.
+func agreeOnResult(store *storage.Storage, nebulaId account.NebulaId, pulseId int64, bftCount int, hash []byte) error {
+	hashes, err := store.ResultHashes(nebulaId, pulseId)
 	if err != nil {
 		return err
 	}
 
-	return store.SetResult(nebulaAddress, pulseId, oracles[chainType], signBytes)
+	count := 0
+	for _, v := range hashes {
+		if bytes.Equal(v, hash) {
+			count++
+		}
+	}
+	if count*3 < bftCount*2 {
+		return nil
+	}
+
+	return store.SetPulseHash(nebulaId, pulseId, hash)
 }
 
-func newRound(store *storage.Storage, tx *transactions.Transaction, ledgerHeight uint64, adaptors map[account.ChainType]adaptors.IBlockchainAdaptor, ctx context.Context) error {
-	chainType := account.ChainType(tx.Value(0).([]byte)[0])
-	tcHeight := tx.Value(1).(int64)
+func newRound(store *storage.Storage, tx *transactions.Transaction, args *transactions.NewRoundArgs, height uint64) error {
+	chainType := args.ChainType
+	ledgerHeight := args.LedgerHeight
+	tcHeight := args.TcHeight
+
+	if ledgerHeight > height {
+		return ErrInvalidHeight
+	}
 
 	_, err := store.RoundHeight(chainType, ledgerHeight)
 	if err != storage.ErrKeyNotFound {
 		return ErrNewRound
 	}
 
-	height, err := adaptors[chainType].GetHeight(ctx)
-	if err != nil {
+	attestations, err := store.RoundAttestations(chainType, ledgerHeight)
+	if err != nil && err != storage.ErrKeyNotFound {
 		return err
 	}
 
-	if uint64(tcHeight) != height {
-		return ErrInvalidHeight
+	attestations, isQuorum, err := attest(store, ledgerHeight/CalculateScoreInterval, tx.SenderPubKey, attestations, tcHeight)
+	if err != nil {
+		return err
 	}
 
-	return store.SetNewRound(chainType, ledgerHeight, uint64(tcHeight))
-}
-
-func vote(store *storage.Storage, tx *transactions.Transaction) error {
-	votesBytes := tx.Value(0).([]byte)
-
-	var votes []storage.Vote
-	err := json.Unmarshal(votesBytes, &votes)
+	err = store.SetRoundAttestations(chainType, ledgerHeight, attestations)
 	if err != nil {
 		return err
 	}
 
-	return store.SetVote(tx.SenderPubKey, votes)
+	if !isQuorum {
+		return nil
+	}
+
+	return store.SetNewRound(chainType, ledgerHeight, median(attestations))
+}
+
+func vote(store *storage.Storage, tx *transactions.Transaction, args *transactions.VoteArgs) error {
+	return store.SetVote(tx.SenderPubKey, args.Votes)
 }
 
-func setNebula(store *storage.Storage, tx *transactions.Transaction) error {
-	nebulaId := account.BytesToNebulaId(tx.Value(0).([]byte))
-	nebulaInfoBytes := tx.Value(1).([]byte)
+func setNebula(store *storage.Storage, tx *transactions.Transaction, args *transactions.SetNebulaArgs) error {
+	nebulaId := args.NebulaId
 
 	nebula, err := store.NebulaInfo(nebulaId)
 	if err != nil && err != storage.ErrKeyNotFound {
@@ -250,29 +363,50 @@ func setNebula(store *storage.Storage, tx *transactions.Transaction) error {
 		return ErrInvalidNebulaOwner
 	}
 
-	var nebulaInfo storage.NebulaInfo
-	err = json.Unmarshal(nebulaInfoBytes, &nebulaInfo)
+	if _, err := storage.ParseAggregatorType(args.Info.Aggregator); err != nil {
+		return err
+	}
+
+	return store.SetNebula(nebulaId, args.Info)
+}
+
+// useNonce accepts only the next nonce of the sender, so a transaction can't
+// be included twice.
+func useNonce(store *storage.Storage, tx *transactions.Transaction) error {
+	nonce, err := store.Nonce(tx.SenderPubKey)
 	if err != nil {
 		return err
 	}
 
-	return store.SetNebula(nebulaId, nebulaInfo)
+	if tx.Nonce != nonce {
+		return ErrInvalidNonce
+	}
+
+	return store.SetNonce(tx.SenderPubKey, nonce+1)
 }
 
-func isValidSigns(store *storage.Storage, tx *transactions.Transaction) error {
-	score, err := store.Score(tx.SenderPubKey)
-	if err != nil || score < 0 {
-		return ErrInvalidScore
+// isValidSigns recomputes the id from the transaction body and checks that it
+// is signed by a registered validator.
+func isValidSigns(store *storage.Storage, tx *transactions.Transaction, chainId string) error {
+	if tx.Id != tx.Hash(chainId) {
+		return ErrInvalidTxId
 	}
 
-	if ed25519.Verify(tx.SenderPubKey[:], tx.Id.Bytes(), tx.Signature[:]) {
+	if !ed25519.Verify(tx.SenderPubKey[:], tx.Id.Bytes(), tx.Signature[:]) {
 		return ErrInvalidSign
 	}
+
+	_, err := store.Score(tx.SenderPubKey)
+	if err == storage.ErrKeyNotFound {
+		return ErrNotRegistered
+	} else if err != nil {
+		return err
+	}
+
 	return nil
 }
-func addOracle(store *storage.Storage, tx *transactions.Transaction) error {
-	chainType := account.ChainType(tx.Value(0).([]byte)[0])
-	pubKey := tx.Value(1).([]byte)
+func addOracle(store *storage.Storage, tx *transactions.Transaction, args *transactions.AddOracleArgs) error {
+	chainType := args.ChainType
 
 	oracles, err := store.OraclesByConsul(tx.SenderPubKey)
 	if err != nil && err != storage.ErrKeyNotFound {
@@ -282,10 +416,7 @@ func addOracle(store *storage.Storage, tx *transactions.Transaction) error {
 		oracles = make(storage.OraclesByTypeMap)
 	}
 
-	var oraclePubKey account.OraclesPubKey
-	copy(oraclePubKey[:], pubKey)
-
-	oracles[chainType] = oraclePubKey
+	oracles[chainType] = args.OraclePubKey
 
 	err = store.SetOraclesByConsul(tx.SenderPubKey, oracles)
 	if err != nil {
@@ -294,10 +425,10 @@ func addOracle(store *storage.Storage, tx *transactions.Transaction) error {
 
 	return nil
 }
-func signNewConsuls(store *storage.Storage, tx *transactions.Transaction) error {
-	chainType := account.ChainType(tx.Value(0).([]byte)[0])
-	roundId := tx.Value(1).(int64)
-	sign := tx.Value(2).([]byte)
+func signNewConsuls(store *storage.Storage, tx *transactions.Transaction, args *transactions.SignNewConsulsArgs) error {
+	chainType := args.ChainType
+	roundId := args.RoundId
+	sign := args.Sign
 
 	_, err := store.SignConsulsByConsul(tx.SenderPubKey, chainType, roundId)
 	if err != nil && err != storage.ErrKeyNotFound {
@@ -313,10 +444,10 @@ func signNewConsuls(store *storage.Storage, tx *transactions.Transaction) error
 
 	return nil
 }
-func signNewOracles(store *storage.Storage, tx *transactions.Transaction) error {
-	roundId := tx.Value(0).(int64)
-	sign := tx.Value(1).([]byte)
-	nebulaAddress := account.BytesToNebulaId(tx.Value(2).([]byte))
+func signNewOracles(store *storage.Storage, tx *transactions.Transaction, args *transactions.SignNewOraclesArgs) error {
+	roundId := args.RoundId
+	sign := args.Sign
+	nebulaAddress := args.NebulaId
 
 	_, err := store.SignOraclesByConsul(tx.SenderPubKey, nebulaAddress, roundId)
 	if err != nil && err != storage.ErrKeyNotFound {
@@ -332,8 +463,11 @@ func signNewOracles(store *storage.Storage, tx *transactions.Transaction) error
 
 	return nil
 }
-func approveLastRound(store *storage.Storage, adaptors map[account.ChainType]adaptors.IBlockchainAdaptor, height uint64, ctx context.Context) error {
-	roundId := height / scheduler.CalculateScoreInterval
+func approveLastRound(store *storage.Storage, tx *transactions.Transaction, args *transactions.ApproveLastRoundArgs, height uint64) error {
+	roundId := args.RoundId
+	if roundId != height/CalculateScoreInterval {
+		return ErrInvalidHeight
+	}
 
 	lastRound, err := store.LastRoundApproved()
 	if err != nil && err != storage.ErrKeyNotFound {
@@ -343,25 +477,25 @@ func approveLastRound(store *storage.Storage, adaptors map[account.ChainType]ada
 	if lastRound >= roundId {
 		return ErrRoundIsExist
 	}
-	isExist := true
-	for _, v := range adaptors {
-		isExist, err = v.RoundExist(int64(roundId), ctx)
-		if err != nil {
-			return err
-		}
 
-		if isExist {
-			lastRoundContract, err := v.LastRound(ctx)
-			if err != nil {
-				return err
-			}
-			isExist = lastRoundContract < roundId
-		}
+	attestations, err := store.ApproveRoundAttestations(roundId)
+	if err != nil && err != storage.ErrKeyNotFound {
+		return err
 	}
 
-	err = store.SetLastRoundApproved(roundId)
+	attestations, isQuorum, err := attest(store, roundId, tx.SenderPubKey, attestations, roundId)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	err = store.SetApproveRoundAttestations(roundId, attestations)
+	if err != nil {
+		return err
+	}
+
+	if !isQuorum {
+		return nil
+	}
+
+	return store.SetLastRoundApproved(roundId)
 }
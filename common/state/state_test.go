@@ -0,0 +1,379 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/Gravity-Tech/gravity-core/common/transactions"
+	"github.com/dgraph-io/badger"
+)
+
+func newTestStorage(t *testing.T, consuls []account.ConsulPubKey) (*storage.Storage, func()) {
+	dir, err := ioutil.TempDir("", "gravity-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stateDB, err := storage.OpenDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := storage.New()
+	store.NewTransaction(stateDB)
+
+	var stored []storage.Consul
+	for _, v := range consuls {
+		stored = append(stored, storage.Consul{PubKey: v, Value: 1})
+	}
+	if err := store.SetConsuls(stored); err != nil {
+		t.Fatal(err)
+	}
+
+	return store, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func newTestTx(sender account.ConsulPubKey, payload transactions.Payload) *transactions.Transaction {
+	return &transactions.Transaction{
+		SenderPubKey: sender,
+		Func:         payload.Func(),
+		Payload:      payload,
+	}
+}
+
+func sendNewRound(store *storage.Storage, sender account.ConsulPubKey, ledgerHeight uint64, tcHeight uint64, height uint64) error {
+	args := &transactions.NewRoundArgs{
+		ChainType:    account.Ethereum,
+		LedgerHeight: ledgerHeight,
+		TcHeight:     tcHeight,
+	}
+
+	return newRound(store, newTestTx(sender, args), args, height)
+}
+
+func sendApproveLastRound(store *storage.Storage, sender account.ConsulPubKey, roundId uint64, height uint64) error {
+	args := &transactions.ApproveLastRoundArgs{RoundId: roundId}
+
+	return approveLastRound(store, newTestTx(sender, args), args, height)
+}
+
+func TestNewRoundAttestations(t *testing.T) {
+	consuls := []account.ConsulPubKey{{1}, {2}, {3}, {4}}
+	store, closeStore := newTestStorage(t, consuls)
+	defer closeStore()
+
+	if err := sendNewRound(store, account.ConsulPubKey{9}, 5, 100, 10); err != ErrNotConsul {
+		t.Errorf("unexpected error for non consul: %v", err)
+	}
+	if err := sendNewRound(store, consuls[0], 11, 100, 10); err != ErrInvalidHeight {
+		t.Errorf("unexpected error for future height: %v", err)
+	}
+
+	for i, tcHeight := range []uint64{120, 100} {
+		if err := sendNewRound(store, consuls[i], 5, tcHeight, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sendNewRound(store, consuls[0], 5, 100, 10); err != ErrAttestationIsExist {
+		t.Errorf("unexpected error for duplicate: %v", err)
+	}
+	if _, err := store.RoundHeight(account.Ethereum, 5); err != storage.ErrKeyNotFound {
+		t.Fatal("round set without quorum")
+	}
+
+	if err := sendNewRound(store, consuls[2], 5, 1000000, 10); err != nil {
+		t.Fatal(err)
+	}
+	tcHeight, err := store.RoundHeight(account.Ethereum, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tcHeight != 120 {
+		t.Errorf("round height is %d, expected median 120", tcHeight)
+	}
+
+	if err := sendNewRound(store, consuls[3], 5, 100, 10); err != ErrNewRound {
+		t.Errorf("unexpected error after quorum: %v", err)
+	}
+}
+
+func TestApproveLastRoundAttestations(t *testing.T) {
+	consuls := []account.ConsulPubKey{{1}, {2}, {3}}
+	store, closeStore := newTestStorage(t, consuls)
+	defer closeStore()
+
+	if err := sendApproveLastRound(store, consuls[0], 2, 250); err != ErrInvalidHeight {
+		t.Errorf("unexpected error for wrong round: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := sendApproveLastRound(store, consuls[i], 1, 250); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lastRound, err := store.LastRoundApproved()
+	if err != nil || lastRound != 1 {
+		t.Fatalf("round is not approved: %d %v", lastRound, err)
+	}
+	if err := sendApproveLastRound(store, consuls[2], 1, 250); err != ErrRoundIsExist {
+		t.Errorf("unexpected error after approval: %v", err)
+	}
+}
+
+func TestAttestationQuorumOfRound(t *testing.T) {
+	consuls := []account.ConsulPubKey{{1}, {2}, {3}}
+	store, closeStore := newTestStorage(t, consuls)
+	defer closeStore()
+
+	roundConsuls, err := store.Consuls()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetRoundConsuls(1, roundConsuls); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := append([]storage.Consul{}, roundConsuls...)
+	for _, v := range []account.ConsulPubKey{{4}, {5}, {6}} {
+		rotated = append(rotated, storage.Consul{PubKey: v, Value: 1})
+	}
+	if err := store.SetConsuls(rotated); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sendNewRound(store, account.ConsulPubKey{4}, 200, 100, 210); err != ErrNotConsul {
+		t.Errorf("consul of another round attested: %v", err)
+	}
+	for _, sender := range consuls[:2] {
+		if err := sendNewRound(store, sender, 200, 100, 210); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.RoundHeight(account.Ethereum, 200); err != nil {
+		t.Errorf("quorum of the round consuls is not reached: %v", err)
+	}
+}
+
+func TestFailedTxUsesOnlyNonce(t *testing.T) {
+	signer := newTestSigner("consul")
+	store, closeStore := newSignatureTestStorage(t, signer)
+	defer closeStore()
+
+	args := &transactions.SetNebulaArgs{
+		NebulaId: account.NebulaId{1},
+		Info:     storage.NebulaInfo{Owner: signer.pubKey, Aggregator: "unknown"},
+	}
+	if err := SetState(signer.sign(t, args, 0), store, testChainId); err == nil {
+		t.Fatal("nebula with an unknown aggregator accepted")
+	}
+
+	if nonce, err := store.Nonce(signer.pubKey); err != nil || nonce != 1 {
+		t.Errorf("nonce of a failed transaction: expected 1, got %d (%v)", nonce, err)
+	}
+	if _, err := store.NebulaInfo(args.NebulaId); err != storage.ErrKeyNotFound {
+		t.Errorf("failed transaction reached the state: %v", err)
+	}
+}
+
+func TestNonce(t *testing.T) {
+	sender := account.ConsulPubKey{1}
+	store, closeStore := newTestStorage(t, []account.ConsulPubKey{sender})
+	defer closeStore()
+
+	for i, c := range []struct {
+		nonce uint64
+		err   error
+	}{
+		{0, nil},
+		{0, ErrInvalidNonce},
+		{2, ErrInvalidNonce},
+		{1, nil},
+	} {
+		tx := newTestTx(sender, &transactions.ApproveLastRoundArgs{})
+		tx.Nonce = c.nonce
+		if err := useNonce(store, tx); err != c.err {
+			t.Errorf("case %d: expected %v, got %v", i, c.err, err)
+		}
+	}
+
+	nonce, err := store.Nonce(sender)
+	if err != nil || nonce != 2 {
+		t.Errorf("unexpected nonce %d: %v", nonce, err)
+	}
+}
+
+func TestChainIdBinding(t *testing.T) {
+	sender := account.ConsulPubKey{1}
+	store, closeStore := newTestStorage(t, []account.ConsulPubKey{sender})
+	defer closeStore()
+
+	tx := newTestTx(sender, &transactions.ApproveLastRoundArgs{})
+	tx.Id = tx.Hash("gravity-test")
+
+	if err := SetState(tx, store, "gravity-other"); err != ErrInvalidTxId {
+		t.Errorf("transaction of another chain accepted: %v", err)
+	}
+}
+
+func TestSubRoundAdmission(t *testing.T) {
+	signer := newTestSigner("consul")
+	store, closeStore := newSignatureTestStorage(t, signer)
+	defer closeStore()
+
+	hash := make([]byte, transactions.HashLength)
+	payloads := map[SubRound]transactions.Payload{
+		CommitSubRound: &transactions.CommitArgs{NebulaId: account.NebulaId{1}, PulseId: 1, Height: 1, Commit: hash},
+		RevealSubRound: &transactions.RevealArgs{Commit: hash, NebulaId: account.NebulaId{1}, PulseId: 1, Height: 1, Reveal: []byte{1}, Salt: hash},
+		ResultSubRound: &transactions.ResultArgs{NebulaId: account.NebulaId{1}, PulseId: 1, Sign: []byte{1}, Hash: hash},
+	}
+
+	for height := uint64(4); height < 4+SubRoundCount; height++ {
+		if err := store.SetLastHeight(height); err != nil {
+			t.Fatal(err)
+		}
+		actual := CalculateSubRound(height)
+
+		for expected, payload := range payloads {
+			nonce, err := store.Nonce(signer.pubKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = SetState(signer.sign(t, payload, nonce), store, testChainId)
+			var subRoundErr *SubRoundError
+			if expected == actual {
+				if errors.As(err, &subRoundErr) {
+					t.Errorf("%s at height %d: unexpected rejection: %v", payload.Func(), height, err)
+				}
+				continue
+			}
+
+			if !errors.Is(err, ErrInvalidSubRound) || !errors.As(err, &subRoundErr) {
+				t.Errorf("%s at height %d: expected sub round error, got %v", payload.Func(), height, err)
+				continue
+			}
+			if subRoundErr.Expected != expected || subRoundErr.Actual != actual || subRoundErr.Func != payload.Func() {
+				t.Errorf("%s at height %d: unexpected error fields: %+v", payload.Func(), height, subRoundErr)
+			}
+		}
+	}
+}
+
+func TestPulseWindow(t *testing.T) {
+	window := NewPulseWindow(10)
+	if window.SubRound != ResultSubRound || window.Start != 8 {
+		t.Fatalf("unexpected window: %+v", window)
+	}
+	if window.Commit != 8 || window.Reveal != 9 || window.Result != 10 || window.Send != 11 {
+		t.Errorf("unexpected sub round heights: %+v", window)
+	}
+}
+
+func TestSaltedReveal(t *testing.T) {
+	store, closeStore := newTestStorage(t, nil)
+	defer closeStore()
+
+	nebulaId := account.NebulaId{1}
+	oracle := account.OraclesPubKey{2}
+	value := []byte{3}
+	salt := make([]byte, transactions.SaltLength)
+	salt[0] = 4
+	commitHash := transactions.CommitHash(salt, value)
+
+	err := commit(store, &transactions.CommitArgs{NebulaId: nebulaId, PulseId: 1, Height: 1, Commit: commitHash, OraclePubKey: oracle})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newReveal := func(salt []byte, value []byte) *transactions.RevealArgs {
+		return &transactions.RevealArgs{Commit: commitHash, NebulaId: nebulaId, PulseId: 1, Height: 1, Reveal: value, OraclePubKey: oracle, Salt: salt}
+	}
+	otherSalt := make([]byte, transactions.SaltLength)
+
+	if err := reveal(store, newReveal(otherSalt, value)); err != ErrInvalidReveal {
+		t.Errorf("wrong salt: expected %v, got %v", ErrInvalidReveal, err)
+	}
+	if err := reveal(store, newReveal(salt, []byte{5})); err != ErrInvalidReveal {
+		t.Errorf("wrong value: expected %v, got %v", ErrInvalidReveal, err)
+	}
+	if err := reveal(store, newReveal(salt, value)); err != nil {
+		t.Fatalf("valid reveal rejected: %v", err)
+	}
+	if err := reveal(store, newReveal(salt, value)); err != ErrRevealIsExist {
+		t.Errorf("repeated reveal: expected %v, got %v", ErrRevealIsExist, err)
+	}
+
+	reveals, err := store.Reveals(nebulaId, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reveals) != 1 || !bytes.Equal(reveals[0].Salt, salt) || !bytes.Equal(reveals[0].Value, value) {
+		t.Errorf("unexpected stored reveals: %+v", reveals)
+	}
+}
+
+func TestSetNebulaAggregator(t *testing.T) {
+	owner := account.ConsulPubKey{1}
+	store, closeStore := newTestStorage(t, []account.ConsulPubKey{owner})
+	defer closeStore()
+
+	for i, c := range []struct {
+		aggregator string
+		valid      bool
+	}{
+		{"", true},
+		{"remote", true},
+		{"median", true},
+		{"score-weighted", true},
+		{"average", false},
+	} {
+		args := &transactions.SetNebulaArgs{
+			NebulaId: account.NebulaId{1},
+			Info:     storage.NebulaInfo{Owner: owner, Aggregator: c.aggregator},
+		}
+		err := setNebula(store, newTestTx(owner, args), args)
+		if c.valid && err != nil || !c.valid && err == nil {
+			t.Errorf("case %d: aggregator %q: unexpected result %v", i, c.aggregator, err)
+		}
+	}
+}
+
+func TestSetNebulaAggregatorTx(t *testing.T) {
+	signer := newTestSigner("consul")
+	store, closeStore := newSignatureTestStorage(t, signer)
+	defer closeStore()
+
+	args := &transactions.SetNebulaArgs{
+		NebulaId: account.NebulaId{1},
+		Info:     storage.NebulaInfo{Owner: signer.pubKey, Aggregator: "median"},
+	}
+	tx, err := transactions.Unmarshal(signer.sign(t, args, 0).Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetState(tx, store, testChainId); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := store.NebulaInfo(args.NebulaId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Aggregator != args.Info.Aggregator {
+		t.Errorf("aggregator: expected %q, got %q", args.Info.Aggregator, info.Aggregator)
+	}
+}
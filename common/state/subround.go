@@ -0,0 +1,73 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/Gravity-Tech/gravity-core/common/transactions"
+)
+
+// SubRoundError is returned when a pulse transaction arrives outside of the
+// sub round it belongs to.
+type SubRoundError struct {
+	Func     transactions.TxFunc
+	Expected SubRound
+	Actual   SubRound
+}
+
+func (err *SubRoundError) Error() string {
+	return fmt.Sprintf("%s: %s: expected %s sub round, got %s", ErrInvalidSubRound, err.Func, err.Expected, err.Actual)
+}
+
+func (err *SubRoundError) Is(target error) bool {
+	return target == ErrInvalidSubRound
+}
+
+func (subRound SubRound) String() string {
+	switch subRound {
+	case CommitSubRound:
+		return "commit"
+	case RevealSubRound:
+		return "reveal"
+	case ResultSubRound:
+		return "result"
+	case SendToTargetChain:
+		return "sendToTargetChain"
+	default:
+		return fmt.Sprintf("SubRound(%d)", int64(subRound))
+	}
+}
+
+// PulseWindow describes the pulse that is open at a ledger height. Heights
+// are compared with the last committed ledger height, which is the height
+// oracles see when they send a transaction.
+type PulseWindow struct {
+	LedgerHeight uint64
+	SubRound     SubRound
+	Start        uint64
+	Commit       uint64
+	Reveal       uint64
+	Result       uint64
+	Send         uint64
+}
+
+func NewPulseWindow(height uint64) PulseWindow {
+	start := height - height%SubRoundCount
+	return PulseWindow{
+		LedgerHeight: height,
+		SubRound:     CalculateSubRound(height),
+		Start:        start,
+		Commit:       start + uint64(CommitSubRound),
+		Reveal:       start + uint64(RevealSubRound),
+		Result:       start + uint64(ResultSubRound),
+		Send:         start + uint64(SendToTargetChain),
+	}
+}
+
+func checkSubRound(txFunc transactions.TxFunc, expected SubRound, height uint64) error {
+	actual := CalculateSubRound(height)
+	if actual != expected {
+		return &SubRoundError{Func: txFunc, Expected: expected, Actual: actual}
+	}
+
+	return nil
+}
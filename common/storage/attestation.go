@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+)
+
+// Attestation is an observation of a target chain reported by a consul.
+type Attestation struct {
+	PubKey account.ConsulPubKey
+	Value  uint64
+}
+
+func formRoundAttestationKey(chainType account.ChainType, ledgerHeight uint64) []byte {
+	return formKey(string(RoundAttestationKey), chainType.String(), fmt.Sprintf("%d", ledgerHeight))
+}
+
+func formApproveRoundAttestationKey(roundId uint64) []byte {
+	return formKey(string(ApproveRoundAttestationKey), fmt.Sprintf("%d", roundId))
+}
+
+func (storage *Storage) attestations(key []byte) ([]Attestation, error) {
+	b, err := storage.getValue(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var attestations []Attestation
+	err = json.Unmarshal(b, &attestations)
+	if err != nil {
+		return nil, err
+	}
+
+	return attestations, nil
+}
+
+func (storage *Storage) RoundAttestations(chainType account.ChainType, ledgerHeight uint64) ([]Attestation, error) {
+	return storage.attestations(formRoundAttestationKey(chainType, ledgerHeight))
+}
+
+func (storage *Storage) SetRoundAttestations(chainType account.ChainType, ledgerHeight uint64, attestations []Attestation) error {
+	return storage.setValue(formRoundAttestationKey(chainType, ledgerHeight), attestations)
+}
+
+func (storage *Storage) ApproveRoundAttestations(roundId uint64) ([]Attestation, error) {
+	return storage.attestations(formApproveRoundAttestationKey(roundId))
+}
+
+func (storage *Storage) SetApproveRoundAttestations(roundId uint64, attestations []Attestation) error {
+	return storage.setValue(formApproveRoundAttestationKey(roundId), attestations)
+}
@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/dgraph-io/badger"
+	dbm "github.com/tendermint/tm-db"
+)
+
+var (
+	errEmptyKey     = errors.New("key cannot be empty")
+	errNilValue     = errors.New("value cannot be nil")
+	errBatchWritten = errors.New("batch has been written or closed")
+)
+
+// badgerDB exposes a badger instance as a tm-db backend, so the IAVL tree
+// can keep its nodes in the same database the ledger has always used.
+type badgerDB struct {
+	db *badger.DB
+}
+
+var _ dbm.DB = (*badgerDB)(nil)
+
+func newBadgerDB(db *badger.DB) *badgerDB {
+	return &badgerDB{db: db}
+}
+
+func (b *badgerDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errEmptyKey
+	}
+
+	var value []byte
+	err := b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err == badger.ErrKeyNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		value, err = item.ValueCopy(nil)
+		if err == nil && value == nil {
+			value = []byte{}
+		}
+		return err
+	})
+	return value, err
+}
+
+func (b *badgerDB) Has(key []byte) (bool, error) {
+	value, err := b.Get(key)
+	if err != nil {
+		return false, err
+	}
+
+	return value != nil, nil
+}
+
+func (b *badgerDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errEmptyKey
+	}
+	if value == nil {
+		return errNilValue
+	}
+
+	return b.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, value)
+	})
+}
+
+func (b *badgerDB) SetSync(key []byte, value []byte) error {
+	err := b.Set(key, value)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Sync()
+}
+
+func (b *badgerDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errEmptyKey
+	}
+
+	return b.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+func (b *badgerDB) DeleteSync(key []byte) error {
+	err := b.Delete(key)
+	if err != nil {
+		return err
+	}
+
+	return b.db.Sync()
+}
+
+func (b *badgerDB) Iterator(start, end []byte) (dbm.Iterator, error) {
+	return b.newIterator(start, end, false)
+}
+
+func (b *badgerDB) ReverseIterator(start, end []byte) (dbm.Iterator, error) {
+	return b.newIterator(start, end, true)
+}
+
+func (b *badgerDB) Close() error {
+	return b.db.Close()
+}
+
+func (b *badgerDB) NewBatch() dbm.Batch {
+	return &badgerBatch{
+		db: b.db,
+		wb: b.db.NewWriteBatch(),
+	}
+}
+
+func (b *badgerDB) Print() error {
+	return nil
+}
+
+func (b *badgerDB) Stats() map[string]string {
+	return nil
+}
+
+func (b *badgerDB) newIterator(start, end []byte, reverse bool) (*badgerIterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errEmptyKey
+	}
+
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = reverse
+
+	txn := b.db.NewTransaction(false)
+	it := txn.NewIterator(opts)
+	if !reverse {
+		it.Seek(start)
+	} else if end == nil {
+		it.Rewind()
+	} else {
+		// reverse seek lands on the last key <= end, but end is exclusive
+		it.Seek(end)
+		if it.Valid() && bytes.Equal(it.Item().Key(), end) {
+			it.Next()
+		}
+	}
+
+	return &badgerIterator{
+		start:   start,
+		end:     end,
+		reverse: reverse,
+		txn:     txn,
+		it:      it,
+	}, nil
+}
+
+type badgerIterator struct {
+	start   []byte
+	end     []byte
+	reverse bool
+
+	txn *badger.Txn
+	it  *badger.Iterator
+	err error
+}
+
+func (i *badgerIterator) Domain() ([]byte, []byte) {
+	return i.start, i.end
+}
+
+func (i *badgerIterator) Valid() bool {
+	if !i.it.Valid() {
+		return false
+	}
+
+	key := i.it.Item().Key()
+	if !i.reverse && i.end != nil && bytes.Compare(key, i.end) >= 0 {
+		return false
+	}
+	if i.reverse && i.start != nil && bytes.Compare(key, i.start) < 0 {
+		return false
+	}
+
+	return true
+}
+
+func (i *badgerIterator) Next() {
+	if !i.Valid() {
+		panic("iterator is invalid")
+	}
+	i.it.Next()
+}
+
+func (i *badgerIterator) Key() []byte {
+	if !i.Valid() {
+		panic("iterator is invalid")
+	}
+	return i.it.Item().KeyCopy(nil)
+}
+
+func (i *badgerIterator) Value() []byte {
+	if !i.Valid() {
+		panic("iterator is invalid")
+	}
+
+	value, err := i.it.Item().ValueCopy(nil)
+	if err != nil {
+		i.err = err
+	}
+	return value
+}
+
+func (i *badgerIterator) Error() error {
+	return i.err
+}
+
+func (i *badgerIterator) Close() error {
+	i.it.Close()
+	i.txn.Discard()
+	return nil
+}
+
+type badgerBatch struct {
+	db      *badger.DB
+	wb      *badger.WriteBatch
+	written bool
+}
+
+func (b *badgerBatch) Set(key, value []byte) error {
+	if b.written {
+		return errBatchWritten
+	}
+	if len(key) == 0 {
+		return errEmptyKey
+	}
+	if value == nil {
+		return errNilValue
+	}
+
+	return b.wb.Set(key, value)
+}
+
+func (b *badgerBatch) Delete(key []byte) error {
+	if b.written {
+		return errBatchWritten
+	}
+	if len(key) == 0 {
+		return errEmptyKey
+	}
+
+	return b.wb.Delete(key)
+}
+
+func (b *badgerBatch) Write() error {
+	if b.written {
+		return errBatchWritten
+	}
+	// badger panics on a second Flush or on Cancel after Flush
+	b.written = true
+
+	return b.wb.Flush()
+}
+
+func (b *badgerBatch) WriteSync() error {
+	err := b.Write()
+	if err != nil {
+		return err
+	}
+
+	return b.db.Sync()
+}
+
+func (b *badgerBatch) Close() error {
+	if b.written {
+		return nil
+	}
+	b.written = true
+	b.wb.Cancel()
+
+	return nil
+}
@@ -15,28 +15,14 @@ type Consul struct {
 }
 
 func formSignConsulsByConsulKey(pubKey account.ConsulPubKey, chainType account.ChainType, roundId int64) []byte {
-	prefix := ""
-	switch chainType {
-	case account.Waves:
-		prefix = "waves"
-	case account.Ethereum:
-		prefix = "ethereum"
-	case account.Binance:
-		prefix = "bsc"
-	}
-	return formKey(string(SignConsulsResultByConsulKey), hexutil.Encode(pubKey[:]), prefix, fmt.Sprintf("%d", roundId))
+	return formKey(string(SignConsulsResultByConsulKey), hexutil.Encode(pubKey[:]), chainType.String(), fmt.Sprintf("%d", roundId))
 }
 
 func (storage *Storage) Consuls() ([]Consul, error) {
 	var consuls []Consul
 
 	key := []byte(ConsulsKey)
-	item, err := storage.txn.Get(key)
-	if err != nil {
-		return nil, err
-	}
-
-	b, err := item.ValueCopy(nil)
+	b, err := storage.getValue(key)
 	if err != nil {
 		return nil, err
 	}
@@ -52,16 +38,35 @@ func (storage *Storage) SetConsuls(consuls []Consul) error {
 	return storage.setValue([]byte(ConsulsKey), consuls)
 }
 
-func (storage *Storage) ConsulsCandidate() ([]Consul, error) {
+func formRoundConsulsKey(roundId uint64) []byte {
+	return formKey(string(RoundConsulsKey), fmt.Sprintf("%d", roundId))
+}
+
+// RoundConsuls returns the consuls as they were at the start of the round.
+func (storage *Storage) RoundConsuls(roundId uint64) ([]Consul, error) {
 	var consuls []Consul
 
-	key := []byte(ConsulsCandidateKey)
-	item, err := storage.txn.Get(key)
+	b, err := storage.getValue(formRoundConsulsKey(roundId))
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, &consuls)
 	if err != nil {
 		return nil, err
 	}
 
-	b, err := item.ValueCopy(nil)
+	return consuls, err
+}
+func (storage *Storage) SetRoundConsuls(roundId uint64, consuls []Consul) error {
+	return storage.setValue(formRoundConsulsKey(roundId), consuls)
+}
+
+func (storage *Storage) ConsulsCandidate() ([]Consul, error) {
+	var consuls []Consul
+
+	key := []byte(ConsulsCandidateKey)
+	b, err := storage.getValue(key)
 	if err != nil {
 		return nil, err
 	}
@@ -79,12 +84,7 @@ func (storage *Storage) SetConsulsCandidate(consuls []Consul) error {
 
 func (storage *Storage) SignConsulsByConsul(consulPubKey account.ConsulPubKey, chainType account.ChainType, roundId int64) ([]byte, error) {
 	key := formSignConsulsByConsulKey(consulPubKey, chainType, roundId)
-	item, err := storage.txn.Get(key)
-	if err != nil {
-		return nil, err
-	}
-
-	b, err := item.ValueCopy(nil)
+	b, err := storage.getValue(key)
 	if err != nil {
 		return nil, err
 	}
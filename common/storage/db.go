@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/cosmos/iavl"
+	"github.com/dgraph-io/badger"
+	dbm "github.com/tendermint/tm-db"
+)
+
+const (
+	treeCacheSize = 10000
+)
+
+var (
+	ErrVersionNotFound = errors.New("state version not found")
+)
+
+// DB is the versioned Merkle state of the ledger. Each committed block is
+// saved as a new tree version and its root hash is the application hash.
+type DB struct {
+	tree    *iavl.MutableTree
+	pending map[string][]byte
+
+	pinLock sync.Mutex
+	pinned  map[int64]int
+}
+
+// PinnedVersion is a committed version that Prune keeps until Release, so it
+// can be read outside of the ABCI connection.
+type PinnedVersion struct {
+	db      *DB
+	tree    *iavl.ImmutableTree
+	version int64
+}
+
+func OpenDB(db *badger.DB) (*DB, error) {
+	tree, err := iavl.NewMutableTree(newBadgerDB(db), treeCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tree.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &DB{
+		tree:    tree,
+		pending: make(map[string][]byte),
+		pinned:  make(map[int64]int),
+	}, nil
+}
+
+func (db *DB) Version() int64 {
+	return db.tree.Version()
+}
+
+func (db *DB) Hash() []byte {
+	return db.tree.Hash()
+}
+
+// Commit applies the pending writes in key order and saves a new version.
+func (db *DB) Commit() ([]byte, int64, error) {
+	for _, k := range sortedKeys(db.pending) {
+		db.tree.Set([]byte(k), db.pending[k])
+	}
+	db.pending = make(map[string][]byte)
+
+	return db.tree.SaveVersion()
+}
+
+// Pin keeps version from being pruned until the returned version is
+// released. It must be called with the other DB methods, the pinned version
+// can then be read from any goroutine.
+func (db *DB) Pin(version int64) (*PinnedVersion, error) {
+	tree, err := db.immutable(version)
+	if err != nil {
+		return nil, err
+	}
+
+	db.pinLock.Lock()
+	db.pinned[version]++
+	db.pinLock.Unlock()
+
+	return &PinnedVersion{
+		db:      db,
+		tree:    tree,
+		version: version,
+	}, nil
+}
+
+func (v *PinnedVersion) Version() int64 {
+	return v.version
+}
+
+// Release lets Prune delete the version.
+func (v *PinnedVersion) Release() {
+	v.db.pinLock.Lock()
+	defer v.db.pinLock.Unlock()
+
+	v.db.pinned[v.version]--
+	if v.db.pinned[v.version] <= 0 {
+		delete(v.db.pinned, v.version)
+	}
+}
+
+// Prune deletes every version but the last retain ones. The versions from the
+// oldest pinned one on are kept until it is released.
+func (db *DB) Prune(retain int64) error {
+	if retain <= 0 {
+		return nil
+	}
+
+	versions := db.tree.AvailableVersions()
+	if len(versions) == 0 {
+		return nil
+	}
+
+	from := int64(versions[0])
+	to := db.tree.Version() - retain + 1
+	db.pinLock.Lock()
+	for version := range db.pinned {
+		if version < to {
+			to = version
+		}
+	}
+	db.pinLock.Unlock()
+	if from >= to {
+		return nil
+	}
+
+	return db.tree.DeleteVersionsRange(from, to)
+}
+
+func (db *DB) immutable(version int64) (*iavl.ImmutableTree, error) {
+	if version == 0 && db.tree.Version() == 0 {
+		return iavl.NewImmutableTree(dbm.NewMemDB(), 0), nil
+	}
+	if version <= 0 || version > db.tree.Version() || !db.tree.VersionExists(version) {
+		return nil, ErrVersionNotFound
+	}
+
+	return db.tree.GetImmutable(version)
+}
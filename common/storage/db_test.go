@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/dgraph-io/badger"
+)
+
+func openTestDB(t *testing.T, dir string) (*badger.DB, *DB) {
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stateDB, err := OpenDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db, stateDB
+}
+
+func TestCommitAppHash(t *testing.T) {
+	dirA, _ := ioutil.TempDir("", "gravity-state")
+	defer os.RemoveAll(dirA)
+	dirB, _ := ioutil.TempDir("", "gravity-state")
+	defer os.RemoveAll(dirB)
+
+	badgerA, dbA := openTestDB(t, dirA)
+	defer badgerA.Close()
+	badgerB, dbB := openTestDB(t, dirB)
+	defer badgerB.Close()
+
+	storageA := New()
+	storageA.NewTransaction(dbA)
+	storageB := New()
+	storageB.NewTransaction(dbB)
+
+	for _, s := range []*Storage{storageA, storageB} {
+		if err := s.SetConsulsCount(5); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetLastHeight(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hashA, versionA, err := dbA.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashB, _, err := dbB.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if versionA != 1 {
+		t.Errorf("invalid version: %d", versionA)
+	}
+	if len(hashA) == 0 || !bytes.Equal(hashA, hashB) {
+		t.Error("equal states must have equal app hashes")
+	}
+
+	if err := storageB.SetLastHeight(2); err != nil {
+		t.Fatal(err)
+	}
+	hashA, _, _ = dbA.Commit()
+	hashB, _, _ = dbB.Commit()
+	if bytes.Equal(hashA, hashB) {
+		t.Error("diverged states must have different app hashes")
+	}
+}
+
+func TestCacheTransaction(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gravity-state")
+	defer os.RemoveAll(dir)
+
+	db, stateDB := openTestDB(t, dir)
+
+	deliver := New()
+	deliver.NewTransaction(stateDB)
+	if err := deliver.SetLastHeight(1); err != nil {
+		t.Fatal(err)
+	}
+	hash, _, err := stateDB.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := New()
+	if err := check.NewCacheTransaction(stateDB); err != nil {
+		t.Fatal(err)
+	}
+	if err := check.SetLastHeight(10); err != nil {
+		t.Fatal(err)
+	}
+	height, err := check.LastHeight()
+	if err != nil || height != 10 {
+		t.Errorf("cache transaction must see its own writes: %d", height)
+	}
+	if !bytes.Equal(stateDB.Hash(), hash) {
+		t.Error("cache transaction must not change the state")
+	}
+
+	db.Close()
+	db, stateDB = openTestDB(t, dir)
+	defer db.Close()
+
+	if stateDB.Version() != 1 || !bytes.Equal(stateDB.Hash(), hash) {
+		t.Error("committed state must survive reopening")
+	}
+}
+
+func TestHistoricalState(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gravity-state")
+	defer os.RemoveAll(dir)
+
+	db, stateDB := openTestDB(t, dir)
+	defer db.Close()
+
+	deliver := New()
+	deliver.NewTransaction(stateDB)
+	for height := uint64(1); height <= 5; height++ {
+		if err := deliver.SetLastHeight(height * 10); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := stateDB.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for version := int64(1); version <= 5; version++ {
+		store := New()
+		if err := store.NewCacheTransactionAt(stateDB, version); err != nil {
+			t.Fatal(err)
+		}
+		height, err := store.LastHeight()
+		if err != nil || height != uint64(version*10) {
+			t.Errorf("invalid state at version %d: %d", version, height)
+		}
+	}
+
+	if err := stateDB.Prune(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := New().NewCacheTransactionAt(stateDB, 3); err != ErrVersionNotFound {
+		t.Error("pruned version must not be readable")
+	}
+	if err := New().NewCacheTransactionAt(stateDB, 4); err != nil {
+		t.Errorf("retained version must be readable: %s", err)
+	}
+	if err := New().NewCacheTransactionAt(stateDB, 6); err != ErrVersionNotFound {
+		t.Error("future version must not be readable")
+	}
+}
+
+func TestPinnedVersionIsNotPruned(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gravity-state")
+	defer os.RemoveAll(dir)
+
+	db, stateDB := openTestDB(t, dir)
+	defer db.Close()
+
+	deliver := New()
+	deliver.NewTransaction(stateDB)
+	commit := func(height uint64) {
+		if err := deliver.SetLastHeight(height); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := stateDB.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if err := stateDB.Prune(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commit(1)
+	pinned, err := stateDB.Pin(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit(2)
+	commit(3)
+
+	if err := pinned.Export(ioutil.Discard); err != nil {
+		t.Errorf("pinned version: %s", err)
+	}
+	if _, err := stateDB.immutable(2); err != nil {
+		t.Errorf("version after the pinned one: %s", err)
+	}
+
+	pinned.Release()
+	commit(4)
+	if _, err := stateDB.immutable(1); err != ErrVersionNotFound {
+		t.Error("released version must be pruned")
+	}
+}
+
+func TestBranch(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gravity-state")
+	defer os.RemoveAll(dir)
+
+	db, stateDB := openTestDB(t, dir)
+	defer db.Close()
+
+	store := New()
+	store.NewTransaction(stateDB)
+	if err := store.SetScore(account.ConsulPubKey{1}, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	branch := store.Branch()
+	if err := branch.SetScore(account.ConsulPubKey{2}, 20); err != nil {
+		t.Fatal(err)
+	}
+	scores, err := branch.Scores()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 2 {
+		t.Errorf("branch scores: unexpected %v", scores)
+	}
+	if _, err := store.Score(account.ConsulPubKey{2}); err != ErrKeyNotFound {
+		t.Fatal("branch write reached the storage before Write")
+	}
+
+	branch.Write()
+	if score, err := store.Score(account.ConsulPubKey{2}); err != nil || score != 20 {
+		t.Errorf("written score: expected 20, got %d (%v)", score, err)
+	}
+}
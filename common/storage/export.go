@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/cosmos/iavl"
+)
+
+const (
+	nodeFlagNilValue byte = 1
+)
+
+var (
+	ErrStateNotEmpty = errors.New("state is not empty")
+)
+
+// Export writes every node of the given version to w. The stream can be
+// imported into an empty DB and reproduces the same root hash.
+func (db *DB) Export(version int64, w io.Writer) error {
+	pinned, err := db.Pin(version)
+	if err != nil {
+		return err
+	}
+	defer pinned.Release()
+
+	return pinned.Export(w)
+}
+
+// Export writes every node of the pinned version to w, see DB.Export.
+func (v *PinnedVersion) Export(w io.Writer) error {
+	exporter := v.tree.Export()
+	defer exporter.Close()
+
+	bw := bufio.NewWriter(w)
+	for {
+		node, err := exporter.Next()
+		if err == iavl.ExportDone {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		err = writeExportNode(bw, node)
+		if err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Import restores a version written by Export. The DB must be empty.
+func (db *DB) Import(version int64, r io.Reader) error {
+	if db.tree.Version() != 0 {
+		return ErrStateNotEmpty
+	}
+
+	importer, err := db.tree.Import(version)
+	if err != nil {
+		return err
+	}
+	defer importer.Close()
+
+	br := bufio.NewReader(r)
+	for {
+		node, err := readExportNode(br)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		err = importer.Add(node)
+		if err != nil {
+			return err
+		}
+	}
+
+	return importer.Commit()
+}
+
+func writeExportNode(w *bufio.Writer, node *iavl.ExportNode) error {
+	var buf [binary.MaxVarintLen64]byte
+
+	flags := byte(0)
+	if node.Value == nil {
+		flags |= nodeFlagNilValue
+	}
+	err := w.WriteByte(flags)
+	if err != nil {
+		return err
+	}
+	err = w.WriteByte(byte(node.Height))
+	if err != nil {
+		return err
+	}
+
+	n := binary.PutVarint(buf[:], node.Version)
+	_, err = w.Write(buf[:n])
+	if err != nil {
+		return err
+	}
+
+	err = writeBytes(w, node.Key)
+	if err != nil {
+		return err
+	}
+	if node.Value != nil {
+		return writeBytes(w, node.Value)
+	}
+
+	return nil
+}
+
+func readExportNode(r *bufio.Reader) (*iavl.ExportNode, error) {
+	flags, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := r.ReadByte()
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	version, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	key, err := readBytes(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	if flags&nodeFlagNilValue == 0 {
+		value, err = readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &iavl.ExportNode{
+		Key:     key,
+		Value:   value,
+		Version: version,
+		Height:  int8(height),
+	}, nil
+}
+
+func writeBytes(w *bufio.Writer, b []byte) error {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(len(b)))
+	_, err := w.Write(buf[:n])
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+func readBytes(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := make([]byte, size)
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	return b, nil
+}
@@ -2,21 +2,64 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"strings"
 
-	"github.com/dgraph-io/badger"
-
 	"github.com/ethereum/go-ethereum/common/hexutil"
 
 	"github.com/Gravity-Tech/gravity-core/common/account"
 )
 
+// AggregatorType names the aggregation of the reveals of a nebula. Remote
+// posts them to the aggregate endpoint of the extractor, the others are
+// computed by the oracle itself.
+type AggregatorType string
+
+const (
+	RemoteAggregator        AggregatorType = "remote"
+	MedianAggregator        AggregatorType = "median"
+	TrimmedMeanAggregator   AggregatorType = "trimmed-mean"
+	ModeAggregator          AggregatorType = "mode"
+	ScoreWeightedAggregator AggregatorType = "score-weighted"
+)
+
+var (
+	ErrUnknownAggregator = errors.New("unknown aggregator type")
+
+	aggregatorTypes = map[AggregatorType]bool{
+		RemoteAggregator:        true,
+		MedianAggregator:        true,
+		TrimmedMeanAggregator:   true,
+		ModeAggregator:          true,
+		ScoreWeightedAggregator: true,
+	}
+)
+
+// ParseAggregatorType returns the aggregator type of value. An empty value
+// is RemoteAggregator, as it was the only aggregation before the types.
+func ParseAggregatorType(value string) (AggregatorType, error) {
+	if value == "" {
+		return RemoteAggregator, nil
+	}
+	if !aggregatorTypes[AggregatorType(value)] {
+		return "", ErrUnknownAggregator
+	}
+
+	return AggregatorType(value), nil
+}
+
 type NebulaMap map[string]NebulaInfo
 type NebulaInfo struct {
 	MaxPulseCountInBlock uint64
 	MinScore             uint64
 	ChainType            account.ChainType
 	Owner                account.ConsulPubKey
+	// Aggregator is the aggregator type of the reveals, empty means the
+	// aggregate endpoint of the extractor.
+	Aggregator string `json:",omitempty"`
+	// Precision is the number of fraction digits of the Decimal values of
+	// the nebula, reveals with another precision are not aggregated.
+	Precision uint8 `json:",omitempty"`
 }
 
 func parseNebulaInfoKey(value []byte) (account.NebulaId, error) {
@@ -34,30 +77,23 @@ func formNebulaInfoKey(nebulaId account.NebulaId) []byte {
 }
 
 func (storage *Storage) Nebulae() (NebulaMap, error) {
-	it := storage.txn.NewIterator(badger.DefaultIteratorOptions)
-	defer it.Close()
-
 	prefix := []byte(NebulaInfoKey)
 	nebulaeInfo := make(NebulaMap)
-	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-		item := it.Item()
-		k := item.Key()
-		err := item.Value(func(v []byte) error {
-			var nebulaInfo NebulaInfo
-			err := json.Unmarshal(v, &nebulaInfo)
-			if err != nil {
-				return err
-			}
-			pubKey, err := parseNebulaInfoKey(k)
-			if err != nil {
-				return err
-			}
-			nebulaeInfo[pubKey.ToString(nebulaInfo.ChainType)] = nebulaInfo
-			return nil
-		})
+	err := storage.iterate(prefix, func(k []byte, v []byte) error {
+		var nebulaInfo NebulaInfo
+		err := json.Unmarshal(v, &nebulaInfo)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		pubKey, err := parseNebulaInfoKey(k)
+		if err != nil {
+			return err
+		}
+		nebulaeInfo[pubKey.ToString(nebulaInfo.ChainType)] = nebulaInfo
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return nebulaeInfo, nil
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"encoding/binary"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func formNonceKey(pubKey account.ConsulPubKey) []byte {
+	return formKey(string(NonceKey), hexutil.Encode(pubKey[:]))
+}
+
+// Nonce returns the nonce expected in the next transaction of the consul.
+func (storage *Storage) Nonce(pubKey account.ConsulPubKey) (uint64, error) {
+	b, err := storage.getValue(formNonceKey(pubKey))
+	if err == ErrKeyNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func (storage *Storage) SetNonce(pubKey account.ConsulPubKey, nonce uint64) error {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], nonce)
+	return storage.setValue(formNonceKey(pubKey), b[:])
+}
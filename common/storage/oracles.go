@@ -107,12 +107,7 @@ func (storage *Storage) SetOraclesByConsul(pubKey account.ConsulPubKey, oracles
 
 func (storage *Storage) SignOraclesByConsul(pubKey account.ConsulPubKey, nebulaId account.NebulaId, roundId int64) ([]byte, error) {
 	key := formSignOraclesByConsulKey(pubKey, nebulaId, roundId)
-	item, err := storage.txn.Get(key)
-	if err != nil {
-		return nil, err
-	}
-
-	b, err := item.ValueCopy(nil)
+	b, err := storage.getValue(key)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/cosmos/iavl"
+	iavlproto "github.com/cosmos/iavl/proto"
+	tmcrypto "github.com/tendermint/tendermint/proto/tendermint/crypto"
+)
+
+const (
+	// ProofOpKey proves the value or the absence of one key.
+	ProofOpKey = "gravity:key"
+	// ProofOpRange proves every key and value under a prefix.
+	ProofOpRange = "gravity:range"
+)
+
+var (
+	ErrProofNotSupported = errors.New("proof is supported only for reads of committed state")
+	ErrInvalidProof      = errors.New("invalid proof")
+	ErrUnprovenRead      = errors.New("read of unproven state")
+)
+
+// proofData is the data of a proof op: the values read by the query and the
+// range proof of the tree that contains them.
+type proofData struct {
+	Keys   [][]byte
+	Values [][]byte
+	Proof  []byte
+}
+
+// Proof returns the Merkle proofs of every key read and every prefix iterated
+// by a cache transaction. Each op is verified on its own against the app hash,
+// see NewProvenTransaction.
+func (storage *Storage) Proof() (*tmcrypto.ProofOps, error) {
+	store, ok := storage.store.(*cacheStore)
+	if !ok || len(store.writes) != 0 {
+		return nil, ErrProofNotSupported
+	}
+
+	ops := &tmcrypto.ProofOps{}
+	for _, key := range store.reads {
+		value, proof, err := store.tree.GetWithProof(key)
+		if err != nil {
+			return nil, err
+		}
+
+		data := proofData{}
+		if value != nil {
+			data.Keys = [][]byte{key}
+			data.Values = [][]byte{value}
+		}
+		op, err := proofOp(ProofOpKey, key, proof, data)
+		if err != nil {
+			return nil, err
+		}
+		ops.Ops = append(ops.Ops, op)
+	}
+
+	for _, prefix := range store.prefixes {
+		keys, values, proof, err := store.tree.GetRangeWithProof(prefix, prefixEnd(prefix), 0)
+		if err != nil {
+			return nil, err
+		}
+
+		op, err := proofOp(ProofOpRange, prefix, proof, proofData{Keys: keys, Values: values})
+		if err != nil {
+			return nil, err
+		}
+		ops.Ops = append(ops.Ops, op)
+	}
+
+	return ops, nil
+}
+
+func proofOp(opType string, key []byte, proof *iavl.RangeProof, data proofData) (tmcrypto.ProofOp, error) {
+	if proof == nil {
+		return tmcrypto.ProofOp{}, ErrProofNotSupported
+	}
+
+	b, err := proof.ToProto().Marshal()
+	if err != nil {
+		return tmcrypto.ProofOp{}, err
+	}
+	data.Proof = b
+
+	b, err = json.Marshal(data)
+	if err != nil {
+		return tmcrypto.ProofOp{}, err
+	}
+
+	return tmcrypto.ProofOp{Type: opType, Key: key, Data: b}, nil
+}
+
+// NewProvenTransaction verifies the proof ops against the app hash root and
+// binds the storage to the proven values. Reads outside of them make Proven
+// fail.
+func (storage *Storage) NewProvenTransaction(root []byte, ops *tmcrypto.ProofOps) error {
+	if ops == nil {
+		return ErrInvalidProof
+	}
+
+	store := newProvenStore()
+	for _, op := range ops.Ops {
+		err := store.verify(root, op)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidProof, err.Error())
+		}
+	}
+
+	storage.store = store
+	return nil
+}
+
+// Proven returns ErrUnprovenRead if the storage read a key or iterated a
+// prefix that the proof of NewProvenTransaction does not cover.
+func (storage *Storage) Proven() error {
+	store, ok := storage.store.(*provenStore)
+	if !ok || store.unproven {
+		return ErrUnprovenRead
+	}
+
+	return nil
+}
+
+// provenStore serves the values of verified proof ops.
+type provenStore struct {
+	values   map[string][]byte
+	keys     map[string]bool
+	prefixes [][]byte
+	unproven bool
+}
+
+func newProvenStore() *provenStore {
+	return &provenStore{
+		values: make(map[string][]byte),
+		keys:   make(map[string]bool),
+	}
+}
+
+func (store *provenStore) verify(root []byte, op tmcrypto.ProofOp) error {
+	var data proofData
+	err := json.Unmarshal(op.Data, &data)
+	if err != nil {
+		return err
+	}
+	if len(data.Keys) != len(data.Values) {
+		return errors.New("keys and values mismatch")
+	}
+
+	var pbProof iavlproto.RangeProof
+	err = pbProof.Unmarshal(data.Proof)
+	if err != nil {
+		return err
+	}
+	proof, err := iavl.RangeProofFromProto(&pbProof)
+	if err != nil {
+		return err
+	}
+	err = proof.Verify(root)
+	if err != nil {
+		return err
+	}
+
+	switch op.Type {
+	case ProofOpKey:
+		store.keys[string(op.Key)] = true
+		if len(data.Keys) == 0 {
+			return proof.VerifyAbsence(op.Key)
+		}
+		if len(data.Keys) != 1 || !bytes.Equal(data.Keys[0], op.Key) {
+			return errors.New("proof and value mismatch")
+		}
+		store.values[string(op.Key)] = data.Values[0]
+
+		return proof.VerifyItem(op.Key, data.Values[0])
+	case ProofOpRange:
+		return store.verifyRange(&proof, op.Key, data)
+	default:
+		return errors.New("unknown proof op " + op.Type)
+	}
+}
+
+// verifyRange checks that data holds every key under prefix. The leaves of a
+// verified range proof are adjacent in the tree, so the range is complete
+// when both of its ends are covered by the proof.
+func (store *provenStore) verifyRange(proof *iavl.RangeProof, prefix []byte, data proofData) error {
+	start, end := prefix, prefixEnd(prefix)
+	if len(start) == 0 || end == nil {
+		return errors.New("unbounded range")
+	}
+	err := covered(proof, start)
+	if err != nil {
+		return err
+	}
+	err = covered(proof, end)
+	if err != nil {
+		return err
+	}
+
+	var inRange [][]byte
+	for _, key := range proof.Keys() {
+		if bytes.Compare(key, start) >= 0 && bytes.Compare(key, end) < 0 {
+			inRange = append(inRange, key)
+		}
+	}
+	if len(inRange) != len(data.Keys) {
+		return errors.New("proof and values mismatch")
+	}
+	for i, key := range inRange {
+		if !bytes.Equal(key, data.Keys[i]) {
+			return errors.New("proof and values mismatch")
+		}
+		err = proof.VerifyItem(key, data.Values[i])
+		if err != nil {
+			return err
+		}
+		store.values[string(key)] = data.Values[i]
+	}
+	store.prefixes = append(store.prefixes, prefix)
+
+	return nil
+}
+
+// covered checks that key is a leaf of the proof or is proven absent by it.
+func covered(proof *iavl.RangeProof, key []byte) error {
+	for _, k := range proof.Keys() {
+		if bytes.Equal(k, key) {
+			return nil
+		}
+	}
+
+	return proof.VerifyAbsence(key)
+}
+
+func (store *provenStore) isProven(key []byte) bool {
+	return store.keys[string(key)] || store.inProvenRange(key)
+}
+
+func (store *provenStore) inProvenRange(key []byte) bool {
+	for _, prefix := range store.prefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (store *provenStore) get(key []byte) []byte {
+	if !store.isProven(key) {
+		store.unproven = true
+		return nil
+	}
+
+	return store.values[string(key)]
+}
+
+func (store *provenStore) set(key []byte, value []byte) {
+	store.unproven = true
+}
+
+func (store *provenStore) iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	if len(prefix) == 0 || !store.inProvenRange(prefix) {
+		store.unproven = true
+		return ErrUnprovenRead
+	}
+
+	values := make(map[string][]byte)
+	for k, v := range store.values {
+		if bytes.HasPrefix([]byte(k), prefix) {
+			values[k] = v
+		}
+	}
+
+	for _, k := range sortedKeys(values) {
+		err := fn([]byte(k), values[k])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	tmcrypto "github.com/tendermint/tendermint/proto/tendermint/crypto"
+)
+
+// prove runs read on a cache transaction of the last version and returns the
+// proof of its reads.
+func prove(t *testing.T, stateDB *DB, read func(store *Storage) error) *tmcrypto.ProofOps {
+	store := New()
+	if err := store.NewCacheTransaction(stateDB); err != nil {
+		t.Fatal(err)
+	}
+	if err := read(store); err != nil && err != ErrKeyNotFound {
+		t.Fatal(err)
+	}
+
+	proof, err := store.Proof()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proof
+}
+
+func TestProof(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gravity-state")
+	defer os.RemoveAll(dir)
+
+	db, stateDB := openTestDB(t, dir)
+	defer db.Close()
+
+	deliver := New()
+	deliver.NewTransaction(stateDB)
+	if err := deliver.SetLastHeight(7); err != nil {
+		t.Fatal(err)
+	}
+	for i := byte(1); i <= 3; i++ {
+		if err := deliver.SetScore(account.ConsulPubKey{i}, uint64(i)*10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := deliver.SetNonce(account.ConsulPubKey{9}, 1); err != nil {
+		t.Fatal(err)
+	}
+	hash, _, err := stateDB.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof := prove(t, stateDB, func(store *Storage) error {
+		_, err := store.LastHeight()
+		return err
+	})
+	proven := New()
+	if err := proven.NewProvenTransaction(hash, proof); err != nil {
+		t.Fatalf("valid inclusion proof rejected: %s", err)
+	}
+	if height, err := proven.LastHeight(); err != nil || height != 7 {
+		t.Errorf("proven last height: expected 7, got %d (%v)", height, err)
+	}
+	if err := proven.Proven(); err != nil {
+		t.Error(err)
+	}
+	if _, err := proven.ConsulsCount(); err != ErrKeyNotFound {
+		t.Fatal("unproven key must read as absent")
+	}
+	if err := proven.Proven(); err != ErrUnprovenRead {
+		t.Error("read of an unproven key must be reported")
+	}
+
+	var data proofData
+	if err := json.Unmarshal(proof.Ops[0].Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	data.Values[0] = []byte{0, 0, 0, 0, 0, 0, 0, 8}
+	proof.Ops[0].Data, _ = json.Marshal(data)
+	if err := New().NewProvenTransaction(hash, proof); err == nil {
+		t.Error("inclusion proof of a forged value accepted")
+	}
+
+	proof = prove(t, stateDB, func(store *Storage) error {
+		_, err := store.ConsulsCount()
+		return err
+	})
+	proven = New()
+	if err := proven.NewProvenTransaction(hash, proof); err != nil {
+		t.Fatalf("valid absence proof rejected: %s", err)
+	}
+	if _, err := proven.ConsulsCount(); err != ErrKeyNotFound {
+		t.Error("proven absent key must read as absent")
+	}
+
+	proof = prove(t, stateDB, func(store *Storage) error {
+		_, err := store.Scores()
+		return err
+	})
+	proven = New()
+	if err := proven.NewProvenTransaction(hash, proof); err != nil {
+		t.Fatalf("valid range proof rejected: %s", err)
+	}
+	scores, err := proven.Scores()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 3 || scores[account.ConsulPubKey{2}] != 20 {
+		t.Errorf("proven scores: unexpected %v", scores)
+	}
+	if err := proven.Proven(); err != nil {
+		t.Error(err)
+	}
+	proven.Nonce(account.ConsulPubKey{9})
+	if err := proven.Proven(); err != ErrUnprovenRead {
+		t.Error("read outside of the proven range must be reported")
+	}
+
+	if err := json.Unmarshal(proof.Ops[0].Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	data.Keys, data.Values = data.Keys[1:], data.Values[1:]
+	proof.Ops[0].Data, _ = json.Marshal(data)
+	if err := New().NewProvenTransaction(hash, proof); err == nil {
+		t.Error("range proof with a missing key accepted")
+	}
+}
@@ -3,9 +3,9 @@ package storage
 import (
 	"encoding/base64"
 	"fmt"
+	"strings"
 
 	"github.com/Gravity-Tech/gravity-core/common/account"
-	"github.com/dgraph-io/badger"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 )
@@ -23,17 +23,14 @@ func (storage *Storage) Result(nebulaId account.NebulaId, pulseId int64, oracleP
 	return b, err
 }
 func (storage *Storage) Results(nebulaId account.NebulaId, pulseId uint64) ([]string, error) {
-	it := storage.txn.NewIterator(badger.DefaultIteratorOptions)
-	defer it.Close()
-
 	prefix := formKey(string(SignResultKey), hexutil.Encode(nebulaId[:]), fmt.Sprintf("%d", pulseId))
 	var values []string
-	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-		item := it.Item()
-		item.Value(func(v []byte) error {
-			values = append(values, base64.StdEncoding.EncodeToString(v))
-			return nil
-		})
+	err := storage.iterate(prefix, func(k []byte, v []byte) error {
+		values = append(values, base64.StdEncoding.EncodeToString(v))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return values, nil
@@ -41,3 +38,50 @@ func (storage *Storage) Results(nebulaId account.NebulaId, pulseId uint64) ([]st
 func (storage *Storage) SetResult(nebulaId account.NebulaId, pulseId int64, oraclePubKey account.OraclesPubKey, sign []byte) error {
 	return storage.setValue(formResultKey(nebulaId, pulseId, oraclePubKey), sign)
 }
+
+func formResultHashKey(nebulaId account.NebulaId, pulseId int64, oraclePubKey account.OraclesPubKey) []byte {
+	return formKey(string(ResultHashKey), hexutil.Encode(nebulaId[:]), fmt.Sprintf("%d", pulseId), hexutil.Encode(oraclePubKey[:]))
+}
+
+// ResultHashes returns the hashes signed by the oracles in their results of
+// the pulse.
+func (storage *Storage) ResultHashes(nebulaId account.NebulaId, pulseId int64) (map[account.OraclesPubKey][]byte, error) {
+	prefix := formKey(string(ResultHashKey), hexutil.Encode(nebulaId[:]), fmt.Sprintf("%d", pulseId), "")
+	values := make(map[account.OraclesPubKey][]byte)
+	err := storage.iterate(prefix, func(k []byte, v []byte) error {
+		parts := strings.Split(string(k), Separator)
+		key, err := hexutil.Decode(parts[len(parts)-1])
+		if err != nil {
+			return err
+		}
+		var oraclePubKey account.OraclesPubKey
+		copy(oraclePubKey[:], key)
+
+		values[oraclePubKey] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+func (storage *Storage) ResultHash(nebulaId account.NebulaId, pulseId int64, oraclePubKey account.OraclesPubKey) ([]byte, error) {
+	return storage.getValue(formResultHashKey(nebulaId, pulseId, oraclePubKey))
+}
+func (storage *Storage) SetResultHash(nebulaId account.NebulaId, pulseId int64, oraclePubKey account.OraclesPubKey, hash []byte) error {
+	return storage.setValue(formResultHashKey(nebulaId, pulseId, oraclePubKey), hash)
+}
+
+func formPulseHashKey(nebulaId account.NebulaId, pulseId int64) []byte {
+	return formKey(string(PulseHashKey), hexutil.Encode(nebulaId[:]), fmt.Sprintf("%d", pulseId))
+}
+
+// PulseHash returns the result hash of the pulse that the BFT oracles of the
+// nebula agreed on.
+func (storage *Storage) PulseHash(nebulaId account.NebulaId, pulseId int64) ([]byte, error) {
+	return storage.getValue(formPulseHashKey(nebulaId, pulseId))
+}
+func (storage *Storage) SetPulseHash(nebulaId account.NebulaId, pulseId int64, hash []byte) error {
+	return storage.setValue(formPulseHashKey(nebulaId, pulseId), hash)
+}
@@ -1,46 +1,89 @@
 package storage
 
 import (
-	"encoding/base64"
+	"encoding/json"
 	"fmt"
-
-	"github.com/dgraph-io/badger"
+	"strings"
 
 	"github.com/Gravity-Tech/gravity-core/common/account"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
+// RevealValue is a revealed pulse value together with the salt that was
+// hashed into its commit.
+type RevealValue struct {
+	Salt  []byte
+	Value []byte
+}
+
 func formRevealKey(nebulaId account.NebulaId, height int64, pulseId int64, commitHash []byte, oraclePubKey account.OraclesPubKey) []byte {
 	return formKey(string(RevealKey), hexutil.Encode(nebulaId[:]), fmt.Sprintf("%d", height), fmt.Sprintf("%d", pulseId), hexutil.Encode(commitHash), hexutil.Encode(oraclePubKey[:]))
 }
 
-func (storage *Storage) Reveal(nebulaId account.NebulaId, height int64, pulseId int64, commitHash []byte, oraclePubKey account.OraclesPubKey) ([]byte, error) {
+func (storage *Storage) Reveal(nebulaId account.NebulaId, height int64, pulseId int64, commitHash []byte, oraclePubKey account.OraclesPubKey) (*RevealValue, error) {
 	b, err := storage.getValue(formRevealKey(nebulaId, height, pulseId, commitHash, oraclePubKey))
 	if err != nil {
 		return nil, err
 	}
 
-	return b, err
-}
+	var reveal RevealValue
+	err = json.Unmarshal(b, &reveal)
+	if err != nil {
+		return nil, err
+	}
 
-func (storage *Storage) Reveals(nebulaId account.NebulaId, height int64, pulseId int64) ([]string, error) {
-	it := storage.txn.NewIterator(badger.DefaultIteratorOptions)
-	defer it.Close()
+	return &reveal, nil
+}
 
+func (storage *Storage) Reveals(nebulaId account.NebulaId, height int64, pulseId int64) ([]RevealValue, error) {
 	prefix := formKey(string(RevealKey), hexutil.Encode(nebulaId[:]), fmt.Sprintf("%d", height), fmt.Sprintf("%d", pulseId))
-	var values []string
-	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-		item := it.Item()
-		item.Value(func(v []byte) error {
-			values = append(values, base64.StdEncoding.EncodeToString(v))
-			return nil
-		})
+	var values []RevealValue
+	err := storage.iterate(prefix, func(k []byte, v []byte) error {
+		var reveal RevealValue
+		err := json.Unmarshal(v, &reveal)
+		if err != nil {
+			return err
+		}
+		values = append(values, reveal)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// OracleReveals returns the reveals of the pulse by the oracles that sent
+// them.
+func (storage *Storage) OracleReveals(nebulaId account.NebulaId, height int64, pulseId int64) (map[account.OraclesPubKey]RevealValue, error) {
+	prefix := formKey(string(RevealKey), hexutil.Encode(nebulaId[:]), fmt.Sprintf("%d", height), fmt.Sprintf("%d", pulseId), "")
+	values := make(map[account.OraclesPubKey]RevealValue)
+	err := storage.iterate(prefix, func(k []byte, v []byte) error {
+		parts := strings.Split(string(k), Separator)
+		key, err := hexutil.Decode(parts[len(parts)-1])
+		if err != nil {
+			return err
+		}
+		var oraclePubKey account.OraclesPubKey
+		copy(oraclePubKey[:], key)
+
+		var reveal RevealValue
+		err = json.Unmarshal(v, &reveal)
+		if err != nil {
+			return err
+		}
+		values[oraclePubKey] = reveal
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return values, nil
 }
 
-func (storage *Storage) SetReveal(nebulaId account.NebulaId, height int64, pulseId int64, commitHash []byte, oraclePubKey account.OraclesPubKey, reveal []byte) error {
+func (storage *Storage) SetReveal(nebulaId account.NebulaId, height int64, pulseId int64, commitHash []byte, oraclePubKey account.OraclesPubKey, reveal RevealValue) error {
 	return storage.setValue(formRevealKey(nebulaId, height, pulseId, commitHash, oraclePubKey), reveal)
 }
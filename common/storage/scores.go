@@ -4,8 +4,6 @@ import (
 	"encoding/binary"
 	"strings"
 
-	"github.com/dgraph-io/badger"
-
 	"github.com/Gravity-Tech/gravity-core/common/account"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 )
@@ -46,22 +44,18 @@ func (storage *Storage) SetScore(pubKey account.ConsulPubKey, score uint64) erro
 }
 
 func (storage *Storage) Scores() (ScoresByConsulMap, error) {
-	it := storage.txn.NewIterator(badger.DefaultIteratorOptions)
-	defer it.Close()
-
 	prefix := []byte(ScoreKey)
 	scores := make(ScoresByConsulMap)
-	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-		item := it.Item()
-		k := item.Key()
-		item.Value(func(v []byte) error {
-			pubKey, err := parseScoreKey(k)
-			if err != nil {
-				return err
-			}
-			scores[pubKey] = binary.BigEndian.Uint64(v)
+	err := storage.iterate(prefix, func(k []byte, v []byte) error {
+		pubKey, err := parseScoreKey(k)
+		if err != nil {
 			return nil
-		})
+		}
+		scores[pubKey] = binary.BigEndian.Uint64(v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return scores, nil
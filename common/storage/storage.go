@@ -12,6 +12,7 @@ const (
 
 	ConsulsKey                   Key = "consuls"
 	ConsulsCandidateKey          Key = "consuls_candidate"
+	RoundConsulsKey              Key = "round_consuls"
 	LastHeightKey                Key = "last_height"
 	LastRoundApproved            Key = "last_round_approved"
 	ConsulsCountKey              Key = "consuls_count"
@@ -29,7 +30,13 @@ const (
 	CommitKey     Key = "commit"
 	RevealKey     Key = "reveal"
 	SignResultKey Key = "signResult"
+	ResultHashKey Key = "result_hash"
+	PulseHashKey  Key = "pulse_hash"
 	NebulaInfoKey Key = "nebula_info"
+	NonceKey      Key = "nonce"
+
+	RoundAttestationKey        Key = "attestation_round"
+	ApproveRoundAttestationKey Key = "attestation_approve"
 )
 
 var (
@@ -38,7 +45,7 @@ var (
 
 type Key string
 type Storage struct {
-	txn *badger.Txn
+	store kvStore
 }
 
 func formKey(args ...string) []byte {
@@ -50,15 +57,9 @@ func New() *Storage {
 }
 
 func (storage *Storage) getValue(key []byte) ([]byte, error) {
-	item, err := storage.txn.Get(key)
-	if err != nil {
-		return nil, err
-	}
-
-	b, err := item.ValueCopy(nil)
-	if err != nil {
-		return nil, err
-
+	b := storage.store.get(key)
+	if b == nil {
+		return nil, ErrKeyNotFound
 	}
 
 	return b, nil
@@ -76,13 +77,62 @@ func (storage *Storage) setValue(key []byte, jsonOrBytes interface{}) error {
 		}
 	}
 
-	return storage.txn.Set(key, b)
+	if b == nil {
+		b = []byte{}
+	}
+
+	storage.store.set(key, b)
+	return nil
+}
+
+func (storage *Storage) iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	return storage.store.iterate(prefix, fn)
+}
+
+// NewTransaction binds the storage to the working version of the tree.
+// Writes become part of the state at the next DB.Commit.
+func (storage *Storage) NewTransaction(db *DB) {
+	storage.store = &treeStore{db: db}
+}
+
+// NewCacheTransaction binds the storage to the last committed version of the
+// tree. Writes are kept in memory and discarded with the storage.
+func (storage *Storage) NewCacheTransaction(db *DB) error {
+	return storage.NewCacheTransactionAt(db, db.Version())
+}
+
+// NewCacheTransactionAt binds the storage to the given committed version, as
+// long as it has not been pruned.
+func (storage *Storage) NewCacheTransactionAt(db *DB, version int64) error {
+	tree, err := db.immutable(version)
+	if err != nil {
+		return err
+	}
+
+	storage.store = newCacheStore(tree)
+	return nil
 }
 
-func (storage *Storage) NewTransaction(db *badger.DB) {
-	storage.txn = db.NewTransaction(true)
+// Branch returns a storage that reads through to this one and keeps its
+// writes until Write. Dropping the branch discards them.
+func (storage *Storage) Branch() *Storage {
+	return &Storage{
+		store: &branchStore{
+			parent: storage.store,
+			writes: make(map[string][]byte),
+		},
+	}
 }
 
-func (storage *Storage) Commit() error {
-	return storage.txn.Commit()
+// Write applies the writes of a branch to the storage it was made from.
+func (storage *Storage) Write() {
+	store, ok := storage.store.(*branchStore)
+	if !ok {
+		return
+	}
+
+	for _, k := range sortedKeys(store.writes) {
+		store.parent.set([]byte(k), store.writes[k])
+	}
+	store.writes = make(map[string][]byte)
 }
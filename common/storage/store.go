@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/cosmos/iavl"
+)
+
+type kvStore interface {
+	get(key []byte) []byte
+	set(key []byte, value []byte)
+	iterate(prefix []byte, fn func(key []byte, value []byte) error) error
+}
+
+// treeStore reads through to the working tree and buffers its writes in the
+// DB until commit, so the tree shape does not depend on write order.
+type treeStore struct {
+	db *DB
+}
+
+func (store *treeStore) get(key []byte) []byte {
+	if value, ok := store.db.pending[string(key)]; ok {
+		return value
+	}
+
+	_, value := store.db.tree.Get(key)
+	return value
+}
+
+func (store *treeStore) set(key []byte, value []byte) {
+	store.db.pending[string(key)] = value
+}
+
+func (store *treeStore) iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	return iterateMerged(store.db.tree.ImmutableTree, store.db.pending, prefix, fn)
+}
+
+// cacheStore reads through to a committed tree version and keeps its writes
+// in memory, so they never reach the Merkle state.
+type cacheStore struct {
+	tree   *iavl.ImmutableTree
+	writes map[string][]byte
+
+	reads    [][]byte
+	prefixes [][]byte
+}
+
+func newCacheStore(tree *iavl.ImmutableTree) *cacheStore {
+	return &cacheStore{
+		tree:   tree,
+		writes: make(map[string][]byte),
+	}
+}
+
+func (store *cacheStore) get(key []byte) []byte {
+	store.trackRead(key)
+	if value, ok := store.writes[string(key)]; ok {
+		return value
+	}
+
+	_, value := store.tree.Get(key)
+	return value
+}
+
+func (store *cacheStore) set(key []byte, value []byte) {
+	store.writes[string(key)] = value
+}
+
+func (store *cacheStore) iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	store.trackIterate(prefix)
+	return iterateMerged(store.tree, store.writes, prefix, fn)
+}
+
+func (store *cacheStore) trackRead(key []byte) {
+	for _, k := range store.reads {
+		if bytes.Equal(k, key) {
+			return
+		}
+	}
+
+	store.reads = append(store.reads, append([]byte{}, key...))
+}
+
+func (store *cacheStore) trackIterate(prefix []byte) {
+	for _, p := range store.prefixes {
+		if bytes.Equal(p, prefix) {
+			return
+		}
+	}
+
+	store.prefixes = append(store.prefixes, append([]byte{}, prefix...))
+}
+
+func iterateMerged(tree *iavl.ImmutableTree, writes map[string][]byte, prefix []byte, fn func(key []byte, value []byte) error) error {
+	values := make(map[string][]byte)
+	err := iterateTree(tree, prefix, func(key []byte, value []byte) error {
+		values[string(key)] = value
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for k, v := range writes {
+		if bytes.HasPrefix([]byte(k), prefix) {
+			values[k] = v
+		}
+	}
+
+	for _, k := range sortedKeys(values) {
+		err := fn([]byte(k), values[k])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys(values map[string][]byte) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func iterateTree(tree *iavl.ImmutableTree, prefix []byte, fn func(key []byte, value []byte) error) error {
+	var err error
+	tree.IterateRange(prefix, prefixEnd(prefix), true, func(key []byte, value []byte) bool {
+		err = fn(key, value)
+		return err != nil
+	})
+
+	return err
+}
+
+func prefixEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+
+	return nil
+}
+
+// branchStore buffers its writes over a parent store until they are written
+// to it, so a failed transaction leaves the parent untouched.
+type branchStore struct {
+	parent kvStore
+	writes map[string][]byte
+}
+
+func (store *branchStore) get(key []byte) []byte {
+	if value, ok := store.writes[string(key)]; ok {
+		return value
+	}
+
+	return store.parent.get(key)
+}
+
+func (store *branchStore) set(key []byte, value []byte) {
+	store.writes[string(key)] = value
+}
+
+func (store *branchStore) iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	values := make(map[string][]byte)
+	err := store.parent.iterate(prefix, func(key []byte, value []byte) error {
+		values[string(key)] = value
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for k, v := range store.writes {
+		if bytes.HasPrefix([]byte(k), prefix) {
+			values[k] = v
+		}
+	}
+
+	for _, k := range sortedKeys(values) {
+		err := fn([]byte(k), values[k])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"strings"
 
-	"github.com/dgraph-io/badger"
-
 	"github.com/ethereum/go-ethereum/common/hexutil"
 
 	"github.com/Gravity-Tech/gravity-core/common/account"
@@ -50,27 +48,23 @@ func (storage *Storage) SetVote(pubKey account.ConsulPubKey, votes []Vote) error
 }
 
 func (storage *Storage) Votes() (VoteByConsulMap, error) {
-	it := storage.txn.NewIterator(badger.DefaultIteratorOptions)
-	defer it.Close()
-
 	prefix := []byte(VoteKey)
 	votes := make(VoteByConsulMap)
-	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-		item := it.Item()
-		k := item.Key()
-		item.Value(func(v []byte) error {
-			var vote []Vote
-			err := json.Unmarshal(v, &vote)
-			if err != nil {
-				return err
-			}
-			pubKey, err := parseVoteKey(k)
-			if err != nil {
-				return err
-			}
-			votes[pubKey] = vote
+	err := storage.iterate(prefix, func(k []byte, v []byte) error {
+		var vote []Vote
+		err := json.Unmarshal(v, &vote)
+		if err != nil {
+			return nil
+		}
+		pubKey, err := parseVoteKey(k)
+		if err != nil {
 			return nil
-		})
+		}
+		votes[pubKey] = vote
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return votes, nil
@@ -0,0 +1,512 @@
+package transactions
+
+import (
+	"errors"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	HashLength = 32
+	SaltLength = 32
+)
+
+var (
+	ErrMissingField = errors.New("missing field")
+)
+
+// Payload is the typed body of a transaction. Every TxFunc has exactly one
+// payload type, see transactions.proto for the wire schema.
+type Payload interface {
+	Func() TxFunc
+
+	encode(enc *encoder)
+	decode(b []byte) error
+}
+
+type CommitArgs struct {
+	NebulaId     account.NebulaId
+	PulseId      int64
+	Height       int64
+	Commit       []byte
+	OraclePubKey account.OraclesPubKey
+}
+
+type RevealArgs struct {
+	Commit       []byte
+	NebulaId     account.NebulaId
+	PulseId      int64
+	Height       int64
+	Reveal       []byte
+	OraclePubKey account.OraclesPubKey
+	Salt         []byte
+}
+
+// CommitHash is the commit of a pulse value: keccak256(salt || value).
+func CommitHash(salt []byte, value []byte) []byte {
+	return crypto.Keccak256(salt, value)
+}
+
+type ResultArgs struct {
+	NebulaId  account.NebulaId
+	PulseId   int64
+	Sign      []byte
+	ChainType account.ChainType
+	Hash      []byte
+}
+
+type AddOracleArgs struct {
+	ChainType    account.ChainType
+	OraclePubKey account.OraclesPubKey
+}
+
+type AddOracleInNebulaArgs struct {
+	NebulaId     account.NebulaId
+	OraclePubKey account.OraclesPubKey
+}
+
+type NewRoundArgs struct {
+	ChainType    account.ChainType
+	LedgerHeight uint64
+	TcHeight     uint64
+}
+
+type VoteArgs struct {
+	Votes []storage.Vote
+}
+
+type SetNebulaArgs struct {
+	NebulaId account.NebulaId
+	Info     storage.NebulaInfo
+}
+
+type SignNewConsulsArgs struct {
+	ChainType account.ChainType
+	RoundId   int64
+	Sign      []byte
+}
+
+type SignNewOraclesArgs struct {
+	RoundId  int64
+	Sign     []byte
+	NebulaId account.NebulaId
+}
+
+type ApproveLastRoundArgs struct {
+	RoundId uint64
+}
+
+func newPayload(txFunc TxFunc) (Payload, error) {
+	switch txFunc {
+	case Commit:
+		return &CommitArgs{}, nil
+	case Reveal:
+		return &RevealArgs{}, nil
+	case Result:
+		return &ResultArgs{}, nil
+	case AddOracle:
+		return &AddOracleArgs{}, nil
+	case AddOracleInNebula:
+		return &AddOracleInNebulaArgs{}, nil
+	case NewRound:
+		return &NewRoundArgs{}, nil
+	case Vote:
+		return &VoteArgs{}, nil
+	case SetNebula:
+		return &SetNebulaArgs{}, nil
+	case SignNewConsuls:
+		return &SignNewConsulsArgs{}, nil
+	case SignNewOracles:
+		return &SignNewOraclesArgs{}, nil
+	case ApproveLastRound:
+		return &ApproveLastRoundArgs{}, nil
+	default:
+		return nil, ErrUnknownFunc
+	}
+}
+
+func (args *CommitArgs) Func() TxFunc { return Commit }
+
+func (args *CommitArgs) encode(enc *encoder) {
+	enc.bytes(1, args.NebulaId[:])
+	enc.int(2, args.PulseId)
+	enc.int(3, args.Height)
+	enc.bytes(4, args.Commit)
+	enc.bytes(5, args.OraclePubKey[:])
+}
+
+func (args *CommitArgs) decode(b []byte) error {
+	err := decodeMessage(b, func(num protowire.Number, f field) (err error) {
+		switch num {
+		case 1:
+			err = f.fixedBytes(args.NebulaId[:])
+		case 2:
+			args.PulseId, err = f.int()
+		case 3:
+			args.Height, err = f.int()
+		case 4:
+			args.Commit, err = f.bytes()
+		case 5:
+			err = f.fixedBytes(args.OraclePubKey[:])
+		default:
+			err = ErrUnknownField
+		}
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(args.Commit) != HashLength {
+		return ErrInvalidFieldLength
+	}
+
+	return nil
+}
+
+func (args *RevealArgs) Func() TxFunc { return Reveal }
+
+func (args *RevealArgs) encode(enc *encoder) {
+	enc.bytes(1, args.Commit)
+	enc.bytes(2, args.NebulaId[:])
+	enc.int(3, args.PulseId)
+	enc.int(4, args.Height)
+	enc.bytes(5, args.Reveal)
+	enc.bytes(6, args.OraclePubKey[:])
+	enc.bytes(7, args.Salt)
+}
+
+func (args *RevealArgs) decode(b []byte) error {
+	err := decodeMessage(b, func(num protowire.Number, f field) (err error) {
+		switch num {
+		case 1:
+			args.Commit, err = f.bytes()
+		case 2:
+			err = f.fixedBytes(args.NebulaId[:])
+		case 3:
+			args.PulseId, err = f.int()
+		case 4:
+			args.Height, err = f.int()
+		case 5:
+			args.Reveal, err = f.bytes()
+		case 6:
+			err = f.fixedBytes(args.OraclePubKey[:])
+		case 7:
+			args.Salt, err = f.bytes()
+		default:
+			err = ErrUnknownField
+		}
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(args.Commit) != HashLength {
+		return ErrInvalidFieldLength
+	}
+	if len(args.Reveal) == 0 {
+		return ErrMissingField
+	}
+	if len(args.Salt) != SaltLength {
+		return ErrInvalidFieldLength
+	}
+
+	return nil
+}
+
+func (args *ResultArgs) Func() TxFunc { return Result }
+
+func (args *ResultArgs) encode(enc *encoder) {
+	enc.bytes(1, args.NebulaId[:])
+	enc.int(2, args.PulseId)
+	enc.bytes(3, args.Sign)
+	enc.uint(4, uint64(args.ChainType))
+	enc.bytes(5, args.Hash)
+}
+
+func (args *ResultArgs) decode(b []byte) error {
+	err := decodeMessage(b, func(num protowire.Number, f field) (err error) {
+		switch num {
+		case 1:
+			err = f.fixedBytes(args.NebulaId[:])
+		case 2:
+			args.PulseId, err = f.int()
+		case 3:
+			args.Sign, err = f.bytes()
+		case 4:
+			var chainType byte
+			chainType, err = f.byte()
+			args.ChainType = account.ChainType(chainType)
+		case 5:
+			args.Hash, err = f.bytes()
+		default:
+			err = ErrUnknownField
+		}
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(args.Sign) == 0 {
+		return ErrMissingField
+	}
+	if len(args.Hash) != HashLength {
+		return ErrInvalidFieldLength
+	}
+
+	return nil
+}
+
+func (args *AddOracleArgs) Func() TxFunc { return AddOracle }
+
+func (args *AddOracleArgs) encode(enc *encoder) {
+	enc.uint(1, uint64(args.ChainType))
+	enc.bytes(2, args.OraclePubKey[:])
+}
+
+func (args *AddOracleArgs) decode(b []byte) error {
+	return decodeMessage(b, func(num protowire.Number, f field) (err error) {
+		switch num {
+		case 1:
+			var chainType byte
+			chainType, err = f.byte()
+			args.ChainType = account.ChainType(chainType)
+		case 2:
+			err = f.fixedBytes(args.OraclePubKey[:])
+		default:
+			err = ErrUnknownField
+		}
+		return
+	})
+}
+
+func (args *AddOracleInNebulaArgs) Func() TxFunc { return AddOracleInNebula }
+
+func (args *AddOracleInNebulaArgs) encode(enc *encoder) {
+	enc.bytes(1, args.NebulaId[:])
+	enc.bytes(2, args.OraclePubKey[:])
+}
+
+func (args *AddOracleInNebulaArgs) decode(b []byte) error {
+	return decodeMessage(b, func(num protowire.Number, f field) (err error) {
+		switch num {
+		case 1:
+			err = f.fixedBytes(args.NebulaId[:])
+		case 2:
+			err = f.fixedBytes(args.OraclePubKey[:])
+		default:
+			err = ErrUnknownField
+		}
+		return
+	})
+}
+
+func (args *NewRoundArgs) Func() TxFunc { return NewRound }
+
+func (args *NewRoundArgs) encode(enc *encoder) {
+	enc.uint(1, uint64(args.ChainType))
+	enc.uint(2, args.LedgerHeight)
+	enc.uint(3, args.TcHeight)
+}
+
+func (args *NewRoundArgs) decode(b []byte) error {
+	return decodeMessage(b, func(num protowire.Number, f field) (err error) {
+		switch num {
+		case 1:
+			var chainType byte
+			chainType, err = f.byte()
+			args.ChainType = account.ChainType(chainType)
+		case 2:
+			args.LedgerHeight, err = f.uint()
+		case 3:
+			args.TcHeight, err = f.uint()
+		default:
+			err = ErrUnknownField
+		}
+		return
+	})
+}
+
+func (args *VoteArgs) Func() TxFunc { return Vote }
+
+func (args *VoteArgs) encode(enc *encoder) {
+	for _, v := range args.Votes {
+		vote := v
+		enc.message(1, func(enc *encoder) {
+			enc.bytes(1, vote.PubKey[:])
+			enc.uint(2, vote.Score)
+		})
+	}
+}
+
+func (args *VoteArgs) decode(b []byte) error {
+	return decodeMessage(b, func(num protowire.Number, f field) error {
+		if num != 1 {
+			return ErrUnknownField
+		}
+
+		msg, err := f.bytes()
+		if err != nil {
+			return err
+		}
+
+		var vote storage.Vote
+		err = decodeMessage(msg, func(num protowire.Number, f field) (err error) {
+			switch num {
+			case 1:
+				err = f.fixedBytes(vote.PubKey[:])
+			case 2:
+				vote.Score, err = f.uint()
+			default:
+				err = ErrUnknownField
+			}
+			return
+		})
+		if err != nil {
+			return err
+		}
+
+		args.Votes = append(args.Votes, vote)
+		return nil
+	}, 1)
+}
+
+func (args *SetNebulaArgs) Func() TxFunc { return SetNebula }
+
+func (args *SetNebulaArgs) encode(enc *encoder) {
+	enc.bytes(1, args.NebulaId[:])
+	enc.message(2, func(enc *encoder) {
+		enc.uint(1, args.Info.MaxPulseCountInBlock)
+		enc.uint(2, args.Info.MinScore)
+		enc.uint(3, uint64(args.Info.ChainType))
+		enc.bytes(4, args.Info.Owner[:])
+		enc.string(5, args.Info.Aggregator)
+	})
+}
+
+func (args *SetNebulaArgs) decode(b []byte) error {
+	return decodeMessage(b, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			return f.fixedBytes(args.NebulaId[:])
+		case 2:
+			msg, err := f.bytes()
+			if err != nil {
+				return err
+			}
+
+			return decodeMessage(msg, func(num protowire.Number, f field) (err error) {
+				switch num {
+				case 1:
+					args.Info.MaxPulseCountInBlock, err = f.uint()
+				case 2:
+					args.Info.MinScore, err = f.uint()
+				case 3:
+					var chainType byte
+					chainType, err = f.byte()
+					args.Info.ChainType = account.ChainType(chainType)
+				case 4:
+					err = f.fixedBytes(args.Info.Owner[:])
+				case 5:
+					args.Info.Aggregator, err = f.string()
+				default:
+					err = ErrUnknownField
+				}
+				return
+			})
+		default:
+			return ErrUnknownField
+		}
+	})
+}
+
+func (args *SignNewConsulsArgs) Func() TxFunc { return SignNewConsuls }
+
+func (args *SignNewConsulsArgs) encode(enc *encoder) {
+	enc.uint(1, uint64(args.ChainType))
+	enc.int(2, args.RoundId)
+	enc.bytes(3, args.Sign)
+}
+
+func (args *SignNewConsulsArgs) decode(b []byte) error {
+	err := decodeMessage(b, func(num protowire.Number, f field) (err error) {
+		switch num {
+		case 1:
+			var chainType byte
+			chainType, err = f.byte()
+			args.ChainType = account.ChainType(chainType)
+		case 2:
+			args.RoundId, err = f.int()
+		case 3:
+			args.Sign, err = f.bytes()
+		default:
+			err = ErrUnknownField
+		}
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(args.Sign) == 0 {
+		return ErrMissingField
+	}
+
+	return nil
+}
+
+func (args *SignNewOraclesArgs) Func() TxFunc { return SignNewOracles }
+
+func (args *SignNewOraclesArgs) encode(enc *encoder) {
+	enc.int(1, args.RoundId)
+	enc.bytes(2, args.Sign)
+	enc.bytes(3, args.NebulaId[:])
+}
+
+func (args *SignNewOraclesArgs) decode(b []byte) error {
+	err := decodeMessage(b, func(num protowire.Number, f field) (err error) {
+		switch num {
+		case 1:
+			args.RoundId, err = f.int()
+		case 2:
+			args.Sign, err = f.bytes()
+		case 3:
+			err = f.fixedBytes(args.NebulaId[:])
+		default:
+			err = ErrUnknownField
+		}
+		return
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(args.Sign) == 0 {
+		return ErrMissingField
+	}
+
+	return nil
+}
+
+func (args *ApproveLastRoundArgs) Func() TxFunc { return ApproveLastRound }
+
+func (args *ApproveLastRoundArgs) encode(enc *encoder) {
+	enc.uint(1, args.RoundId)
+}
+
+func (args *ApproveLastRoundArgs) decode(b []byte) error {
+	return decodeMessage(b, func(num protowire.Number, f field) (err error) {
+		switch num {
+		case 1:
+			args.RoundId, err = f.uint()
+		default:
+			err = ErrUnknownField
+		}
+		return
+	})
+}
@@ -1,14 +1,14 @@
 package transactions
 
 import (
-	"encoding/binary"
-	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/Gravity-Tech/gravity-core/common/account"
 	"github.com/ethereum/go-ethereum/crypto"
 	tCrypto "github.com/tendermint/tendermint/crypto"
-	_ "github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"google.golang.org/protobuf/encoding/protowire"
 )
 
 const (
@@ -24,138 +24,155 @@ const (
 	SignNewOracles    TxFunc = "signNewOracles"
 	ApproveLastRound  TxFunc = "approveLastRound"
 
-	String Type = "string"
-	Int    Type = "int"
-	Bytes  Type = "bytes"
+	Version byte = 1
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported transaction version")
+	ErrUnknownFunc        = errors.New("unknown transaction function")
 )
 
 type ID [32]byte
 type TxFunc string
-type Type string
-type Arg struct {
-	Type  Type
-	Value []byte
-}
-type Value interface{}
-type StringValue struct {
-	Value string
-}
-type IntValue struct {
-	Value int64
-}
-type BytesValue struct {
-	Value []byte
-}
 
 type Transaction struct {
 	Id           ID
 	SenderPubKey account.ConsulPubKey
-	Signature    [72]byte
+	Signature    [ed25519.SignatureSize]byte
 	Func         TxFunc
 	Timestamp    uint64
-	Args         []Arg
+	Nonce        uint64
+	Payload      Payload
 }
 
-func New(pubKey account.ConsulPubKey, funcName TxFunc, privKey tCrypto.PrivKey) (*Transaction, error) {
-	tx := &Transaction{
+// New returns an unsigned transaction. The nonce has to be set before it is
+// signed, gravity.Client.SendTx does both.
+func New(pubKey account.ConsulPubKey, payload Payload) *Transaction {
+	return &Transaction{
 		SenderPubKey: pubKey,
-		Func:         funcName,
+		Func:         payload.Func(),
 		Timestamp:    uint64(time.Now().Unix()),
+		Payload:      payload,
 	}
-	tx.Hash()
-
-	err := tx.Sign(privKey)
-	if err != nil {
-		return tx, err
-	}
-
-	return tx, nil
 }
 
-func (tx *Transaction) Hash() {
-	tx.Id = ID(crypto.Keccak256Hash(tx.Bytes()))
+// Hash computes the transaction id on the given ledger chain.
+func (tx *Transaction) Hash(chainId string) ID {
+	return ID(crypto.Keccak256Hash(tx.Bytes(chainId)))
 }
 
-func (tx *Transaction) Sign(privKey tCrypto.PrivKey) error {
+func (tx *Transaction) Sign(chainId string, privKey tCrypto.PrivKey) error {
+	tx.Id = tx.Hash(chainId)
 	sign, err := account.Sign(privKey, tx.Id.Bytes())
 	if err != nil {
 		return err
 	}
+	if len(sign) != len(tx.Signature) {
+		return ErrInvalidFieldLength
+	}
 
 	copy(tx.Signature[:], sign)
 	return nil
 }
 
-func (tx *Transaction) Bytes() []byte {
-	var result []byte
-	result = append(result, tx.Id[:]...)
-	result = append(result, tx.SenderPubKey[:]...)
-	result = append(result, tx.Func...)
-
-	for _, v := range tx.Args {
-		result = append(result, v.Value...)
-	}
-
-	var b [8]byte
-	binary.BigEndian.PutUint64(b[:], tx.Timestamp)
-	result = append(result, b[:]...)
+// Bytes returns the canonical bytes covered by the id: everything except the
+// id and the signature, bound to the chain id of the ledger.
+func (tx *Transaction) Bytes(chainId string) []byte {
+	enc := &encoder{}
+	enc.string(1, chainId)
+	enc.bytes(2, tx.SenderPubKey[:])
+	enc.string(3, string(tx.Func))
+	enc.uint(4, tx.Timestamp)
+	enc.uint(5, tx.Nonce)
+	enc.b = protowire.AppendTag(enc.b, 6, protowire.BytesType)
+	enc.b = protowire.AppendBytes(enc.b, tx.payloadBytes())
+
+	return enc.b
+}
 
-	return result
+// Marshal encodes the transaction as a version byte followed by the
+// Transaction message of transactions.proto.
+func (tx *Transaction) Marshal() []byte {
+	enc := &encoder{b: []byte{Version}}
+	enc.bytes(1, tx.Id[:])
+	enc.bytes(2, tx.SenderPubKey[:])
+	enc.bytes(3, tx.Signature[:])
+	enc.string(4, string(tx.Func))
+	enc.uint(5, tx.Timestamp)
+	enc.b = protowire.AppendTag(enc.b, 6, protowire.BytesType)
+	enc.b = protowire.AppendBytes(enc.b, tx.payloadBytes())
+	enc.uint(7, tx.Nonce)
+
+	return enc.b
 }
 
-func UnmarshalJson(data []byte) (*Transaction, error) {
+// Unmarshal decodes a transaction written by Marshal. Unknown versions,
+// functions and fields as well as fields of the wrong type or length are
+// rejected.
+func Unmarshal(data []byte) (*Transaction, error) {
+	if len(data) == 0 {
+		return nil, ErrMalformedTx
+	}
+	if data[0] != Version {
+		return nil, ErrUnsupportedVersion
+	}
+
 	tx := new(Transaction)
-	err := json.Unmarshal(data, tx)
+	var payload []byte
+	hasPayload := false
+	err := decodeMessage(data[1:], func(num protowire.Number, f field) (err error) {
+		switch num {
+		case 1:
+			err = f.fixedBytes(tx.Id[:])
+		case 2:
+			err = f.fixedBytes(tx.SenderPubKey[:])
+		case 3:
+			err = f.fixedBytes(tx.Signature[:])
+		case 4:
+			var txFunc string
+			txFunc, err = f.string()
+			tx.Func = TxFunc(txFunc)
+		case 5:
+			tx.Timestamp, err = f.uint()
+		case 6:
+			payload, err = f.bytes()
+			hasPayload = true
+		case 7:
+			tx.Nonce, err = f.uint()
+		default:
+			err = ErrUnknownField
+		}
+		return
+	})
 	if err != nil {
 		return nil, err
 	}
+	if !hasPayload {
+		return nil, ErrMissingField
+	}
 
-	return tx, nil
-}
+	tx.Payload, err = newPayload(tx.Func)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Payload.decode(payload)
+	if err != nil {
+		return nil, err
+	}
 
-func (id ID) Bytes() []byte {
-	return id[:]
+	return tx, nil
 }
 
-func (tx *Transaction) AddValue(value Value) {
-	var b []byte
-	var t Type
-	switch value.(type) {
-	case StringValue:
-		b = []byte(value.(StringValue).Value)
-		t = String
-	case IntValue:
-		var bInt [8]byte
-		binary.BigEndian.PutUint64(bInt[:], uint64(value.(IntValue).Value))
-		b = bInt[:]
-		t = Int
-	case BytesValue:
-		b = value.(BytesValue).Value
-		t = Bytes
+func (tx *Transaction) payloadBytes() []byte {
+	if tx.Payload == nil {
+		return nil
 	}
-	tx.Args = append(tx.Args, Arg{
-		Type:  t,
-		Value: b,
-	})
-}
 
-func (tx *Transaction) AddValues(values []Value) {
-	for _, value := range values {
-		tx.AddValue(value)
-	}
+	enc := &encoder{}
+	tx.Payload.encode(enc)
+	return enc.b
 }
 
-func (tx *Transaction) Value(index int) interface{} {
-	v := tx.Args[index]
-
-	switch v.Type {
-	case String:
-		return string(v.Value)
-	case Int:
-		return int64(binary.BigEndian.Uint64(v.Value))
-	case Bytes:
-		return v.Value
-	}
-
-	return nil
+func (id ID) Bytes() []byte {
+	return id[:]
 }
@@ -0,0 +1,114 @@
+package transactions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func testPayloads() []Payload {
+	nebulaId := account.NebulaId{1, 2, 3}
+	oracle := account.OraclesPubKey{4, 5, 6}
+	hash := make([]byte, HashLength)
+	hash[0] = 7
+
+	return []Payload{
+		&CommitArgs{NebulaId: nebulaId, PulseId: 1, Height: 2, Commit: hash, OraclePubKey: oracle},
+		&RevealArgs{Commit: hash, NebulaId: nebulaId, PulseId: 1, Height: 2, Reveal: []byte{8}, OraclePubKey: oracle, Salt: hash},
+		&ResultArgs{NebulaId: nebulaId, PulseId: 1, Sign: []byte{9}, ChainType: account.Waves, Hash: hash},
+		&AddOracleArgs{ChainType: account.ChainType(2), OraclePubKey: oracle},
+		&AddOracleInNebulaArgs{NebulaId: nebulaId, OraclePubKey: oracle},
+		&NewRoundArgs{ChainType: account.Waves, LedgerHeight: 10, TcHeight: 100},
+		&VoteArgs{Votes: []storage.Vote{{PubKey: account.ConsulPubKey{1}, Score: 50}, {PubKey: account.ConsulPubKey{2}}}},
+		&SetNebulaArgs{NebulaId: nebulaId, Info: storage.NebulaInfo{MaxPulseCountInBlock: 1, MinScore: 2, ChainType: account.Waves, Owner: account.ConsulPubKey{3}}},
+		&SignNewConsulsArgs{ChainType: account.Waves, RoundId: 3, Sign: []byte{10}},
+		&SignNewOraclesArgs{RoundId: 3, Sign: []byte{11}, NebulaId: nebulaId},
+		&ApproveLastRoundArgs{RoundId: 4},
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	privKey := ed25519.GenPrivKey()
+	var pubKey account.ConsulPubKey
+	copy(pubKey[:], privKey.PubKey().Bytes())
+
+	for _, payload := range testPayloads() {
+		tx := New(pubKey, payload)
+		tx.Nonce = 3
+		if err := tx.Sign("gravity-test", privKey); err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := Unmarshal(tx.Marshal())
+		if err != nil {
+			t.Fatalf("%s: %s", payload.Func(), err)
+		}
+		if !reflect.DeepEqual(tx, decoded) {
+			t.Errorf("%s: decoded transaction differs", payload.Func())
+		}
+	}
+}
+
+func TestUnmarshalMalformed(t *testing.T) {
+	tx := &Transaction{Func: Commit, Payload: testPayloads()[0]}
+	valid := tx.Marshal()
+
+	withField := func(b []byte, num protowire.Number, value []byte) []byte {
+		b = append([]byte{}, b...)
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, value)
+	}
+	withPayload := func(txFunc TxFunc, payload []byte) []byte {
+		enc := &encoder{b: []byte{Version}}
+		enc.string(4, string(txFunc))
+		enc.b = protowire.AppendTag(enc.b, 6, protowire.BytesType)
+		enc.b = protowire.AppendBytes(enc.b, payload)
+		return enc.b
+	}
+
+	cases := map[string]struct {
+		data []byte
+		err  error
+	}{
+		"empty":           {nil, ErrMalformedTx},
+		"version":         {append([]byte{Version + 1}, valid[1:]...), ErrUnsupportedVersion},
+		"truncated":       {valid[:len(valid)-1], ErrMalformedTx},
+		"unknown field":   {withField(valid, 15, []byte{1}), ErrUnknownField},
+		"duplicate field": {withField(valid, 1, make([]byte, 32)), ErrDuplicateField},
+		"id length":       {withField([]byte{Version}, 1, []byte{1}), ErrInvalidFieldLength},
+		"unknown func":    {withPayload("transfer", nil), ErrUnknownFunc},
+		"no payload":      {[]byte{Version}, ErrMissingField},
+		"payload type":    {withPayload(ApproveLastRound, withField(nil, 1, []byte{1})), ErrInvalidFieldType},
+		"commit length":   {withPayload(Commit, withField(nil, 4, []byte{1})), ErrInvalidFieldLength},
+		"missing sign":    {withPayload(Result, nil), ErrMissingField},
+		"salt length":     {withPayload(Reveal, withField(withField(nil, 1, make([]byte, HashLength)), 5, []byte{1})), ErrInvalidFieldLength},
+		"result hash":     {withPayload(Result, withField(nil, 3, []byte{1})), ErrInvalidFieldLength},
+		"chain type":      {withPayload(NewRound, protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 256)), ErrInvalidFieldLength},
+		"vote field":      {withPayload(Vote, withField(nil, 1, withField(nil, 3, nil))), ErrUnknownField},
+	}
+
+	for name, c := range cases {
+		_, err := Unmarshal(c.data)
+		if err != c.err {
+			t.Errorf("%s: expected %v, got %v", name, c.err, err)
+		}
+	}
+}
+
+func TestHashBinding(t *testing.T) {
+	tx := New(account.ConsulPubKey{1}, &ApproveLastRoundArgs{RoundId: 1})
+	id := tx.Hash("gravity-test")
+
+	if tx.Hash("gravity-other") == id {
+		t.Error("id does not depend on the chain id")
+	}
+
+	tx.Nonce++
+	if tx.Hash("gravity-test") == id {
+		t.Error("id does not depend on the nonce")
+	}
+}
@@ -0,0 +1,158 @@
+package transactions
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var (
+	ErrMalformedTx        = errors.New("malformed transaction")
+	ErrUnknownField       = errors.New("unknown field")
+	ErrDuplicateField     = errors.New("duplicate field")
+	ErrInvalidFieldType   = errors.New("invalid field type")
+	ErrInvalidFieldLength = errors.New("invalid field length")
+)
+
+// encoder appends protobuf fields. Zero values are skipped as in proto3.
+type encoder struct {
+	b []byte
+}
+
+func (enc *encoder) bytes(num protowire.Number, v []byte) {
+	if len(v) == 0 {
+		return
+	}
+	enc.b = protowire.AppendTag(enc.b, num, protowire.BytesType)
+	enc.b = protowire.AppendBytes(enc.b, v)
+}
+
+func (enc *encoder) string(num protowire.Number, v string) {
+	enc.bytes(num, []byte(v))
+}
+
+func (enc *encoder) uint(num protowire.Number, v uint64) {
+	if v == 0 {
+		return
+	}
+	enc.b = protowire.AppendTag(enc.b, num, protowire.VarintType)
+	enc.b = protowire.AppendVarint(enc.b, v)
+}
+
+func (enc *encoder) int(num protowire.Number, v int64) {
+	enc.uint(num, uint64(v))
+}
+
+func (enc *encoder) message(num protowire.Number, fn func(enc *encoder)) {
+	msg := &encoder{}
+	fn(msg)
+	enc.b = protowire.AppendTag(enc.b, num, protowire.BytesType)
+	enc.b = protowire.AppendBytes(enc.b, msg.b)
+}
+
+// field is a single decoded protobuf field.
+type field struct {
+	typ   protowire.Type
+	value []byte
+	u     uint64
+}
+
+func (f field) bytes() ([]byte, error) {
+	if f.typ != protowire.BytesType {
+		return nil, ErrInvalidFieldType
+	}
+
+	return f.value, nil
+}
+
+func (f field) fixedBytes(v []byte) error {
+	b, err := f.bytes()
+	if err != nil {
+		return err
+	}
+	if len(b) != len(v) {
+		return ErrInvalidFieldLength
+	}
+	copy(v, b)
+
+	return nil
+}
+
+func (f field) string() (string, error) {
+	b, err := f.bytes()
+	return string(b), err
+}
+
+func (f field) uint() (uint64, error) {
+	if f.typ != protowire.VarintType {
+		return 0, ErrInvalidFieldType
+	}
+
+	return f.u, nil
+}
+
+func (f field) int() (int64, error) {
+	v, err := f.uint()
+	return int64(v), err
+}
+
+func (f field) byte() (byte, error) {
+	v, err := f.uint()
+	if err != nil {
+		return 0, err
+	}
+	if v > 0xff {
+		return 0, ErrInvalidFieldLength
+	}
+
+	return byte(v), nil
+}
+
+// decodeMessage calls fn for every field of b. Fields that are not listed in
+// repeated may occur only once and fn must reject unknown field numbers.
+func decodeMessage(b []byte, fn func(num protowire.Number, f field) error, repeated ...protowire.Number) error {
+	seen := make(map[protowire.Number]bool)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return ErrMalformedTx
+		}
+		b = b[n:]
+
+		f := field{typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.u, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.value, n = protowire.ConsumeBytes(b)
+		default:
+			return ErrInvalidFieldType
+		}
+		if n < 0 {
+			return ErrMalformedTx
+		}
+		b = b[n:]
+
+		if seen[num] && !isRepeated(num, repeated) {
+			return ErrDuplicateField
+		}
+		seen[num] = true
+
+		err := fn(num, f)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isRepeated(num protowire.Number, repeated []protowire.Number) bool {
+	for _, v := range repeated {
+		if v == num {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"github.com/Gravity-Tech/gravity-core/common/account"
+)
+
+// ChainTypeConfig describes a target chain that is not built in, for example
+// an EVM sidechain. Kind is "evm" or "waves".
+type ChainTypeConfig struct {
+	Name string
+	Type uint8
+	Kind string
+}
+
+// BinanceSmartChain is bsc with the chain type it had when it was built in,
+// for the genesis of networks that use it.
+var BinanceSmartChain = ChainTypeConfig{Name: "bsc", Type: 2, Kind: "evm"}
+
+func RegisterChainTypes(chainTypes []ChainTypeConfig) error {
+	for _, v := range chainTypes {
+		kind, err := account.ParseChainKind(v.Kind)
+		if err != nil {
+			return err
+		}
+
+		err = account.RegisterChainType(account.ChainType(v.Type), v.Name, kind)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
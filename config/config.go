@@ -38,8 +38,8 @@ func GeneratePrivKeys() (*Keys, error) {
 
 	return &Keys{
 		Validator: Key{
-			Address: hexutil.Encode(validatorPrivKey.PubKey().Bytes()[5:]),
-			PubKey:  hexutil.Encode(validatorPrivKey.PubKey().Bytes()[5:]),
+			Address: hexutil.Encode(validatorPrivKey.PubKey().Bytes()),
+			PubKey:  hexutil.Encode(validatorPrivKey.PubKey().Bytes()),
 			PrivKey: hexutil.Encode(validatorPrivKey[:]),
 		},
 		TargetChains: map[string]Key{
@@ -3,15 +3,16 @@ package config
 import (
 	"time"
 
-	"github.com/tendermint/tendermint/types"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
 )
 
 type Genesis struct {
 	ConsulsCount              int
 	GenesisTime               time.Time
 	ChainID                   string
-	Block                     types.BlockParams
-	Evidence                  types.EvidenceParams
+	Block                     tmproto.BlockParams
+	Evidence                  tmproto.EvidenceParams
 	InitScore                 map[string]uint64
 	OraclesAddressByValidator map[string]map[string]string
+	ChainTypes                []ChainTypeConfig `json:",omitempty"`
 }
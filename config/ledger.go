@@ -8,12 +8,17 @@ import (
 
 const (
 	DefaultMoniker = "robot"
+
+	DefaultSnapshotInterval   = 1000
+	DefaultSnapshotKeepRecent = 2
 )
 
 type AdaptorsConfig struct {
 	NodeUrl                string
 	ChainId                string
 	GravityContractAddress string
+	GasPrice               string `json:",omitempty"`
+	GasPriceMultiplier     int64  `json:",omitempty"`
 }
 
 type ValidatorDetails struct {
@@ -49,6 +54,15 @@ type LedgerConfig struct {
 	Details    *ValidatorDetails
 	PublicIP   string
 
+	StateRetention     int64
+	SnapshotInterval   int64
+	SnapshotKeepRecent int
+
+	// Instrumentation is the prometheus listener of tendermint, and
+	// MetricsListenAddress the one of the ledger application and scheduler.
+	Instrumentation      *cfg.InstrumentationConfig `json:",omitempty"`
+	MetricsListenAddress string                     `json:",omitempty"`
+
 	Adapters map[string]AdaptorsConfig
 }
 
@@ -60,6 +74,10 @@ func DefaultLedgerConfig() LedgerConfig {
 		RPC:        cfg.DefaultRPCConfig(),
 		P2P:        cfg.DefaultP2PConfig(),
 		Details:    (&ValidatorDetails{}).DefaultNew(),
+
+		SnapshotInterval:   DefaultSnapshotInterval,
+		SnapshotKeepRecent: DefaultSnapshotKeepRecent,
+
 		Adapters: map[string]AdaptorsConfig{
 			account.Ethereum.String(): {
 				NodeUrl:                "",
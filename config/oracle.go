@@ -1,5 +1,12 @@
 package config
 
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/tendermint/tendermint/light"
+)
+
 type OracleConfig struct {
 	TargetChainNodeUrl string
 	ChainId            string
@@ -7,4 +14,81 @@ type OracleConfig struct {
 	ChainType          string
 	ExtractorUrl       string
 	BlocksInterval     uint64
+	GasPrice           string             `json:",omitempty"`
+	GasPriceMultiplier int64              `json:",omitempty"`
+	ChainTypes         []ChainTypeConfig  `json:",omitempty"`
+	LightClient        *LightClientConfig `json:",omitempty"`
+	Extractor          *ExtractorConfig   `json:",omitempty"`
+	// Sources replace ExtractorUrl with several extractors, the oracle
+	// aggregates their values itself.
+	Sources *SourcesConfig `json:",omitempty"`
+	// MaxMissedPulses is the number of pulses missed in a row that fails
+	// the health check of the oracle, 3 when it is not set.
+	MaxMissedPulses uint64 `json:",omitempty"`
+}
+
+type SourcesConfig struct {
+	Extractors []ExtractorSourceConfig
+	// Quorum is the number of sources a value is aggregated from at least.
+	Quorum int `json:",omitempty"`
+	// MaxDeviation, MaxFailures and Quarantine decide when a source is
+	// quarantined and for how long, Quarantine is a duration like 10m.
+	MaxDeviation float64 `json:",omitempty"`
+	MaxFailures  int     `json:",omitempty"`
+	Quarantine   string  `json:",omitempty"`
+}
+
+// ExtractorSourceConfig is one of the extractors of the sources. Its weight
+// is 1 when it is not set, a source of weight 0 is only checked against the
+// others.
+type ExtractorSourceConfig struct {
+	Name   string
+	Url    string
+	Weight *uint64 `json:",omitempty"`
+	ExtractorConfig
+}
+
+// ExtractorConfig is the connection to the extractor of ExtractorUrl. An
+// http(s) url is served by the http extractor and a grpc:// or grpcs:// one
+// by the grpc extractor protocol, grpcs over TLS.
+type ExtractorConfig struct {
+	// Timeout is a duration like 10s that limits every call, 0 for no
+	// limit. The grpc client has a default one when it is empty.
+	Timeout string `json:",omitempty"`
+	// Token is sent as the bearer token of every call.
+	Token string `json:",omitempty"`
+	// CAFile verifies the grpcs extractor, CertFile and KeyFile are the
+	// certificate of the oracle for mutual TLS.
+	CAFile   string `json:",omitempty"`
+	CertFile string `json:",omitempty"`
+	KeyFile  string `json:",omitempty"`
+	// Subscribe takes the values the grpc extractor pushes instead of asking
+	// for them.
+	Subscribe bool `json:",omitempty"`
+}
+
+type LightClientConfig struct {
+	ChainId     string
+	TrustHeight int64
+	TrustHash   string
+	TrustPeriod string
+	Witnesses   []string
+}
+
+func (cfg *LightClientConfig) TrustOptions() (light.TrustOptions, error) {
+	hash, err := hexutil.Decode(cfg.TrustHash)
+	if err != nil {
+		return light.TrustOptions{}, err
+	}
+
+	period, err := time.ParseDuration(cfg.TrustPeriod)
+	if err != nil {
+		return light.TrustOptions{}, err
+	}
+
+	return light.TrustOptions{
+		Period: period,
+		Height: cfg.TrustHeight,
+		Hash:   hash,
+	}, nil
 }
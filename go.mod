@@ -5,12 +5,18 @@ go 1.14
 require (
 	github.com/btcsuite/btcd v0.21.0-beta // indirect
 	github.com/btcsuite/btcutil v1.0.2
+	github.com/cosmos/iavl v0.15.3
 	github.com/dgraph-io/badger v1.6.1
 	github.com/ethereum/go-ethereum v1.9.23
-	github.com/tendermint/tendermint v0.33.4
+	github.com/go-kit/kit v0.10.0
+	github.com/prometheus/client_golang v1.8.0
+	github.com/tendermint/go-amino v0.14.1 // indirect
+	github.com/tendermint/tendermint v0.34.0
+	github.com/tendermint/tm-db v0.6.3
 	github.com/urfave/cli/v2 v2.2.0
 	github.com/wavesplatform/go-lib-crypto v0.0.0-20190905125804-474f21517ad5
 	github.com/wavesplatform/gowaves v0.7.0
-	golang.org/x/crypto v0.0.0-20201016220609-9e8e0b390897 // indirect
 	golang.org/x/sys v0.0.0-20201020230747-6e5568b54d1a // indirect
+	google.golang.org/grpc v1.33.2
+	google.golang.org/protobuf v1.25.0
 )
@@ -2,13 +2,14 @@ package app
 
 import (
 	"bytes"
-	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/Gravity-Tech/gravity-core/config"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/tendermint/tendermint/version"
 	"sort"
-
-	"github.com/Gravity-Tech/gravity-core/common/adaptors"
+	"strconv"
 
 	"github.com/Gravity-Tech/gravity-core/ledger/query"
 
@@ -19,6 +20,7 @@ import (
 	"github.com/Gravity-Tech/gravity-core/common/storage"
 	"github.com/Gravity-Tech/gravity-core/common/transactions"
 	"github.com/Gravity-Tech/gravity-core/ledger/scheduler"
+	"github.com/Gravity-Tech/gravity-core/ledger/snapshot"
 
 	"github.com/dgraph-io/badger"
 	abcitypes "github.com/tendermint/tendermint/abci/types"
@@ -26,10 +28,19 @@ import (
 
 const (
 	Success      uint32 = 0
+	DecodeError  uint32 = 400
 	Error        uint32 = 500
 	NotFoundCode uint32 = 404
+	// NonceError rejects a transaction in CheckTx whose nonce is not the
+	// next one of the sender. The data of the response is the expected
+	// nonce, which counts the transactions of the sender in the mempool.
+	NonceError uint32 = 409
 
 	AppVersion uint64 = 1
+
+	// UnknownFunc is the function label of the transactions that can not be
+	// decoded.
+	UnknownFunc = "unknown"
 )
 
 type OraclesAddresses struct {
@@ -37,43 +48,77 @@ type OraclesAddresses struct {
 	account.OraclesPubKey
 }
 type Genesis struct {
+	ChainId                   string
 	ConsulsCount              int
 	OraclesAddressByValidator map[account.ConsulPubKey][]OraclesAddresses
 }
 
 type GHApplication struct {
-	IsSync    bool
-	db        *badger.DB
-	storage   *storage.Storage
-	adaptors  map[account.ChainType]adaptors.IBlockchainAdaptor
-	scheduler *scheduler.Scheduler
-	ctx       context.Context
-	genesis   *Genesis
+	abcitypes.BaseApplication
+
+	IsSync       bool
+	db           *badger.DB
+	stateDB      *storage.DB
+	storage      *storage.Storage
+	snapshots    *snapshot.Store
+	restore      *snapshot.Restore
+	scheduler    *scheduler.Scheduler
+	genesis      *Genesis
 	ledgerConfig *config.LedgerConfig
+
+	// checkNonces holds the next nonce of senders with transactions accepted
+	// by CheckTx since the last commit.
+	checkNonces map[account.ConsulPubKey]uint64
+	// snapshotting is held while a snapshot is created in the background.
+	snapshotting chan struct{}
+
+	metrics *Metrics
 }
 
 var _ abcitypes.Application = (*GHApplication)(nil)
 
-func NewGHApplication(adaptors map[account.ChainType]adaptors.IBlockchainAdaptor, scheduler *scheduler.Scheduler, db *badger.DB, genesis *Genesis, ctx context.Context, config *config.LedgerConfig) (*GHApplication, error) {
-	return &GHApplication{
-		db:        db,
-		adaptors:  adaptors,
-		scheduler: scheduler,
-		ctx:       ctx,
-		genesis:   genesis,
-		storage:   storage.New(),
+type Option func(*GHApplication)
+
+func WithMetrics(metrics *Metrics) Option {
+	return func(app *GHApplication) {
+		app.metrics = metrics
+	}
+}
+
+func NewGHApplication(scheduler *scheduler.Scheduler, db *badger.DB, snapshots *snapshot.Store, genesis *Genesis, config *config.LedgerConfig, opts ...Option) (*GHApplication, error) {
+	stateDB, err := storage.OpenDB(db)
+	if err != nil {
+		return nil, err
+	}
+
+	deliverStorage := storage.New()
+	deliverStorage.NewTransaction(stateDB)
+
+	app := &GHApplication{
+		db:           db,
+		stateDB:      stateDB,
+		snapshots:    snapshots,
+		scheduler:    scheduler,
+		genesis:      genesis,
+		storage:      deliverStorage,
 		ledgerConfig: config,
-	}, nil
+		checkNonces:  make(map[account.ConsulPubKey]uint64),
+		snapshotting: make(chan struct{}, 1),
+		metrics:      NopMetrics(),
+	}
+	for _, opt := range opts {
+		opt(app)
+	}
+
+	return app, nil
 }
 
 func (app *GHApplication) Info(req abcitypes.RequestInfo) abcitypes.ResponseInfo {
-	store := storage.New()
-	store.NewTransaction(app.db)
-	height, _ := store.LastHeight()
 	return abcitypes.ResponseInfo{
-		Version:         version.ABCIVersion,
-		AppVersion:      AppVersion,
-		LastBlockHeight: int64(height),
+		Version:          version.ABCIVersion,
+		AppVersion:       AppVersion,
+		LastBlockHeight:  app.stateDB.Version(),
+		LastBlockAppHash: app.stateDB.Hash(),
 	}
 }
 
@@ -81,65 +126,139 @@ func (app *GHApplication) SetOption(req abcitypes.RequestSetOption) abcitypes.Re
 	return abcitypes.ResponseSetOption{}
 }
 
-func (app *GHApplication) DeliverTx(req abcitypes.RequestDeliverTx) abcitypes.ResponseDeliverTx {
-	tx, err := transactions.UnmarshalJson(req.Tx)
+func (app *GHApplication) DeliverTx(req abcitypes.RequestDeliverTx) (rs abcitypes.ResponseDeliverTx) {
+	txFunc := UnknownFunc
+	defer func() {
+		app.metrics.DeliverTxs.With(FuncLabel, txFunc, CodeLabel, strconv.FormatUint(uint64(rs.Code), 10)).Add(1)
+	}()
+
+	tx, err := transactions.Unmarshal(req.Tx)
 	if err != nil {
-		return abcitypes.ResponseDeliverTx{Code: Error, Info: err.Error()}
+		return abcitypes.ResponseDeliverTx{Code: DecodeError, Info: err.Error()}
 	}
+	txFunc = string(tx.Func)
 
-	err = state.SetState(tx, app.storage, app.adaptors, app.ctx)
+	err = state.SetState(tx, app.storage, app.genesis.ChainId)
 	if err != nil {
 		return abcitypes.ResponseDeliverTx{Code: Error, Info: err.Error()}
 	}
 	return abcitypes.ResponseDeliverTx{Code: 0}
 }
 
-func (app *GHApplication) CheckTx(req abcitypes.RequestCheckTx) abcitypes.ResponseCheckTx {
-	tx, err := transactions.UnmarshalJson(req.Tx)
+func (app *GHApplication) CheckTx(req abcitypes.RequestCheckTx) (rs abcitypes.ResponseCheckTx) {
+	txFunc := UnknownFunc
+	defer func() {
+		app.metrics.CheckTxs.With(FuncLabel, txFunc, CodeLabel, strconv.FormatUint(uint64(rs.Code), 10)).Add(1)
+	}()
+
+	tx, err := transactions.Unmarshal(req.Tx)
 	if err != nil {
-		return abcitypes.ResponseCheckTx{Code: Error, Info: err.Error()}
+		return checkTxError(DecodeError, err)
 	}
+	txFunc = string(tx.Func)
 
 	store := storage.New()
-	store.NewTransaction(app.db)
-	err = state.SetState(tx, store, app.adaptors, app.ctx)
+	err = store.NewCacheTransaction(app.stateDB)
 	if err != nil {
-		return abcitypes.ResponseCheckTx{Code: Error, Info: err.Error()}
+		return checkTxError(Error, err)
+	}
+
+	if nonce, ok := app.checkNonces[tx.SenderPubKey]; ok {
+		err = store.SetNonce(tx.SenderPubKey, nonce)
+		if err != nil {
+			return checkTxError(Error, err)
+		}
 	}
 
+	err = state.SetState(tx, store, app.genesis.ChainId)
+	if errors.Is(err, state.ErrInvalidNonce) {
+		nonce, nonceErr := store.Nonce(tx.SenderPubKey)
+		if nonceErr != nil {
+			return checkTxError(Error, nonceErr)
+		}
+
+		rs = checkTxError(NonceError, err)
+		rs.Data = make([]byte, 8)
+		binary.BigEndian.PutUint64(rs.Data, nonce)
+		return rs
+	} else if err != nil {
+		return checkTxError(Error, err)
+	}
+	app.checkNonces[tx.SenderPubKey] = tx.Nonce + 1
+
 	return abcitypes.ResponseCheckTx{Code: Success}
 }
 
+// checkTxError rejects a transaction. The error is the log of the response
+// too, since the log is what BroadcastTxSync returns.
+func checkTxError(code uint32, err error) abcitypes.ResponseCheckTx {
+	return abcitypes.ResponseCheckTx{Code: code, Info: err.Error(), Log: err.Error()}
+}
+
 func (app *GHApplication) Commit() abcitypes.ResponseCommit {
-	err := app.storage.Commit()
+	hash, version, err := app.stateDB.Commit()
 	if err != nil {
 		panic(err)
 	}
-	return abcitypes.ResponseCommit{}
+	app.checkNonces = make(map[account.ConsulPubKey]uint64)
+
+	interval := app.ledgerConfig.SnapshotInterval
+	if app.snapshots != nil && interval > 0 && version%interval == 0 {
+		app.startSnapshot(version)
+	}
+
+	err = app.stateDB.Prune(app.ledgerConfig.StateRetention)
+	if err != nil {
+		panic(err)
+	}
+	return abcitypes.ResponseCommit{Data: hash}
 }
 
 func (app *GHApplication) Query(reqQuery abcitypes.RequestQuery) (resQuery abcitypes.ResponseQuery) {
 	var err error
 
+	height := reqQuery.Height
+	if height == 0 {
+		height = app.stateDB.Version()
+	}
+
 	store := storage.New()
-	store.NewTransaction(app.db)
+	err = store.NewCacheTransactionAt(app.stateDB, height)
+	if err != nil {
+		resQuery.Code = Error
+		resQuery.Info = err.Error()
+		return
+	}
 
+	resQuery.Height = height
 	b, err := query.Query(store, reqQuery.Path, reqQuery.Data, app.ledgerConfig.Details)
 
 	if err == query.ErrValueNotFound {
 		resQuery.Code = NotFoundCode
 	} else if err != nil {
 		resQuery.Code = Error
+		resQuery.Info = err.Error()
+		return
 	}
 
 	resQuery.Value = b
+	if !reqQuery.Prove {
+		return
+	}
+
+	proof, err := store.Proof()
+	if err != nil {
+		resQuery.Code = Error
+		resQuery.Info = err.Error()
+		resQuery.Value = nil
+		return
+	}
+	resQuery.ProofOps = proof
 
 	return
 }
 
 func (app *GHApplication) InitChain(req abcitypes.RequestInitChain) abcitypes.ResponseInitChain {
-	app.storage.NewTransaction(app.db)
-
 	err := app.storage.SetConsulsCount(app.genesis.ConsulsCount)
 	if err != nil {
 		panic(err)
@@ -148,7 +267,7 @@ func (app *GHApplication) InitChain(req abcitypes.RequestInitChain) abcitypes.Re
 	var consuls []storage.Consul
 	for _, value := range req.Validators {
 		var pubKey account.ConsulPubKey
-		copy(pubKey[:], value.PubKey.GetData())
+		copy(pubKey[:], value.PubKey.GetEd25519())
 		err := app.storage.SetScore(pubKey, uint64(value.Power))
 		if err != nil {
 			panic(err)
@@ -190,16 +309,10 @@ func (app *GHApplication) InitChain(req abcitypes.RequestInitChain) abcitypes.Re
 		}
 	}
 
-	err = app.storage.Commit()
-	if err != nil {
-		panic(err)
-	}
-
 	return abcitypes.ResponseInitChain{}
 }
 
 func (app *GHApplication) BeginBlock(req abcitypes.RequestBeginBlock) abcitypes.ResponseBeginBlock {
-	app.storage.NewTransaction(app.db)
 	isConsul := false
 	consuls, err := app.storage.Consuls()
 	if err == nil {
@@ -229,21 +342,19 @@ func (app *GHApplication) EndBlock(req abcitypes.RequestEndBlock) abcitypes.Resp
 	if err != nil {
 		panic(err)
 	}
+
+	err = app.observeConsuls(consuls)
+	if err != nil {
+		fmt.Printf("Metrics error: %s \n", err.Error())
+	}
+
 	var newValidators []abcitypes.ValidatorUpdate
 	for i := 0; i < app.genesis.ConsulsCount && i < len(consuls); i++ {
 		if consuls[i].Value == 0 {
 			continue
 		}
 
-		pubKey := abcitypes.PubKey{
-			Type: "ed25519",
-			Data: consuls[i].PubKey[:],
-		}
-
-		newValidators = append(newValidators, abcitypes.ValidatorUpdate{
-			PubKey: pubKey,
-			Power:  int64(consuls[i].Value),
-		})
+		newValidators = append(newValidators, abcitypes.Ed25519ValidatorUpdate(consuls[i].PubKey[:], int64(consuls[i].Value)))
 	}
 
 	if len(newValidators) > 0 {
@@ -251,4 +362,26 @@ func (app *GHApplication) EndBlock(req abcitypes.RequestEndBlock) abcitypes.Resp
 	} else {
 		return abcitypes.ResponseEndBlock{}
 	}
-}
\ No newline at end of file
+}
+
+// observeConsuls reports the consuls, the candidates and the scores.
+func (app *GHApplication) observeConsuls(consuls []storage.Consul) error {
+	app.metrics.Consuls.Set(float64(len(consuls)))
+
+	candidates, err := app.storage.ConsulsCandidate()
+	if err != nil && err != storage.ErrKeyNotFound {
+		return err
+	}
+	app.metrics.Candidates.Set(float64(len(candidates)))
+
+	scores, err := app.storage.Scores()
+	if err != nil {
+		return err
+	}
+	app.metrics.resetScores()
+	for pubKey, score := range scores {
+		app.metrics.Scores.With(ConsulLabel, hexutil.Encode(pubKey[:])).Set(float64(score))
+	}
+
+	return nil
+}
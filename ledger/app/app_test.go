@@ -0,0 +1,407 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/Gravity-Tech/gravity-core/common/transactions"
+	"github.com/Gravity-Tech/gravity-core/config"
+	"github.com/Gravity-Tech/gravity-core/ledger/scheduler"
+	"github.com/Gravity-Tech/gravity-core/ledger/snapshot"
+	"github.com/dgraph-io/badger"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/generic"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+)
+
+type testConsul struct {
+	privKey ed25519.PrivKey
+	pubKey  account.ConsulPubKey
+	nonce   uint64
+}
+
+func newTestConsuls(count int) []testConsul {
+	var consuls []testConsul
+	for i := 0; i < count; i++ {
+		privKey := ed25519.GenPrivKeyFromSecret([]byte{byte(i)})
+		var pubKey account.ConsulPubKey
+		copy(pubKey[:], privKey.PubKey().Bytes())
+		consuls = append(consuls, testConsul{privKey: privKey, pubKey: pubKey})
+	}
+
+	return consuls
+}
+
+func newTestApp(t *testing.T, consuls []testConsul, opts ...Option) (*GHApplication, func()) {
+	dir, err := ioutil.TempDir("", "gravity-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	application, closeApp := openTestApp(t, dir, consuls, opts...)
+	return application, func() {
+		closeApp()
+		os.RemoveAll(dir)
+	}
+}
+
+// openTestApp opens an application over the database in dir, so a test can
+// restart it with the state of a previous instance.
+func openTestApp(t *testing.T, dir string, consuls []testConsul, opts ...Option) (*GHApplication, func()) {
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ledger := &account.LedgerValidator{
+		PrivKey: consuls[0].privKey,
+		PubKey:  consuls[0].pubKey,
+	}
+	client, err := gravity.New("http://127.0.0.1:26657")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockScheduler, err := scheduler.New(nil, ledger, client, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genesis := &Genesis{
+		ChainId:                   testChainId,
+		ConsulsCount:              len(consuls),
+		OraclesAddressByValidator: make(map[account.ConsulPubKey][]OraclesAddresses),
+	}
+	for i, v := range consuls {
+		genesis.OraclesAddressByValidator[v.pubKey] = []OraclesAddresses{
+			{ChainType: account.Ethereum, OraclesPubKey: account.OraclesPubKey{byte(i)}},
+		}
+	}
+
+	ledgerConfig := config.DefaultLedgerConfig()
+	application, err := NewGHApplication(blockScheduler, db, nil, genesis, &ledgerConfig, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	application.IsSync = true
+
+	return application, func() {
+		db.Close()
+	}
+}
+
+const testChainId = "gravity-test"
+
+func newTestTx(t *testing.T, consul *testConsul, payload transactions.Payload) []byte {
+	tx := transactions.New(consul.pubKey, payload)
+	tx.Nonce = consul.nonce
+	if err := tx.Sign(testChainId, consul.privKey); err != nil {
+		t.Fatal(err)
+	}
+	consul.nonce++
+
+	return tx.Marshal()
+}
+
+func newTestBlocks(t *testing.T, consuls []testConsul) [][][]byte {
+	votes := &transactions.VoteArgs{
+		Votes: []storage.Vote{
+			{PubKey: consuls[1].pubKey, Score: 50},
+			{PubKey: consuls[2].pubKey, Score: 70},
+		},
+	}
+	nebula := &transactions.SetNebulaArgs{
+		NebulaId: account.NebulaId{1, 2, 3},
+		Info: storage.NebulaInfo{
+			MaxPulseCountInBlock: 1,
+			MinScore:             0,
+			ChainType:            account.Ethereum,
+			Owner:                consuls[0].pubKey,
+		},
+	}
+
+	return [][][]byte{
+		{},
+		{
+			newTestTx(t, &consuls[0], votes),
+			newTestTx(t, &consuls[1], votes),
+		},
+		{
+			newTestTx(t, &consuls[0], nebula),
+			newTestTx(t, &consuls[2], &transactions.NewRoundArgs{ChainType: account.Ethereum, LedgerHeight: 2, TcHeight: 1000}),
+		},
+		{
+			newTestTx(t, &consuls[1], &transactions.NewRoundArgs{ChainType: account.Ethereum, LedgerHeight: 2, TcHeight: 1010}),
+			newTestTx(t, &consuls[0], &transactions.ApproveLastRoundArgs{RoundId: 0}),
+			[]byte("malformed"),
+		},
+		{},
+	}
+}
+
+func initChain(application *GHApplication, consuls []testConsul) {
+	var validators []abcitypes.ValidatorUpdate
+	for i := range consuls {
+		validators = append(validators, abcitypes.Ed25519ValidatorUpdate(consuls[i].pubKey[:], 100))
+	}
+	application.InitChain(abcitypes.RequestInitChain{Validators: validators})
+}
+
+func executeBlock(application *GHApplication, height int64, txs [][]byte) ([]byte, []uint32) {
+	var codes []uint32
+	application.BeginBlock(abcitypes.RequestBeginBlock{Header: tmproto.Header{Height: height}})
+	for _, tx := range txs {
+		rs := application.DeliverTx(abcitypes.RequestDeliverTx{Tx: tx})
+		codes = append(codes, rs.Code)
+	}
+	application.EndBlock(abcitypes.RequestEndBlock{Height: height})
+
+	return application.Commit().Data, codes
+}
+
+func replay(t *testing.T, consuls []testConsul, blocks [][][]byte, opts ...Option) ([][]byte, []uint32) {
+	application, closeApp := newTestApp(t, consuls, opts...)
+	defer closeApp()
+	initChain(application, consuls)
+
+	var hashes [][]byte
+	var codes []uint32
+	for i, txs := range blocks {
+		hash, blockCodes := executeBlock(application, int64(i+1), txs)
+		hashes = append(hashes, hash)
+		codes = append(codes, blockCodes...)
+	}
+
+	info := application.Info(abcitypes.RequestInfo{})
+	if info.LastBlockHeight != int64(len(blocks)) || !bytes.Equal(info.LastBlockAppHash, hashes[len(hashes)-1]) {
+		t.Error("info does not match the last commit")
+	}
+
+	return hashes, codes
+}
+
+// replayOutEnv names the file a replay in a child process writes its result
+// to, see replayInProcess.
+const replayOutEnv = "GRAVITY_REPLAY_OUT"
+
+type replayResult struct {
+	Hashes [][]byte
+	Codes  []uint32
+}
+
+// replayInProcess replays the blocks in a fresh test process, so nothing of
+// this process, as map seeds or package state, can make the hashes agree.
+func replayInProcess(t *testing.T) replayResult {
+	out, err := ioutil.TempFile("", "gravity-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestReplayProcess$")
+	cmd.Env = append(os.Environ(), replayOutEnv+"="+out.Name())
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("replay process: %s\n%s", err, b)
+	}
+
+	b, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result replayResult
+	if err := json.Unmarshal(b, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
+
+func TestReplayProcess(t *testing.T) {
+	out := os.Getenv(replayOutEnv)
+	if out == "" {
+		t.Skip("runs as the child of TestReplayIsDeterministic")
+	}
+
+	consuls := newTestConsuls(3)
+	hashes, codes := replay(t, consuls, newTestBlocks(t, consuls))
+	b, err := json.Marshal(replayResult{Hashes: hashes, Codes: codes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(out, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// replayWithRestart replays the blocks, restarting the application over its
+// database after the block at restartHeight.
+func replayWithRestart(t *testing.T, consuls []testConsul, blocks [][][]byte, restartHeight int) [][]byte {
+	dir, err := ioutil.TempDir("", "gravity-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	application, closeApp := openTestApp(t, dir, consuls)
+	initChain(application, consuls)
+
+	var hashes [][]byte
+	for i, txs := range blocks {
+		if i == restartHeight {
+			closeApp()
+			application, closeApp = openTestApp(t, dir, consuls)
+		}
+		hash, _ := executeBlock(application, int64(i+1), txs)
+		hashes = append(hashes, hash)
+	}
+	closeApp()
+
+	return hashes
+}
+
+func TestReplayIsDeterministic(t *testing.T) {
+	consuls := newTestConsuls(3)
+	blocks := newTestBlocks(t, consuls)
+
+	first, firstCodes := replay(t, consuls, blocks)
+	second := replayInProcess(t)
+
+	if firstCodes[0] != Success || firstCodes[1] != Success {
+		t.Fatalf("votes rejected: %v", firstCodes)
+	}
+	if len(firstCodes) != len(second.Codes) {
+		t.Fatalf("result count mismatch: %v and %v", firstCodes, second.Codes)
+	}
+	for i := range firstCodes {
+		if firstCodes[i] != second.Codes[i] {
+			t.Fatalf("result mismatch for tx %d", i)
+		}
+	}
+
+	restarted := replayWithRestart(t, consuls, blocks, len(blocks)/2)
+	for i := range first {
+		if len(first[i]) == 0 {
+			t.Fatalf("empty app hash at height %d", i+1)
+		}
+		if !bytes.Equal(first[i], second.Hashes[i]) {
+			t.Fatalf("app hash mismatch with a fresh process at height %d", i+1)
+		}
+		if !bytes.Equal(first[i], restarted[i]) {
+			t.Fatalf("app hash mismatch with a restarted app at height %d", i+1)
+		}
+	}
+}
+
+// testCounter counts by label values.
+type testCounter struct {
+	counts map[string]float64
+	labels string
+}
+
+func (c *testCounter) With(labelValues ...string) metrics.Counter {
+	return &testCounter{counts: c.counts, labels: strings.TrimSpace(c.labels + " " + strings.Join(labelValues, " "))}
+}
+
+func (c *testCounter) Add(delta float64) {
+	c.counts[c.labels] += delta
+}
+
+func TestMetrics(t *testing.T) {
+	consuls := newTestConsuls(3)
+	deliverTxs := &testCounter{counts: make(map[string]float64)}
+	consulsCount := generic.NewGauge("consuls")
+	replay(t, consuls, newTestBlocks(t, consuls), WithMetrics(&Metrics{
+		DeliverTxs: deliverTxs,
+		CheckTxs:   discard.NewCounter(),
+		Consuls:    consulsCount,
+		Candidates: discard.NewGauge(),
+		Scores:     discard.NewGauge(),
+	}))
+
+	votes := strings.Join([]string{FuncLabel, string(transactions.Vote), CodeLabel, "0"}, " ")
+	if deliverTxs.counts[votes] != 2 {
+		t.Errorf("votes: expected 2, got %v", deliverTxs.counts)
+	}
+	malformed := strings.Join([]string{FuncLabel, UnknownFunc, CodeLabel, "400"}, " ")
+	if deliverTxs.counts[malformed] != 1 {
+		t.Errorf("malformed txs: expected 1, got %v", deliverTxs.counts)
+	}
+	if consulsCount.Value() == 0 {
+		t.Error("consuls not observed")
+	}
+}
+
+// waitSnapshot blocks until the running snapshot, if any, is created.
+func (app *GHApplication) waitSnapshot() {
+	app.snapshotting <- struct{}{}
+	<-app.snapshotting
+}
+
+func TestBackgroundSnapshots(t *testing.T) {
+	consuls := newTestConsuls(3)
+	application, closeApp := newTestApp(t, consuls)
+	defer closeApp()
+	initChain(application, consuls)
+
+	dir, err := ioutil.TempDir("", "gravity-snapshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	application.snapshots, err = snapshot.NewStore(dir, snapshot.DefaultChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	application.ledgerConfig.SnapshotInterval = 2
+	application.ledgerConfig.StateRetention = 1
+	application.ledgerConfig.SnapshotKeepRecent = 2
+
+	heights := func() []uint64 {
+		application.waitSnapshot()
+		list, err := application.snapshots.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var heights []uint64
+		for _, v := range list {
+			heights = append(heights, v.Height)
+		}
+		return heights
+	}
+
+	executeBlock(application, 1, nil)
+	executeBlock(application, 2, nil)
+	if h := heights(); len(h) != 1 || h[0] != 2 {
+		t.Fatalf("snapshots: expected [2], got %v", h)
+	}
+
+	// A snapshot in progress makes the next one skip.
+	application.snapshotting <- struct{}{}
+	executeBlock(application, 3, nil)
+	executeBlock(application, 4, nil)
+	<-application.snapshotting
+	if h := heights(); len(h) != 1 || h[0] != 2 {
+		t.Fatalf("snapshots: expected [2], got %v", h)
+	}
+
+	for i := int64(5); i <= 10; i++ {
+		executeBlock(application, i, nil)
+		application.waitSnapshot()
+	}
+	if h := heights(); len(h) != 2 || h[0] != 10 || h[1] != 8 {
+		t.Fatalf("snapshots: expected [10 8], got %v", h)
+	}
+}
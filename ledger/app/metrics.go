@@ -0,0 +1,95 @@
+package app
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// MetricsSubsystem names the metrics of the ledger application.
+	MetricsSubsystem = "app"
+
+	FuncLabel   = "func"
+	CodeLabel   = "code"
+	ConsulLabel = "consul"
+)
+
+// Metrics are the txs the application handles and its consuls.
+type Metrics struct {
+	// Number of delivered transactions by function and result code.
+	DeliverTxs metrics.Counter
+	// Number of checked transactions by function and result code.
+	CheckTxs metrics.Counter
+	// Number of consuls.
+	Consuls metrics.Gauge
+	// Number of consul candidates.
+	Candidates metrics.Gauge
+	// Score of every validator with a score.
+	Scores metrics.Gauge
+
+	// scores is the vector of Scores, reset before the scores of a block so
+	// that it drops the validators that no longer have one.
+	scores *stdprometheus.GaugeVec
+}
+
+// PrometheusMetrics registers the metrics of the application in the default
+// Prometheus registry under namespace.
+func PrometheusMetrics(namespace string) *Metrics {
+	txLabels := []string{FuncLabel, CodeLabel}
+	scores := stdprometheus.NewGaugeVec(stdprometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: MetricsSubsystem,
+		Name:      "score",
+		Help:      "Score of the validator.",
+	}, []string{ConsulLabel})
+	stdprometheus.MustRegister(scores)
+
+	return &Metrics{
+		DeliverTxs: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "deliver_txs",
+			Help:      "Number of delivered transactions by function and result code.",
+		}, txLabels),
+		CheckTxs: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "check_txs",
+			Help:      "Number of checked transactions by function and result code.",
+		}, txLabels),
+		Consuls: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "consuls",
+			Help:      "Number of consuls.",
+		}, nil),
+		Candidates: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "consul_candidates",
+			Help:      "Number of consul candidates.",
+		}, nil),
+		Scores: prometheus.NewGauge(scores),
+		scores: scores,
+	}
+}
+
+// resetScores drops the scores of every validator.
+func (m *Metrics) resetScores() {
+	if m.scores != nil {
+		m.scores.Reset()
+	}
+}
+
+// NopMetrics discards the metrics of an application that does not serve them.
+func NopMetrics() *Metrics {
+	return &Metrics{
+		DeliverTxs: discard.NewCounter(),
+		CheckTxs:   discard.NewCounter(),
+		Consuls:    discard.NewGauge(),
+		Candidates: discard.NewGauge(),
+		Scores:     discard.NewGauge(),
+	}
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/Gravity-Tech/gravity-core/ledger/snapshot"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+)
+
+// startSnapshot pins version and creates its snapshot in the background, so
+// Commit does not wait for the export. A snapshot is skipped while the
+// previous one is still being created.
+func (app *GHApplication) startSnapshot(version int64) {
+	select {
+	case app.snapshotting <- struct{}{}:
+	default:
+		fmt.Printf("Snapshot of height %d skipped: previous snapshot in progress \n", version)
+		return
+	}
+
+	pinned, err := app.stateDB.Pin(version)
+	if err != nil {
+		<-app.snapshotting
+		fmt.Printf("Snapshot error: %s \n", err.Error())
+		return
+	}
+
+	go func() {
+		defer func() { <-app.snapshotting }()
+		defer pinned.Release()
+
+		err := app.createSnapshot(pinned)
+		if err != nil {
+			fmt.Printf("Snapshot error: %s \n", err.Error())
+		}
+	}()
+}
+
+func (app *GHApplication) createSnapshot(version *storage.PinnedVersion) error {
+	err := app.snapshots.Create(version)
+	if err != nil {
+		return err
+	}
+
+	return app.snapshots.Prune(app.ledgerConfig.SnapshotKeepRecent)
+}
+
+func (app *GHApplication) ListSnapshots(req abcitypes.RequestListSnapshots) abcitypes.ResponseListSnapshots {
+	if app.snapshots == nil {
+		return abcitypes.ResponseListSnapshots{}
+	}
+
+	snapshots, err := app.snapshots.List()
+	if err != nil {
+		return abcitypes.ResponseListSnapshots{}
+	}
+
+	return abcitypes.ResponseListSnapshots{Snapshots: snapshots}
+}
+
+func (app *GHApplication) OfferSnapshot(req abcitypes.RequestOfferSnapshot) abcitypes.ResponseOfferSnapshot {
+	if req.Snapshot == nil || app.stateDB.Version() != 0 {
+		return abcitypes.ResponseOfferSnapshot{Result: abcitypes.ResponseOfferSnapshot_ABORT}
+	}
+
+	restore, err := snapshot.NewRestore(req.Snapshot)
+	if err == snapshot.ErrInvalidFormat {
+		return abcitypes.ResponseOfferSnapshot{Result: abcitypes.ResponseOfferSnapshot_REJECT_FORMAT}
+	} else if err != nil {
+		return abcitypes.ResponseOfferSnapshot{Result: abcitypes.ResponseOfferSnapshot_REJECT}
+	}
+
+	app.restore = restore
+	return abcitypes.ResponseOfferSnapshot{Result: abcitypes.ResponseOfferSnapshot_ACCEPT}
+}
+
+func (app *GHApplication) LoadSnapshotChunk(req abcitypes.RequestLoadSnapshotChunk) abcitypes.ResponseLoadSnapshotChunk {
+	if app.snapshots == nil {
+		return abcitypes.ResponseLoadSnapshotChunk{}
+	}
+
+	chunk, err := app.snapshots.LoadChunk(req.Height, req.Format, req.Chunk)
+	if err != nil {
+		return abcitypes.ResponseLoadSnapshotChunk{}
+	}
+
+	return abcitypes.ResponseLoadSnapshotChunk{Chunk: chunk}
+}
+
+func (app *GHApplication) ApplySnapshotChunk(req abcitypes.RequestApplySnapshotChunk) abcitypes.ResponseApplySnapshotChunk {
+	if app.restore == nil {
+		return abcitypes.ResponseApplySnapshotChunk{Result: abcitypes.ResponseApplySnapshotChunk_ABORT}
+	}
+
+	done, err := app.restore.Apply(req.Index, req.Chunk)
+	if err != nil {
+		return abcitypes.ResponseApplySnapshotChunk{
+			Result:        abcitypes.ResponseApplySnapshotChunk_RETRY,
+			RefetchChunks: []uint32{req.Index},
+			RejectSenders: []string{req.Sender},
+		}
+	}
+	if !done {
+		return abcitypes.ResponseApplySnapshotChunk{Result: abcitypes.ResponseApplySnapshotChunk_ACCEPT}
+	}
+
+	err = app.restore.Commit(app.stateDB)
+	app.restore = nil
+	if err != nil {
+		return abcitypes.ResponseApplySnapshotChunk{Result: abcitypes.ResponseApplySnapshotChunk_REJECT_SNAPSHOT}
+	}
+
+	return abcitypes.ResponseApplySnapshotChunk{Result: abcitypes.ResponseApplySnapshotChunk_ACCEPT}
+}
@@ -0,0 +1,23 @@
+package query
+
+import (
+	"encoding/json"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+)
+
+func nonce(store *storage.Storage, value []byte) (uint64, error) {
+	var rq ByValidatorRq
+	err := json.Unmarshal(value, &rq)
+	if err != nil {
+		return 0, err
+	}
+
+	pubKey, err := account.HexToValidatorPubKey(rq.PubKey)
+	if err != nil {
+		return 0, err
+	}
+
+	return store.Nonce(pubKey)
+}
@@ -18,6 +18,7 @@ const (
 	CommitHashPath             Path = "commitHash"
 	RevealPath                 Path = "reveal"
 	RevealsPath                Path = "reveals"
+	ScoredRevealsPath          Path = "scoredReveals"
 	ResultPath                 Path = "result"
 	ResultsPath                Path = "results"
 	NebulaePath                Path = "nebulae"
@@ -30,6 +31,8 @@ const (
 	NebulaOraclesIndexPath     Path = "nebulaOraclesIndex"
 	AllValidatorsPath          Path = "allValidators"
 	ValidatorDetailsPath       Path = "validatorDetails"
+	NoncePath                  Path = "nonce"
+	PulseWindowPath            Path = "pulseWindow"
 )
 
 var (
@@ -53,6 +56,8 @@ func Query(store *storage.Storage, path string, rq []byte, validatorDetails *con
 		value, err = reveal(store, rq)
 	case RevealsPath:
 		value, err = reveals(store, rq)
+	case ScoredRevealsPath:
+		value, err = scoredReveals(store, rq)
 	case ResultPath:
 		value, err = result(store, rq)
 	case BftOracleByNebulaPath:
@@ -79,6 +84,10 @@ func Query(store *storage.Storage, path string, rq []byte, validatorDetails *con
 		value, err = allValidators(store, rq)
 	case ValidatorDetailsPath:
 		value, err = validatorDetails.Bytes()
+	case NoncePath:
+		value, err = nonce(store, rq)
+	case PulseWindowPath:
+		value, err = pulseWindow(store, rq)
 	default:
 		return nil, ErrInvalidPath
 	}
@@ -1,9 +1,12 @@
 package query
 
 import (
+	"bytes"
 	"encoding/json"
+	"sort"
 
 	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/state"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 
@@ -69,7 +72,7 @@ func commitHash(store *storage.Storage, value []byte) ([]byte, error) {
 
 	return v, nil
 }
-func reveal(store *storage.Storage, value []byte) ([]byte, error) {
+func reveal(store *storage.Storage, value []byte) (*storage.RevealValue, error) {
 	var rq RevealRq
 	err := json.Unmarshal(value, &rq)
 	if err != nil {
@@ -98,7 +101,7 @@ func reveal(store *storage.Storage, value []byte) ([]byte, error) {
 
 	return v, nil
 }
-func reveals(store *storage.Storage, value []byte) ([]string, error) {
+func reveals(store *storage.Storage, value []byte) ([]storage.RevealValue, error) {
 	var rq RevealRq
 	err := json.Unmarshal(value, &rq)
 	if err != nil {
@@ -117,6 +120,64 @@ func reveals(store *storage.Storage, value []byte) ([]string, error) {
 
 	return v, nil
 }
+
+// ScoredReveal is a reveal with the score of the validator of its oracle.
+type ScoredReveal struct {
+	OraclePubKey account.OraclesPubKey
+	Value        []byte
+	Score        uint64
+}
+
+// scoredReveals sorts the reveals by oracle, so all oracles aggregate them in
+// the same order.
+func scoredReveals(store *storage.Storage, value []byte) ([]ScoredReveal, error) {
+	var rq RevealRq
+	err := json.Unmarshal(value, &rq)
+	if err != nil {
+		return nil, err
+	}
+
+	nebulaAddress, err := account.StringToNebulaId(rq.NebulaAddress, rq.ChainType)
+	if err != nil {
+		return nil, err
+	}
+
+	reveals, err := store.OracleReveals(nebulaAddress, rq.Height, rq.PulseId)
+	if err != nil {
+		return nil, err
+	}
+
+	scores, err := store.Scores()
+	if err != nil {
+		return nil, err
+	}
+	scoresByOracle := make(map[account.OraclesPubKey]uint64)
+	for consul, score := range scores {
+		oracles, err := store.OraclesByConsul(consul)
+		if err == storage.ErrKeyNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if oracle, ok := oracles[rq.ChainType]; ok {
+			scoresByOracle[oracle] = score
+		}
+	}
+
+	var result []ScoredReveal
+	for oracle, reveal := range reveals {
+		result = append(result, ScoredReveal{
+			OraclePubKey: oracle,
+			Value:        reveal.Value,
+			Score:        scoresByOracle[oracle],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].OraclePubKey[:], result[j].OraclePubKey[:]) < 0
+	})
+
+	return result, nil
+}
 func result(store *storage.Storage, value []byte) ([]byte, error) {
 	var rq ResultRq
 	err := json.Unmarshal(value, &rq)
@@ -139,6 +200,20 @@ func result(store *storage.Storage, value []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	// Only signs of the hash the oracles agreed on can be sent to the
+	// target chain.
+	pulseHash, err := store.PulseHash(nebulaAddress, rq.Height)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := store.ResultHash(nebulaAddress, rq.Height, oraclePubKey)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(hash, pulseHash) {
+		return nil, storage.ErrKeyNotFound
+	}
+
 	return v, nil
 }
 func nebulae(store *storage.Storage) (storage.NebulaMap, error) {
@@ -172,3 +247,29 @@ func nebulaInfo(store *storage.Storage, value []byte) (*storage.NebulaInfo, erro
 
 	return v, nil
 }
+
+func pulseWindow(store *storage.Storage, value []byte) (*state.PulseWindow, error) {
+	var rq ByNebulaRq
+	err := json.Unmarshal(value, &rq)
+	if err != nil {
+		return nil, err
+	}
+
+	nebulaAddress, err := account.StringToNebulaId(rq.NebulaAddress, rq.ChainType)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = store.NebulaInfo(nebulaAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	height, err := store.LastHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	window := state.NewPulseWindow(height)
+	return &window, nil
+}
@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// MetricsSubsystem names the metrics of the consul scheduler.
+	MetricsSubsystem = "scheduler"
+
+	ChainLabel = "chain"
+	StageLabel = "stage"
+
+	// BlockStage and ProcessStage are the stages of the errors: the handling
+	// of a block by every node and the rounds a consul processes.
+	BlockStage   = "block"
+	ProcessStage = "process"
+)
+
+// Metrics follow the rounds of the ledger and of the target chains.
+type Metrics struct {
+	// Ledger round of the last block.
+	Round metrics.Gauge
+	// Last round approved on the ledger.
+	LastRoundApproved metrics.Gauge
+	// Last round of the consuls on a target chain, reported by consuls.
+	LastRound metrics.Gauge
+	// Ledger round ahead of the last round of a target chain.
+	RoundLag metrics.Gauge
+	// Number of errors by stage.
+	Errors metrics.Counter
+}
+
+// PrometheusMetrics registers the metrics of the scheduler in the default
+// Prometheus registry under namespace.
+func PrometheusMetrics(namespace string) *Metrics {
+	return &Metrics{
+		Round: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "round",
+			Help:      "Ledger round of the last block.",
+		}, nil),
+		LastRoundApproved: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "last_round_approved",
+			Help:      "Last round approved on the ledger.",
+		}, nil),
+		LastRound: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "last_round",
+			Help:      "Last round of the consuls on the target chain.",
+		}, []string{ChainLabel}),
+		RoundLag: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "round_lag",
+			Help:      "Rounds the ledger is ahead of the last round of the target chain.",
+		}, []string{ChainLabel}),
+		Errors: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "errors",
+			Help:      "Number of errors of the scheduler by stage.",
+		}, []string{StageLabel}),
+	}
+}
+
+// NopMetrics discards the metrics of a scheduler that does not serve them.
+func NopMetrics() *Metrics {
+	return &Metrics{
+		Round:             discard.NewGauge(),
+		LastRoundApproved: discard.NewGauge(),
+		LastRound:         discard.NewGauge(),
+		RoundLag:          discard.NewGauge(),
+		Errors:            discard.NewCounter(),
+	}
+}
@@ -2,21 +2,24 @@ package scheduler
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"github.com/Gravity-Tech/gravity-core/common/gravity"
 
 	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/state"
 	"github.com/Gravity-Tech/gravity-core/common/transactions"
 )
 
 func (scheduler *Scheduler) process(height int64) {
 	err := scheduler.processByHeight(height)
 	if err != nil {
+		scheduler.metrics.Errors.With(StageLabel, ProcessStage).Add(1)
 		fmt.Printf("Error:%s\n", err)
 	}
 }
 func (scheduler *Scheduler) processByHeight(height int64) error {
-	roundId := height / CalculateScoreInterval
+	roundId := height / state.CalculateScoreInterval
 
 	consulInfo, err := scheduler.consulInfo()
 	if err != nil {
@@ -24,8 +27,15 @@ func (scheduler *Scheduler) processByHeight(height int64) error {
 	}
 
 	isExist := true
-	if height%CalculateScoreInterval == 0 {
-		roundId := (height / CalculateScoreInterval) - 1
+	if height%state.CalculateScoreInterval == 0 {
+		for k := range scheduler.Adaptors {
+			err := scheduler.attestRound(k, uint64(height))
+			if err != nil {
+				fmt.Printf("Error:%s\n", err.Error())
+			}
+		}
+
+		roundId := (height / state.CalculateScoreInterval) - 1
 
 		index := roundId % int64(consulInfo.TotalCount)
 		for k, v := range scheduler.Adaptors {
@@ -71,6 +81,8 @@ func (scheduler *Scheduler) processByHeight(height int64) error {
 		if err != nil {
 			return err
 		}
+		scheduler.metrics.LastRound.With(ChainLabel, k.String()).Set(float64(lastRound))
+		scheduler.metrics.RoundLag.With(ChainLabel, k.String()).Set(float64(roundId) - float64(lastRound))
 		isExist = uint64(roundId) == lastRound
 		if uint64(roundId) <= lastRound {
 			continue
@@ -104,19 +116,43 @@ func (scheduler *Scheduler) processByHeight(height int64) error {
 	if err != nil && err != gravity.ErrValueNotFound {
 		return err
 	}
-	senderIndex := height % int64(consulInfo.TotalCount)
-	if isExist && uint64(roundId) > lastRound && senderIndex == int64(consulInfo.ConsulIndex) {
-		tx, err := transactions.New(scheduler.Ledger.PubKey, transactions.ApproveLastRound, scheduler.Ledger.PrivKey)
-		if err != nil {
-			return err
-		}
-		err = scheduler.client.SendTx(tx)
+	scheduler.metrics.LastRoundApproved.Set(float64(lastRound))
+	if isExist && uint64(roundId) > lastRound && atomic.LoadInt64(&scheduler.approvedRound) < roundId {
+		tx := transactions.New(scheduler.Ledger.PubKey, &transactions.ApproveLastRoundArgs{
+			RoundId: uint64(roundId),
+		})
+		err = scheduler.client.SendTx(tx, scheduler.Ledger.PrivKey)
 		if err != nil {
 			return err
 		}
+		atomic.StoreInt64(&scheduler.approvedRound, roundId)
 	}
 	return nil
 }
+
+// attestRound sends the height of the target chain at the start of the round
+// that begins at ledgerHeight, unless the round already has one.
+func (scheduler *Scheduler) attestRound(chainType account.ChainType, ledgerHeight uint64) error {
+	_, err := scheduler.client.RoundHeight(chainType, ledgerHeight)
+	if err != nil && err != gravity.ErrValueNotFound {
+		return err
+	} else if err == nil {
+		return nil
+	}
+
+	tcHeight, err := scheduler.Adaptors[chainType].GetHeight(scheduler.ctx)
+	if err != nil {
+		return err
+	}
+
+	tx := transactions.New(scheduler.Ledger.PubKey, &transactions.NewRoundArgs{
+		ChainType:    chainType,
+		LedgerHeight: ledgerHeight,
+		TcHeight:     tcHeight,
+	})
+
+	return scheduler.client.SendTx(tx, scheduler.Ledger.PrivKey)
+}
 func (scheduler *Scheduler) consulInfo() (*ConsulInfo, error) {
 	consuls, err := scheduler.client.Consuls()
 	if err != nil {
@@ -175,23 +211,13 @@ func (scheduler *Scheduler) signConsulsResult(roundId int64, chainType account.C
 	if err != nil {
 		return err
 	}
-	tx, err := transactions.New(scheduler.Ledger.PubKey, transactions.SignNewConsuls, scheduler.Ledger.PrivKey)
-	if err != nil {
-		return err
-	}
-
-	tx.AddValues([]transactions.Value{
-		transactions.BytesValue{
-			Value: []byte{byte(chainType)},
-		},
-		transactions.IntValue{
-			Value: roundId,
-		},
-		transactions.BytesValue{
-			Value: sign,
-		},
+	tx := transactions.New(scheduler.Ledger.PubKey, &transactions.SignNewConsulsArgs{
+		ChainType: chainType,
+		RoundId:   roundId,
+		Sign:      sign,
 	})
-	err = scheduler.client.SendTx(tx)
+
+	err = scheduler.client.SendTx(tx, scheduler.Ledger.PrivKey)
 	if err != nil {
 		return err
 	}
@@ -226,23 +252,13 @@ func (scheduler *Scheduler) signOraclesByNebula(roundId int64, nebulaId account.
 		return err
 	}
 
-	tx, err := transactions.New(scheduler.Ledger.PubKey, transactions.SignNewOracles, scheduler.Ledger.PrivKey)
-	if err != nil {
-		return err
-	}
-
-	tx.AddValues([]transactions.Value{
-		transactions.IntValue{
-			Value: roundId,
-		},
-		transactions.BytesValue{
-			Value: sign,
-		},
-		transactions.BytesValue{
-			Value: nebulaId[:],
-		},
+	tx := transactions.New(scheduler.Ledger.PubKey, &transactions.SignNewOraclesArgs{
+		RoundId:  roundId,
+		Sign:     sign,
+		NebulaId: nebulaId,
 	})
-	err = scheduler.client.SendTx(tx)
+
+	err = scheduler.client.SendTx(tx, scheduler.Ledger.PrivKey)
 	if err != nil {
 		return err
 	}
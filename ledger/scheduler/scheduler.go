@@ -12,12 +12,12 @@ import (
 
 	"github.com/Gravity-Tech/gravity-core/common/account"
 	calculator "github.com/Gravity-Tech/gravity-core/common/score"
+	"github.com/Gravity-Tech/gravity-core/common/state"
 	"github.com/Gravity-Tech/gravity-core/common/storage"
 )
 
 const (
-	CalculateScoreInterval = 200
-	OracleCount            = 5
+	OracleCount = 5
 )
 
 type Scheduler struct {
@@ -25,6 +25,9 @@ type Scheduler struct {
 	Ledger   *account.LedgerValidator
 	ctx      context.Context
 	client   *gravity.Client
+
+	approvedRound int64
+	metrics       *Metrics
 }
 
 type ConsulInfo struct {
@@ -33,27 +36,47 @@ type ConsulInfo struct {
 	IsConsul    bool
 }
 
-func New(adaptors map[account.ChainType]adaptors.IBlockchainAdaptor, ledger *account.LedgerValidator, localHost string, ctx context.Context) (*Scheduler, error) {
-	client, err := gravity.New(localHost)
-	if err != nil {
-		return nil, err
+type Option func(*Scheduler)
+
+func WithMetrics(metrics *Metrics) Option {
+	return func(s *Scheduler) {
+		s.metrics = metrics
 	}
-	return &Scheduler{
+}
+
+func New(adaptors map[account.ChainType]adaptors.IBlockchainAdaptor, ledger *account.LedgerValidator, client *gravity.Client, ctx context.Context, opts ...Option) (*Scheduler, error) {
+	scheduler := &Scheduler{
 		Ledger:   ledger,
 		Adaptors: adaptors,
 		ctx:      ctx,
 		client:   client,
-	}, nil
+		metrics:  NopMetrics(),
+	}
+	for _, opt := range opts {
+		opt(scheduler)
+	}
+
+	return scheduler, nil
 }
 
 func (scheduler *Scheduler) HandleBlock(height int64, store *storage.Storage, isSync bool, isConsul bool) error {
+	err := scheduler.handleBlock(height, store, isSync, isConsul)
+	if err != nil {
+		scheduler.metrics.Errors.With(StageLabel, BlockStage).Add(1)
+	}
+
+	return err
+}
+
+func (scheduler *Scheduler) handleBlock(height int64, store *storage.Storage, isSync bool, isConsul bool) error {
 	if !isSync && isConsul {
 		go scheduler.process(height)
 	}
 
-	roundId := height / CalculateScoreInterval
+	roundId := height / state.CalculateScoreInterval
+	scheduler.metrics.Round.Set(float64(roundId))
 
-	if height%CalculateScoreInterval == 0 || height == 1 {
+	if height%state.CalculateScoreInterval == 0 || height == 1 {
 		if err := scheduler.calculateScores(store); err != nil {
 			return err
 		}
@@ -62,6 +85,14 @@ func (scheduler *Scheduler) HandleBlock(height int64, store *storage.Storage, is
 			return err
 		}
 
+		consuls, err := store.Consuls()
+		if err != nil {
+			return err
+		}
+		if err := store.SetRoundConsuls(uint64(roundId), consuls); err != nil {
+			return err
+		}
+
 		nebulae, err := store.Nebulae()
 		if err != nil {
 			return err
@@ -185,8 +216,14 @@ func (scheduler *Scheduler) updateOracles(roundId int64, nebulaId account.Nebula
 	var oracles []account.OraclesPubKey
 	newOraclesMap := make(storage.OraclesMap)
 
-	for k, v := range oraclesByNebula {
-		oracleAddress, err := account.StringToOraclePubKey(k, v)
+	var oraclesAddresses []string
+	for k := range oraclesByNebula {
+		oraclesAddresses = append(oraclesAddresses, k)
+	}
+	sort.Strings(oraclesAddresses)
+
+	for _, k := range oraclesAddresses {
+		oracleAddress, err := account.StringToOraclePubKey(k, oraclesByNebula[k])
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,68 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+)
+
+var (
+	ErrInvalidFormat   = errors.New("invalid snapshot format")
+	ErrInvalidMetadata = errors.New("invalid snapshot metadata")
+	ErrInvalidChunk    = errors.New("invalid snapshot chunk")
+)
+
+// Restore collects the chunks of an offered snapshot and imports them into
+// the state once the last one arrives. Tendermint applies chunks in order.
+type Restore struct {
+	snapshot *abcitypes.Snapshot
+	hashes   [][]byte
+	next     uint32
+	buf      bytes.Buffer
+}
+
+func NewRestore(snapshot *abcitypes.Snapshot) (*Restore, error) {
+	if snapshot.Format != Format {
+		return nil, ErrInvalidFormat
+	}
+
+	var meta metadata
+	err := json.Unmarshal(snapshot.Metadata, &meta)
+	if err != nil {
+		return nil, ErrInvalidMetadata
+	}
+	if uint32(len(meta.ChunkHashes)) != snapshot.Chunks || !bytes.Equal(snapshotHash(meta.ChunkHashes), snapshot.Hash) {
+		return nil, ErrInvalidMetadata
+	}
+
+	return &Restore{
+		snapshot: snapshot,
+		hashes:   meta.ChunkHashes,
+	}, nil
+}
+
+// Apply checks the chunk against the snapshot metadata and reports whether
+// it was the last one.
+func (restore *Restore) Apply(index uint32, chunk []byte) (bool, error) {
+	if index != restore.next {
+		return false, ErrInvalidChunk
+	}
+
+	hash := sha256.Sum256(chunk)
+	if !bytes.Equal(hash[:], restore.hashes[index]) {
+		return false, ErrInvalidChunk
+	}
+
+	restore.buf.Write(chunk)
+	restore.next++
+
+	return restore.next == restore.snapshot.Chunks, nil
+}
+
+func (restore *Restore) Commit(db *storage.DB) error {
+	return db.Import(int64(restore.snapshot.Height), &restore.buf)
+}
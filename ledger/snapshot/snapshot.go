@@ -0,0 +1,244 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+)
+
+const (
+	Format uint32 = 1
+
+	DefaultChunkSize = 4 << 20
+
+	metadataFileName = "snapshot.json"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	ErrChunkNotFound    = errors.New("chunk not found")
+)
+
+type metadata struct {
+	ChunkHashes [][]byte
+}
+
+// Store keeps state snapshots on disk, one directory per height with the
+// chunk files and their hashes.
+type Store struct {
+	dir       string
+	chunkSize int
+}
+
+func NewStore(dir string, chunkSize int) (*Store, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		dir:       dir,
+		chunkSize: chunkSize,
+	}, nil
+}
+
+// Create writes the snapshot of a pinned state version. It does not touch the
+// DB of the version and can run beside block execution.
+func (store *Store) Create(version *storage.PinnedVersion) error {
+	dir := store.snapshotDir(uint64(version.Version()))
+	tmpDir := dir + ".tmp"
+	err := os.RemoveAll(tmpDir)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(tmpDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	writer := &chunkWriter{dir: tmpDir, size: store.chunkSize}
+	err = version.Export(writer)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return err
+	}
+
+	b, err := json.Marshal(&metadata{ChunkHashes: writer.hashes})
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(path.Join(tmpDir, metadataFileName), b, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpDir, dir)
+}
+
+func (store *Store) List() ([]*abcitypes.Snapshot, error) {
+	heights, err := store.heights()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*abcitypes.Snapshot
+	for i := len(heights) - 1; i >= 0; i-- {
+		snapshot, err := store.load(heights[i])
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+func (store *Store) LoadChunk(height uint64, format uint32, chunk uint32) ([]byte, error) {
+	if format != Format {
+		return nil, ErrSnapshotNotFound
+	}
+
+	b, err := ioutil.ReadFile(path.Join(store.snapshotDir(height), strconv.FormatUint(uint64(chunk), 10)))
+	if os.IsNotExist(err) {
+		return nil, ErrChunkNotFound
+	}
+
+	return b, err
+}
+
+// Prune removes all but the keepRecent most recent snapshots.
+func (store *Store) Prune(keepRecent int) error {
+	heights, err := store.heights()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(heights)-keepRecent; i++ {
+		err := os.RemoveAll(store.snapshotDir(heights[i]))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (store *Store) load(height uint64) (*abcitypes.Snapshot, error) {
+	b, err := ioutil.ReadFile(path.Join(store.snapshotDir(height), metadataFileName))
+	if os.IsNotExist(err) {
+		return nil, ErrSnapshotNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	var meta metadata
+	err = json.Unmarshal(b, &meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return &abcitypes.Snapshot{
+		Height:   height,
+		Format:   Format,
+		Chunks:   uint32(len(meta.ChunkHashes)),
+		Hash:     snapshotHash(meta.ChunkHashes),
+		Metadata: b,
+	}, nil
+}
+
+func (store *Store) heights() ([]uint64, error) {
+	files, err := ioutil.ReadDir(store.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var heights []uint64
+	for _, f := range files {
+		if !f.IsDir() {
+			continue
+		}
+		height, err := strconv.ParseUint(f.Name(), 10, 64)
+		if err != nil {
+			continue
+		}
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	return heights, nil
+}
+
+func (store *Store) snapshotDir(height uint64) string {
+	return path.Join(store.dir, strconv.FormatUint(height, 10))
+}
+
+func snapshotHash(chunkHashes [][]byte) []byte {
+	h := sha256.New()
+	for _, v := range chunkHashes {
+		h.Write(v)
+	}
+
+	return h.Sum(nil)
+}
+
+type chunkWriter struct {
+	dir  string
+	size int
+
+	buf    []byte
+	hashes [][]byte
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		free := w.size - len(w.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		w.buf = append(w.buf, p[:free]...)
+		p = p[free:]
+
+		if len(w.buf) == w.size {
+			err := w.flush()
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (w *chunkWriter) Close() error {
+	if len(w.buf) > 0 || len(w.hashes) == 0 {
+		return w.flush()
+	}
+
+	return nil
+}
+
+func (w *chunkWriter) flush() error {
+	name := path.Join(w.dir, strconv.Itoa(len(w.hashes)))
+	err := ioutil.WriteFile(name, w.buf, 0644)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(w.buf)
+	w.hashes = append(w.hashes, hash[:])
+	w.buf = w.buf[:0]
+
+	return nil
+}
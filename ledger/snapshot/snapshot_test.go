@@ -0,0 +1,122 @@
+package snapshot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/dgraph-io/badger"
+)
+
+func openTestDB(t *testing.T, dir string) (*badger.DB, *storage.DB) {
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stateDB, err := storage.OpenDB(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db, stateDB
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gravity-snapshot")
+	defer os.RemoveAll(dir)
+
+	db, stateDB := openTestDB(t, path.Join(dir, "source"))
+	defer db.Close()
+
+	store := storage.New()
+	store.NewTransaction(stateDB)
+	for i := 0; i < 100; i++ {
+		var pubKey account.ConsulPubKey
+		pubKey[0] = byte(i)
+		if err := store.SetScore(pubKey, uint64(i)); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetLastHeight(uint64(i)); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := stateDB.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshots, err := NewStore(path.Join(dir, "snapshots"), 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, height := range []int64{50, 100} {
+		version, err := stateDB.Pin(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = snapshots.Create(version)
+		version.Release()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := snapshots.Prune(1); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := snapshots.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Height != 100 || list[0].Chunks < 2 {
+		t.Fatalf("unexpected snapshots: %v", list)
+	}
+
+	targetDB, targetStateDB := openTestDB(t, path.Join(dir, "target"))
+	defer targetDB.Close()
+
+	restore, err := NewRestore(list[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint32(0); i < list[0].Chunks; i++ {
+		chunk, err := snapshots.LoadChunk(list[0].Height, list[0].Format, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		forged := append([]byte{}, chunk...)
+		forged[0] ^= 0xff
+		if _, err := restore.Apply(i, forged); err != ErrInvalidChunk {
+			t.Error("forged chunk accepted")
+		}
+
+		done, err := restore.Apply(i, chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done != (i == list[0].Chunks-1) {
+			t.Errorf("unexpected done at chunk %d", i)
+		}
+	}
+
+	if err := restore.Commit(targetStateDB); err != nil {
+		t.Fatal(err)
+	}
+	if targetStateDB.Version() != 100 || !bytes.Equal(targetStateDB.Hash(), stateDB.Hash()) {
+		t.Error("restored state differs from the snapshot")
+	}
+
+	restored := storage.New()
+	if err := restored.NewCacheTransaction(targetStateDB); err != nil {
+		t.Fatal(err)
+	}
+	scores, err := restored.Scores()
+	if err != nil || len(scores) != 100 {
+		t.Errorf("invalid restored scores: %d", len(scores))
+	}
+}
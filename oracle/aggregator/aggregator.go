@@ -0,0 +1,340 @@
+package aggregator
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/Gravity-Tech/gravity-core/abi"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+)
+
+// Type names the aggregation of the reveals of a nebula, the types are
+// listed by the ledger in storage. Remote posts them to the aggregate
+// endpoint of the extractor, the others are computed by the oracle itself,
+// so every oracle gets the same result from the same reveals.
+type Type = storage.AggregatorType
+
+const (
+	Remote        = storage.RemoteAggregator
+	Median        = storage.MedianAggregator
+	TrimmedMean   = storage.TrimmedMeanAggregator
+	Mode          = storage.ModeAggregator
+	ScoreWeighted = storage.ScoreWeightedAggregator
+
+	// TrimmedMeanCut is the share of the values that TrimmedMean drops from
+	// each end, as a divisor of their count.
+	TrimmedMeanCut = 4
+)
+
+var (
+	ErrUnknownType     = storage.ErrUnknownAggregator
+	ErrNoValues        = errors.New("no values to aggregate")
+	ErrUnsupportedType = errors.New("aggregator does not support the data type")
+	ErrZeroWeight      = errors.New("total weight of values is zero")
+)
+
+// Value is a revealed value with the weight of its oracle.
+type Value struct {
+	Data   extractor.Data
+	Weight uint64
+}
+
+type Aggregator func(values []Value) (*extractor.Data, error)
+
+var aggregators = map[Type]Aggregator{
+	Median:        median,
+	TrimmedMean:   trimmedMean,
+	Mode:          mode,
+	ScoreWeighted: scoreWeighted,
+}
+
+// ParseType returns the aggregator type of value. An empty value is Remote,
+// as it was the only aggregation before the types.
+func ParseType(value string) (Type, error) {
+	return storage.ParseAggregatorType(value)
+}
+
+// Local returns the aggregator of the type computed by the oracle, it is
+// false for Remote.
+func Local(aggregatorType Type) (Aggregator, bool) {
+	aggregator, ok := aggregators[aggregatorType]
+	return aggregator, ok
+}
+
+// numbers parses the values of a numeric type: int64, uint256 or decimals
+// of one precision. A decimal is its mantissa.
+func numbers(values []Value) ([]*big.Int, error) {
+	if len(values) == 0 {
+		return nil, ErrNoValues
+	}
+
+	first := values[0].Data
+	var result []*big.Int
+	for _, v := range values {
+		if v.Data.Type != first.Type || v.Data.Precision != first.Precision {
+			return nil, ErrUnsupportedType
+		}
+		i, err := number(&v.Data)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, i)
+	}
+
+	return result, nil
+}
+
+func number(data *extractor.Data) (*big.Int, error) {
+	switch data.Type {
+	case extractor.Int64:
+		i, err := strconv.ParseInt(data.Value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return big.NewInt(i), nil
+	case extractor.Uint256:
+		i, ok := new(big.Int).SetString(data.Value, 10)
+		if !ok || i.Sign() < 0 {
+			return nil, abi.ErrInvalidValue
+		}
+		return i, nil
+	case extractor.Decimal:
+		return abi.ParseDecimal(data.Value, data.Precision)
+	default:
+		return nil, ErrUnsupportedType
+	}
+}
+
+// sorted parses the numbers in ascending order.
+func sorted(values []Value) ([]*big.Int, error) {
+	result, err := numbers(values)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Cmp(result[j]) < 0
+	})
+
+	return result, nil
+}
+
+// mean rounds toward negative infinity. The mean of values of a type is in
+// its range, so like is the template of the result.
+func mean(values []*big.Int, like extractor.Data) *extractor.Data {
+	sum := new(big.Int)
+	for _, v := range values {
+		sum.Add(sum, v)
+	}
+	sum.Div(sum, big.NewInt(int64(len(values))))
+
+	return numberData(sum, like)
+}
+
+func numberData(i *big.Int, like extractor.Data) *extractor.Data {
+	if like.Type == extractor.Decimal {
+		return &extractor.Data{Type: like.Type, Value: abi.FormatDecimal(i, like.Precision), Precision: like.Precision}
+	}
+
+	return &extractor.Data{Type: like.Type, Value: i.String()}
+}
+
+// median averages the two middle values of an even count.
+func median(values []Value) (*extractor.Data, error) {
+	parsed, err := sorted(values)
+	if err != nil {
+		return nil, err
+	}
+
+	middle := len(parsed) / 2
+	if len(parsed)%2 == 1 {
+		return mean(parsed[middle:middle+1], values[0].Data), nil
+	}
+
+	return mean(parsed[middle-1:middle+1], values[0].Data), nil
+}
+
+// trimmedMean averages the values left after dropping the lowest and the
+// highest 1/TrimmedMeanCut of them.
+func trimmedMean(values []Value) (*extractor.Data, error) {
+	parsed, err := sorted(values)
+	if err != nil {
+		return nil, err
+	}
+
+	cut := len(parsed) / TrimmedMeanCut
+	return mean(parsed[cut:len(parsed)-cut], values[0].Data), nil
+}
+
+// mode returns the most frequent value of any type. Values are equal when
+// their canonical encodings are. A tie goes to the smallest value,
+// numerically for the numeric types and bytewise for the others.
+func mode(values []Value) (*extractor.Data, error) {
+	return heaviest(values, func(Value) uint64 { return 1 })
+}
+
+// heaviest returns the value of the most total weight, with the ties of
+// mode.
+func heaviest(values []Value, weight func(Value) uint64) (*extractor.Data, error) {
+	if len(values) == 0 {
+		return nil, ErrNoValues
+	}
+
+	t, err := abi.ExtractorTypeOf(values[0].Data.Type)
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	type candidate struct {
+		data   extractor.Data
+		number *big.Int
+		weight uint64
+	}
+	candidates := make(map[string]*candidate)
+	for _, v := range values {
+		if v.Data.Type != values[0].Data.Type {
+			return nil, ErrUnsupportedType
+		}
+		key, err := abi.Encode(&v.Data, t)
+		if err != nil {
+			return nil, err
+		}
+		c, ok := candidates[string(key)]
+		if !ok {
+			c = &candidate{data: v.Data}
+			c.number, _ = number(&v.Data)
+			candidates[string(key)] = c
+		}
+		c.weight += weight(v)
+	}
+
+	less := func(a, b string) bool {
+		x, y := candidates[a].number, candidates[b].number
+		if x != nil && y != nil && x.Cmp(y) != 0 {
+			return x.Cmp(y) < 0
+		}
+		return a < b
+	}
+
+	var result string
+	var best *candidate
+	for key, c := range candidates {
+		if best == nil || c.weight > best.weight || c.weight == best.weight && less(key, result) {
+			result, best = key, c
+		}
+	}
+
+	data := best.data
+	return &data, nil
+}
+
+// WeightedMedian returns the lower weighted median of numeric values and
+// the value of the most weight, like mode, of the other types.
+func WeightedMedian(values []Value) (*extractor.Data, error) {
+	if len(values) == 0 {
+		return nil, ErrNoValues
+	}
+	if _, err := number(&values[0].Data); err == ErrUnsupportedType {
+		return heaviest(values, func(v Value) uint64 { return v.Weight })
+	}
+
+	parsed, err := numbers(values)
+	if err != nil {
+		return nil, err
+	}
+
+	order := make([]int, len(values))
+	var total uint64
+	for i, v := range values {
+		order[i] = i
+		total += v.Weight
+	}
+	if total == 0 {
+		return nil, ErrZeroWeight
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return parsed[order[i]].Cmp(parsed[order[j]]) < 0
+	})
+
+	var below uint64
+	for _, i := range order {
+		below += values[i].Weight
+		if below >= total-below {
+			return numberData(parsed[i], values[0].Data), nil
+		}
+	}
+
+	return nil, ErrZeroWeight
+}
+
+// Deviation is the distance of value from result relative to result. It is
+// 0 for equal values of the other types and +Inf for different ones, like
+// for a numeric value that differs from a zero result.
+func Deviation(value extractor.Data, result extractor.Data) (float64, error) {
+	if value.Type != result.Type || value.Precision != result.Precision {
+		return math.Inf(1), nil
+	}
+
+	x, err := number(&value)
+	if err == ErrUnsupportedType {
+		t, err := abi.ExtractorTypeOf(value.Type)
+		if err != nil {
+			return 0, err
+		}
+		a, err := abi.Encode(&value, t)
+		if err != nil {
+			return 0, err
+		}
+		b, err := abi.Encode(&result, t)
+		if err != nil {
+			return 0, err
+		}
+		if bytes.Equal(a, b) {
+			return 0, nil
+		}
+		return math.Inf(1), nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	y, err := number(&result)
+	if err != nil {
+		return 0, err
+	}
+	if x.Cmp(y) == 0 {
+		return 0, nil
+	}
+	if y.Sign() == 0 {
+		return math.Inf(1), nil
+	}
+
+	deviation, _ := new(big.Rat).Abs(new(big.Rat).SetFrac(new(big.Int).Sub(x, y), y)).Float64()
+	return deviation, nil
+}
+
+// scoreWeighted averages the values weighted by the scores of their oracles
+// and rounds toward negative infinity.
+func scoreWeighted(values []Value) (*extractor.Data, error) {
+	parsed, err := numbers(values)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := new(big.Int)
+	total := new(big.Int)
+	for i, v := range values {
+		weight := new(big.Int).SetUint64(v.Weight)
+		total.Add(total, weight)
+		sum.Add(sum, weight.Mul(weight, parsed[i]))
+	}
+	if total.Sign() == 0 {
+		return nil, ErrZeroWeight
+	}
+	sum.Div(sum, total)
+
+	return numberData(sum, values[0].Data), nil
+}
@@ -0,0 +1,177 @@
+package aggregator
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+)
+
+func ints64(weights []uint64, values ...int64) []Value {
+	var result []Value
+	for i, v := range values {
+		value := Value{Data: extractor.Data{Type: extractor.Int64, Value: strconv.FormatInt(v, 10)}}
+		if i < len(weights) {
+			value.Weight = weights[i]
+		}
+		result = append(result, value)
+	}
+
+	return result
+}
+
+func strs(values ...string) []Value {
+	var result []Value
+	for _, v := range values {
+		result = append(result, Value{Data: extractor.Data{Type: extractor.String, Value: v}})
+	}
+
+	return result
+}
+
+func decimals(precision uint8, values ...string) []Value {
+	var result []Value
+	for _, v := range values {
+		result = append(result, Value{Data: extractor.Data{Type: extractor.Decimal, Value: v, Precision: precision}, Weight: 1})
+	}
+
+	return result
+}
+
+func decimalItems(precision uint8, values ...string) []extractor.Data {
+	var result []extractor.Data
+	for _, v := range decimals(precision, values...) {
+		result = append(result, v.Data)
+	}
+
+	return result
+}
+
+func TestAggregators(t *testing.T) {
+	for i, c := range []struct {
+		aggregatorType Type
+		values         []Value
+		expected       string
+		err            error
+	}{
+		{Median, ints64(nil, 5, 1, 3), "3", nil},
+		{Median, ints64(nil, 4, 1, 3, 10), "3", nil},
+		{Median, ints64(nil, -4, -1), "-3", nil},
+		{Median, ints64(nil, 9223372036854775807, 9223372036854775805), "9223372036854775806", nil},
+		{Median, strs("a"), "", ErrUnsupportedType},
+		{Median, nil, "", ErrNoValues},
+		{TrimmedMean, ints64(nil, 100, 1, 2, 3, 4, 5, 6, -100), "3", nil},
+		{TrimmedMean, ints64(nil, 1, 2, 4), "2", nil},
+		{Mode, ints64(nil, 3, 1, 3, 2), "3", nil},
+		{Mode, ints64(nil, 10, 9, 10, 9), "9", nil},
+		{Mode, ints64(nil, -1, -2), "-2", nil},
+		{Mode, strs("b", "a", "c", "a"), "a", nil},
+		{Mode, append(strs("a"), ints64(nil, 1)...), "", ErrUnsupportedType},
+		{ScoreWeighted, ints64([]uint64{1, 3}, 10, 20), "17", nil},
+		{ScoreWeighted, ints64([]uint64{0, 2}, 10, -20), "-20", nil},
+		{ScoreWeighted, ints64([]uint64{0, 0}, 10, 20), "", ErrZeroWeight},
+		{Median, decimals(2, "1.50", "-0.25"), "0.62", nil},
+		{Median, decimals(2, "-1.50", "0.25"), "-0.63", nil},
+		{Median, append(decimals(2, "1.50"), decimals(3, "1.500")...), "", ErrUnsupportedType},
+		{TrimmedMean, decimals(1, "9.9", "1.0", "1.2", "0.1"), "1.1", nil},
+		{ScoreWeighted, decimals(4, "0.0001", "0.0002"), "0.0001", nil},
+		{Mode, decimals(1, "0.5", "0.5", "-0.5"), "0.5", nil},
+		{Mode, []Value{
+			{Data: extractor.Data{Type: extractor.Array, Items: decimalItems(1, "1.0", "2.0")}},
+			{Data: extractor.Data{Type: extractor.Array, Items: decimalItems(1, "1.0", "2.1")}},
+			{Data: extractor.Data{Type: extractor.Array, Items: decimalItems(1, "1.0", "2.1")}},
+		}, "", nil},
+		{Median, []Value{
+			{Data: extractor.Data{Type: extractor.Uint256, Value: "115792089237316195423570985008687907853269984665640564039457584007913129639935"}},
+			{Data: extractor.Data{Type: extractor.Uint256, Value: "115792089237316195423570985008687907853269984665640564039457584007913129639933"}},
+		}, "115792089237316195423570985008687907853269984665640564039457584007913129639934", nil},
+	} {
+		aggregate, ok := Local(c.aggregatorType)
+		if !ok {
+			t.Fatalf("case %d: %s is not local", i, c.aggregatorType)
+		}
+
+		result, err := aggregate(c.values)
+		if err != c.err {
+			t.Errorf("case %d: expected error %v, got %v", i, c.err, err)
+			continue
+		}
+		if err == nil && (result.Value != c.expected || result.Type != c.values[0].Data.Type) {
+			t.Errorf("case %d: expected %s, got %s", i, c.expected, result.Value)
+		}
+		if err == nil && result.Type == extractor.Array && result.Items[1].Value != "2.1" {
+			t.Errorf("case %d: expected the most frequent array, got %+v", i, result.Items)
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	for value, expected := range map[string]Type{"": Remote, "remote": Remote, "trimmed-mean": TrimmedMean} {
+		aggregatorType, err := ParseType(value)
+		if err != nil || aggregatorType != expected {
+			t.Errorf("%q: expected %s, got %s (%v)", value, expected, aggregatorType, err)
+		}
+	}
+	if _, err := ParseType("mean"); err != ErrUnknownType {
+		t.Errorf("expected %v, got %v", ErrUnknownType, err)
+	}
+	if _, ok := Local(Remote); ok {
+		t.Error("remote aggregator is local")
+	}
+}
+
+func TestWeightedMedian(t *testing.T) {
+	for i, c := range []struct {
+		values   []Value
+		expected string
+		err      error
+	}{
+		{ints64([]uint64{1, 1, 1}, 5, 1, 3), "3", nil},
+		{ints64([]uint64{1, 1, 5}, 5, 1, 3), "3", nil},
+		{ints64([]uint64{1, 5, 1}, 5, 1, 3), "1", nil},
+		{ints64([]uint64{1, 1}, 2, 1), "1", nil},
+		{ints64([]uint64{0, 0}, 2, 1), "", ErrZeroWeight},
+		{append(decimals(2, "1.50", "-0.25", "0.10"), Value{Data: extractor.Data{Type: extractor.Decimal, Value: "7.00", Precision: 2}, Weight: 4}), "7.00", nil},
+		{[]Value{
+			{Data: extractor.Data{Type: extractor.String, Value: "a"}, Weight: 1},
+			{Data: extractor.Data{Type: extractor.String, Value: "b"}, Weight: 3},
+			{Data: extractor.Data{Type: extractor.String, Value: "a"}, Weight: 1},
+		}, "b", nil},
+		{nil, "", ErrNoValues},
+	} {
+		result, err := WeightedMedian(c.values)
+		if err != c.err {
+			t.Errorf("case %d: expected error %v, got %v", i, c.err, err)
+			continue
+		}
+		if err == nil && result.Value != c.expected {
+			t.Errorf("case %d: expected %s, got %s", i, c.expected, result.Value)
+		}
+	}
+}
+
+func TestDeviation(t *testing.T) {
+	inf := math.Inf(1)
+	for i, c := range []struct {
+		value, result extractor.Data
+		expected      float64
+	}{
+		{ints64(nil, 105)[0].Data, ints64(nil, 100)[0].Data, 0.05},
+		{ints64(nil, -90)[0].Data, ints64(nil, -100)[0].Data, 0.1},
+		{ints64(nil, 1)[0].Data, ints64(nil, 0)[0].Data, inf},
+		{decimals(2, "0.99")[0].Data, decimals(2, "1.00")[0].Data, 0.01},
+		{decimals(2, "1.00")[0].Data, decimals(1, "1.0")[0].Data, inf},
+		{strs("a")[0].Data, strs("a")[0].Data, 0},
+		{strs("a")[0].Data, strs("b")[0].Data, inf},
+	} {
+		deviation, err := Deviation(c.value, c.result)
+		if err != nil {
+			t.Errorf("case %d: %v", i, err)
+			continue
+		}
+		if deviation != c.expected {
+			t.Errorf("case %d: expected %v, got %v", i, c.expected, deviation)
+		}
+	}
+}
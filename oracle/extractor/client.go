@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"time"
 )
 
 const (
@@ -15,9 +16,13 @@ const (
 	InfoPath      = "info"
 	AggregatePath = "aggregate"
 
-	String DataType = "string"
-	Int64  DataType = "int64"
-	Base64 DataType = "base64"
+	String  DataType = "string"
+	Int64   DataType = "int64"
+	Base64  DataType = "base64"
+	Decimal DataType = "decimal"
+	Uint256 DataType = "uint256"
+	Array   DataType = "array"
+	Record  DataType = "record"
 )
 
 var (
@@ -27,16 +32,69 @@ type DataType string
 type Data struct {
 	Type  DataType
 	Value string
+	// Precision is the number of fraction digits of a Decimal value.
+	Precision uint8 `json:",omitempty"`
+	// Items are the elements of an Array, all of one type.
+	Items []Data `json:",omitempty"`
+	// Fields are the named values of a Record.
+	Fields map[string]Data `json:",omitempty"`
+}
+
+// Source is where an oracle takes its values from, the http Client or a
+// client of another extractor protocol.
+type Source interface {
+	Info(ctx context.Context) (*Info, error)
+	Extract(ctx context.Context) (*Data, error)
+	Aggregate(values []Data, ctx context.Context) (*Data, error)
 }
 
 type Client struct {
 	hostUrl string
+	timeout time.Duration
+	token   string
 }
 
-func New(hostUrl string) *Client {
-	return &Client{
+type Option func(*Client)
+
+// WithTimeout limits every request of the client.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithBearerToken sends token in the Authorization header.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+func New(hostUrl string, opts ...Option) *Client {
+	client := &Client{
 		hostUrl: hostUrl,
 	}
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	return client
+}
+
+// Info returns NotFoundErr for an extractor without the info endpoint.
+func (client *Client) Info(ctx context.Context) (*Info, error) {
+	rs, err := client.do(InfoPath, http.MethodGet, nil, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Info
+	err = json.Unmarshal(rs, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
 }
 
 func (client *Client) Extract(ctx context.Context) (*Data, error) {
@@ -71,6 +129,11 @@ func (client *Client) Aggregate(values []Data, ctx context.Context) (*Data, erro
 
 func (client *Client) do(route string, method string, rqBody interface{}, ctx context.Context) ([]byte, error) {
 	rqUrl := fmt.Sprintf("%v/%v", client.hostUrl, route)
+	if client.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.timeout)
+		defer cancel()
+	}
 
 	var buf *bytes.Buffer
 	var req *http.Request
@@ -91,6 +154,9 @@ func (client *Client) do(route string, method string, rqBody interface{}, ctx co
 			return nil, err
 		}
 	}
+	if client.token != "" {
+		req.Header.Set("Authorization", "Bearer "+client.token)
+	}
 	response, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -100,6 +166,8 @@ func (client *Client) do(route string, method string, rqBody interface{}, ctx co
 
 	if response.StatusCode == 404 {
 		return nil, NotFoundErr
+	} else if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("extractor %s: %s", route, response.Status)
 	}
 
 	rsBody, err := ioutil.ReadAll(response.Body)
@@ -0,0 +1,261 @@
+package extractorgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultTimeout         = 10 * time.Second
+	SubscribeRetryInterval = 5 * time.Second
+)
+
+var (
+	ErrProtocolVersion = errors.New("unsupported extractor protocol version")
+
+	errorLogger = log.New(os.Stdout,
+		"ERROR: ",
+		log.Ldate|log.Ltime|log.Lshortfile)
+)
+
+// Client is an extractor.Source that talks to the extractor over grpc.
+type Client struct {
+	conn      *grpc.ClientConn
+	timeout   time.Duration
+	creds     credentials.TransportCredentials
+	token     string
+	subscribe bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	lock   sync.Mutex
+	latest *extractor.Data
+}
+
+type Option func(*Client) error
+
+// WithTimeout limits the unary calls, DefaultTimeout when it is not set and
+// no limit when it is zero.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) error {
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// WithTransportCredentials secures the connection, it is plain text
+// otherwise.
+func WithTransportCredentials(creds credentials.TransportCredentials) Option {
+	return func(c *Client) error {
+		c.creds = creds
+		return nil
+	}
+}
+
+// WithBearerToken authenticates the calls with token. It needs transport
+// credentials.
+func WithBearerToken(token string) Option {
+	return func(c *Client) error {
+		c.token = token
+		return nil
+	}
+}
+
+// WithSubscription keeps a Subscribe stream open while the client is, and
+// Extract returns the last value pushed by the extractor instead of asking
+// for one. The stream is opened again after SubscribeRetryInterval when it
+// breaks, and Extract asks until it has a value.
+func WithSubscription() Option {
+	return func(c *Client) error {
+		c.subscribe = true
+		return nil
+	}
+}
+
+// Dial returns a client of the extractor at target, host:port. The
+// connection is made in the background, so Dial does not fail for an
+// extractor that is not up yet.
+func Dial(target string, opts ...Option) (*Client, error) {
+	client := &Client{
+		timeout: DefaultTimeout,
+	}
+	for _, opt := range opts {
+		err := opt(client)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var dialOpts []grpc.DialOption
+	if client.creds != nil {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(client.creds))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithInsecure())
+	}
+	if client.token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(bearerToken(client.token)))
+	}
+
+	conn, err := grpc.Dial(target, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	client.conn = conn
+
+	if client.subscribe {
+		ctx, cancel := context.WithCancel(context.Background())
+		client.cancel = cancel
+		client.done = make(chan struct{})
+		go client.watch(ctx)
+	}
+
+	return client, nil
+}
+
+// Close ends the subscription and the connection.
+func (client *Client) Close() error {
+	if client.cancel != nil {
+		client.cancel()
+		<-client.done
+	}
+
+	return client.conn.Close()
+}
+
+// Info is the handshake with the extractor. It fails with
+// ErrProtocolVersion for an extractor of another protocol version.
+func (client *Client) Info(ctx context.Context) (*extractor.Info, error) {
+	var rs infoResponse
+	err := client.invoke(ctx, "Info", &infoRequest{ProtocolVersion: ProtocolVersion}, &rs)
+	if err != nil {
+		return nil, err
+	}
+	if rs.ProtocolVersion != ProtocolVersion {
+		return nil, ErrProtocolVersion
+	}
+
+	return &extractor.Info{
+		Description: rs.Description,
+		DataFeedTag: rs.DataFeedTag,
+		DataType:    extractor.DataType(rs.DataType),
+	}, nil
+}
+
+func (client *Client) Extract(ctx context.Context) (*extractor.Data, error) {
+	if client.subscribe {
+		client.lock.Lock()
+		latest := client.latest
+		client.lock.Unlock()
+
+		if latest != nil {
+			value := *latest
+			return &value, nil
+		}
+	}
+
+	var rs data
+	err := client.invoke(ctx, "Extract", &empty{}, &rs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rs.Data, nil
+}
+
+func (client *Client) Aggregate(values []extractor.Data, ctx context.Context) (*extractor.Data, error) {
+	var rs data
+	err := client.invoke(ctx, "Aggregate", &aggregateRequest{Values: values}, &rs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rs.Data, nil
+}
+
+// Subscribe calls receive with every value the extractor pushes until ctx
+// is done or the stream breaks.
+func (client *Client) Subscribe(receive func(*extractor.Data), ctx context.Context) error {
+	stream, err := client.conn.NewStream(ctx, &serviceDesc.Streams[0], method("Subscribe"))
+	if err != nil {
+		return clientError(err)
+	}
+	if err := stream.SendMsg(&empty{}); err != nil {
+		return clientError(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return clientError(err)
+	}
+
+	for {
+		var rs data
+		err := stream.RecvMsg(&rs)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return clientError(err)
+		}
+
+		receive(&rs.Data)
+	}
+}
+
+func (client *Client) watch(ctx context.Context) {
+	defer close(client.done)
+
+	for {
+		err := client.Subscribe(client.setLatest, ctx)
+		// A value of a broken stream may be stale, Extract asks for a new
+		// one until the stream is back.
+		client.setLatest(nil)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			errorLogger.Print(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(SubscribeRetryInterval):
+		}
+	}
+}
+
+func (client *Client) setLatest(value *extractor.Data) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	client.latest = value
+}
+
+func (client *Client) invoke(ctx context.Context, name string, rq interface{}, rs interface{}) error {
+	if client.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.timeout)
+		defer cancel()
+	}
+
+	return clientError(client.conn.Invoke(ctx, method(name), rq, rs))
+}
+
+// clientError returns extractor.NotFoundErr for NotFound, like the http
+// client does for 404.
+func clientError(err error) error {
+	if status.Code(err) == codes.NotFound {
+		return extractor.NotFoundErr
+	}
+	return err
+}
@@ -0,0 +1,335 @@
+package extractorgrpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testService struct {
+	lock    sync.Mutex
+	value   *extractor.Data
+	updates chan *extractor.Data
+}
+
+func (s *testService) setValue(value *extractor.Data) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.value = value
+}
+
+func (s *testService) Info(ctx context.Context) (*extractor.Info, error) {
+	return &extractor.Info{Description: "test", DataFeedTag: "tag", DataType: extractor.Record}, nil
+}
+
+func (s *testService) Extract(ctx context.Context) (*extractor.Data, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.value == nil {
+		return nil, extractor.NotFoundErr
+	}
+	return s.value, nil
+}
+
+func (s *testService) Aggregate(values []extractor.Data, ctx context.Context) (*extractor.Data, error) {
+	return &values[len(values)-1], nil
+}
+
+func (s *testService) Subscribe(send func(*extractor.Data) error, ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case value := <-s.updates:
+			if err := send(value); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func serve(t *testing.T, desc *grpc.ServiceDesc, service Service, opts ...grpc.ServerOption) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer(opts...)
+	server.RegisterService(desc, service)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func dial(t *testing.T, target string, opts ...Option) *Client {
+	client, err := Dial(target, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+var testRecord = extractor.Data{
+	Type: extractor.Record,
+	Fields: map[string]extractor.Data{
+		"price": {Type: extractor.Decimal, Value: "-1.50", Precision: 2},
+		"ticks": {Type: extractor.Array, Items: []extractor.Data{
+			{Type: extractor.Int64, Value: "1"},
+			{Type: extractor.Int64, Value: "0"},
+		}},
+		"name": {Type: extractor.String},
+	},
+}
+
+func TestMessages(t *testing.T) {
+	b, err := (&aggregateRequest{Values: []extractor.Data{testRecord, {Type: extractor.Uint256, Value: "7"}}}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rq aggregateRequest
+	if err := rq.Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rq.Values, []extractor.Data{testRecord, {Type: extractor.Uint256, Value: "7"}}) {
+		t.Errorf("values: got %+v", rq.Values)
+	}
+
+	// Fields of later versions are skipped.
+	b = appendString(appendUint32(nil, 1, ProtocolVersion), 15, "unknown")
+	var info infoRequest
+	if err := info.Unmarshal(b); err != nil {
+		t.Fatal(err)
+	}
+	if info.ProtocolVersion != ProtocolVersion {
+		t.Errorf("protocol version: expected %d, got %d", ProtocolVersion, info.ProtocolVersion)
+	}
+
+	if err := new(data).Unmarshal(appendUint32(nil, 3, 256)); err != ErrInvalidMessage {
+		t.Errorf("precision: expected %v, got %v", ErrInvalidMessage, err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	service := &testService{updates: make(chan *extractor.Data)}
+	target := serve(t, &serviceDesc, service)
+	client := dial(t, target)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	info, err := client.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *info != (extractor.Info{Description: "test", DataFeedTag: "tag", DataType: extractor.Record}) {
+		t.Errorf("info: got %+v", info)
+	}
+
+	if _, err := client.Extract(ctx); err != extractor.NotFoundErr {
+		t.Errorf("extract without value: expected %v, got %v", extractor.NotFoundErr, err)
+	}
+
+	service.setValue(&testRecord)
+	value, err := client.Extract(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*value, testRecord) {
+		t.Errorf("extract: got %+v", value)
+	}
+
+	value, err = client.Aggregate([]extractor.Data{{Type: extractor.Int64, Value: "1"}, {Type: extractor.Int64, Value: "2"}}, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.Value != "2" {
+		t.Errorf("aggregate: expected 2, got %s", value.Value)
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	service := &testService{
+		value:   &extractor.Data{Type: extractor.Int64, Value: "1"},
+		updates: make(chan *extractor.Data),
+	}
+	target := serve(t, &serviceDesc, service)
+	client := dial(t, target, WithSubscription())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The client asks until the extractor pushes a value.
+	value, err := client.Extract(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.Value != "1" {
+		t.Errorf("extract before push: expected 1, got %s", value.Value)
+	}
+
+	select {
+	case service.updates <- &extractor.Data{Type: extractor.Int64, Value: "2"}:
+	case <-ctx.Done():
+		t.Fatal("no subscription")
+	}
+	for {
+		value, err := client.Extract(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value.Value == "2" {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("pushed value is not extracted")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	desc := serviceDesc
+	desc.Methods = append([]grpc.MethodDesc{{
+		MethodName: "Info",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			return &infoResponse{ProtocolVersion: ProtocolVersion + 1}, dec(new(infoRequest))
+		},
+	}}, serviceDesc.Methods[1:]...)
+	client := dial(t, serve(t, &desc, &testService{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := client.Info(ctx); err != ErrProtocolVersion {
+		t.Errorf("expected %v, got %v", ErrProtocolVersion, err)
+	}
+}
+
+// writeCert writes the certificate and the key of name to dir, signed by
+// parent or self-signed when it is nil.
+func writeCert(t *testing.T, dir string, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(path.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key
+}
+
+func TestAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gravity-extractor-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, caKey := writeCert(t, dir, "ca", nil, nil)
+	writeCert(t, dir, "extractor", ca, caKey)
+	writeCert(t, dir, "oracle", ca, caKey)
+	file := func(name string) string {
+		return path.Join(dir, name)
+	}
+
+	serverCreds, err := ServerTLS(file("extractor.crt"), file("extractor.key"), file("ca.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unary, stream := TokenInterceptors("secret")
+	target := serve(t, &serviceDesc, &testService{}, grpc.Creds(serverCreds), grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	oracleCreds, err := ClientTLS(file("ca.crt"), file("oracle.crt"), file("oracle.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dial(t, target, WithTransportCredentials(oracleCreds), WithBearerToken("secret"))
+	if _, err := client.Info(ctx); err != nil {
+		t.Errorf("authorized: %v", err)
+	}
+
+	client = dial(t, target, WithTransportCredentials(oracleCreds), WithBearerToken("guess"))
+	if _, err := client.Info(ctx); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("invalid token: expected %v, got %v", codes.Unauthenticated, err)
+	}
+
+	// Without a certificate of the oracle the TLS handshake fails.
+	anonymousCreds, err := ClientTLS(file("ca.crt"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = dial(t, target, WithTransportCredentials(anonymousCreds), WithBearerToken("secret"), WithTimeout(time.Second))
+	if _, err := client.Info(ctx); err == nil {
+		t.Error("client without certificate: expected an error")
+	}
+
+	// The token is not sent in plain text.
+	if _, err := Dial(target, WithBearerToken("secret")); err == nil {
+		t.Error("token without TLS: expected an error")
+	}
+}
@@ -0,0 +1,303 @@
+package extractorgrpc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var (
+	ErrInvalidMessage = errors.New("invalid extractor message")
+)
+
+// The messages of extractor.proto. They marshal themselves, so the proto
+// codec of grpc sends them without generated code. TestProtoMessages checks
+// them against the descriptors of extractor.proto.
+
+type infoRequest struct {
+	ProtocolVersion uint32
+}
+
+type infoResponse struct {
+	ProtocolVersion uint32
+	Description     string
+	DataFeedTag     string
+	DataType        string
+}
+
+// empty is ExtractRequest and SubscribeRequest.
+type empty struct{}
+
+type aggregateRequest struct {
+	Values []extractor.Data
+}
+
+type data struct {
+	extractor.Data
+}
+
+func (m *infoRequest) Reset()         { *m = infoRequest{} }
+func (m *infoRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (m *infoRequest) ProtoMessage()  {}
+
+func (m *infoRequest) Marshal() ([]byte, error) {
+	return appendUint32(nil, 1, m.ProtocolVersion), nil
+}
+
+func (m *infoRequest) Unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 {
+			return consumeUint32(typ, b, &m.ProtocolVersion)
+		}
+		return 0, nil
+	})
+}
+
+func (m *infoResponse) Reset()         { *m = infoResponse{} }
+func (m *infoResponse) String() string { return fmt.Sprintf("%+v", *m) }
+func (m *infoResponse) ProtoMessage()  {}
+
+func (m *infoResponse) Marshal() ([]byte, error) {
+	b := appendUint32(nil, 1, m.ProtocolVersion)
+	b = appendString(b, 2, m.Description)
+	b = appendString(b, 3, m.DataFeedTag)
+	b = appendString(b, 4, m.DataType)
+	return b, nil
+}
+
+func (m *infoResponse) Unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeUint32(typ, b, &m.ProtocolVersion)
+		case 2:
+			return consumeString(typ, b, &m.Description)
+		case 3:
+			return consumeString(typ, b, &m.DataFeedTag)
+		case 4:
+			return consumeString(typ, b, &m.DataType)
+		}
+		return 0, nil
+	})
+}
+
+func (m *empty) Reset()         {}
+func (m *empty) String() string { return "{}" }
+func (m *empty) ProtoMessage()  {}
+
+func (m *empty) Marshal() ([]byte, error) {
+	return nil, nil
+}
+
+func (m *empty) Unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		return 0, nil
+	})
+}
+
+func (m *aggregateRequest) Reset()         { *m = aggregateRequest{} }
+func (m *aggregateRequest) String() string { return fmt.Sprintf("%+v", *m) }
+func (m *aggregateRequest) ProtoMessage()  {}
+
+func (m *aggregateRequest) Marshal() ([]byte, error) {
+	var b []byte
+	for i := range m.Values {
+		b = appendMessage(b, 1, appendData(nil, &m.Values[i]))
+	}
+	return b, nil
+}
+
+func (m *aggregateRequest) Unmarshal(b []byte) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num != 1 {
+			return 0, nil
+		}
+		var value extractor.Data
+		n, err := consumeMessage(typ, b, func(b []byte) error {
+			return unmarshalData(b, &value)
+		})
+		if n > 0 {
+			m.Values = append(m.Values, value)
+		}
+		return n, err
+	})
+}
+
+func (m *data) Reset()         { *m = data{} }
+func (m *data) String() string { return fmt.Sprintf("%+v", m.Data) }
+func (m *data) ProtoMessage()  {}
+
+func (m *data) Marshal() ([]byte, error) {
+	return appendData(nil, &m.Data), nil
+}
+
+func (m *data) Unmarshal(b []byte) error {
+	return unmarshalData(b, &m.Data)
+}
+
+// appendData writes the fields of a record sorted by name, so equal data
+// has equal bytes.
+func appendData(b []byte, d *extractor.Data) []byte {
+	b = appendString(b, 1, string(d.Type))
+	b = appendString(b, 2, d.Value)
+	b = appendUint32(b, 3, uint32(d.Precision))
+	for i := range d.Items {
+		b = appendMessage(b, 4, appendData(nil, &d.Items[i]))
+	}
+
+	names := make([]string, 0, len(d.Fields))
+	for name := range d.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := d.Fields[name]
+		entry := appendString(nil, 1, name)
+		entry = appendMessage(entry, 2, appendData(nil, &field))
+		b = appendMessage(b, 5, entry)
+	}
+
+	return b
+}
+
+func unmarshalData(b []byte, d *extractor.Data) error {
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			var dataType string
+			n, err := consumeString(typ, b, &dataType)
+			d.Type = extractor.DataType(dataType)
+			return n, err
+		case 2:
+			return consumeString(typ, b, &d.Value)
+		case 3:
+			var precision uint32
+			n, err := consumeUint32(typ, b, &precision)
+			if precision > math.MaxUint8 {
+				return 0, ErrInvalidMessage
+			}
+			d.Precision = uint8(precision)
+			return n, err
+		case 4:
+			var item extractor.Data
+			n, err := consumeMessage(typ, b, func(b []byte) error {
+				return unmarshalData(b, &item)
+			})
+			if n > 0 {
+				d.Items = append(d.Items, item)
+			}
+			return n, err
+		case 5:
+			var name string
+			var field extractor.Data
+			n, err := consumeMessage(typ, b, func(b []byte) error {
+				return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+					switch num {
+					case 1:
+						return consumeString(typ, b, &name)
+					case 2:
+						return consumeMessage(typ, b, func(b []byte) error {
+							return unmarshalData(b, &field)
+						})
+					}
+					return 0, nil
+				})
+			})
+			if n > 0 {
+				if d.Fields == nil {
+					d.Fields = make(map[string]extractor.Data)
+				}
+				d.Fields[name] = field
+			}
+			return n, err
+		}
+		return 0, nil
+	})
+}
+
+// unmarshalFields calls field for every field of b. It returns the length
+// of the value it consumed, zero for the fields it does not know, which are
+// skipped like unknown fields of protobuf.
+func unmarshalFields(b []byte, field func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, err := field(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+		}
+		b = b[n:]
+	}
+
+	return nil
+}
+
+func appendUint32(b []byte, num protowire.Number, v uint32) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendMessage(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func consumeUint32(typ protowire.Type, b []byte, v *uint32) (int, error) {
+	if typ != protowire.VarintType {
+		return 0, ErrInvalidMessage
+	}
+	x, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	*v = uint32(x)
+	return n, nil
+}
+
+func consumeString(typ protowire.Type, b []byte, v *string) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, ErrInvalidMessage
+	}
+	s, n := protowire.ConsumeString(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	*v = s
+	return n, nil
+}
+
+func consumeMessage(typ protowire.Type, b []byte, unmarshal func(b []byte) error) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, ErrInvalidMessage
+	}
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+	return n, unmarshal(v)
+}
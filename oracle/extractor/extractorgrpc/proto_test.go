@@ -0,0 +1,284 @@
+package extractorgrpc
+
+import (
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protoParser builds the descriptor of a proto3 file from the part of the
+// language extractor.proto uses: a package, options, services and messages
+// of scalar, message, repeated and map fields.
+type protoParser struct {
+	t      *testing.T
+	tokens []string
+	pkg    string
+}
+
+var (
+	protoComment = regexp.MustCompile(`//[^\n]*`)
+	protoToken   = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_.]*|\d+|"[^"]*"|[{}()<>;=,]`)
+
+	protoScalars = map[string]descriptorpb.FieldDescriptorProto_Type{
+		"bool":   descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+		"bytes":  descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		"int64":  descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		"uint32": descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+		"uint64": descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	}
+)
+
+func parseProto(t *testing.T, name string) protoreflect.FileDescriptor {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := &protoParser{
+		t:      t,
+		tokens: protoToken.FindAllString(protoComment.ReplaceAllString(string(b), ""), -1),
+	}
+	file := &descriptorpb.FileDescriptorProto{Name: proto.String(name)}
+	for len(parser.tokens) > 0 {
+		switch token := parser.next(); token {
+		case "syntax":
+			parser.expect("=")
+			file.Syntax = proto.String(strings.Trim(parser.next(), `"`))
+			parser.expect(";")
+		case "package":
+			parser.pkg = parser.next()
+			file.Package = proto.String(parser.pkg)
+			parser.expect(";")
+		case "option":
+			for parser.next() != ";" {
+			}
+		case "service":
+			file.Service = append(file.Service, parser.service())
+		case "message":
+			file.MessageType = append(file.MessageType, parser.message())
+		default:
+			t.Fatalf("%s: unexpected %q", name, token)
+		}
+	}
+
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fd
+}
+
+func (parser *protoParser) next() string {
+	if len(parser.tokens) == 0 {
+		parser.t.Fatal("unexpected end of the proto file")
+	}
+	token := parser.tokens[0]
+	parser.tokens = parser.tokens[1:]
+
+	return token
+}
+
+func (parser *protoParser) expect(expected string) {
+	if token := parser.next(); token != expected {
+		parser.t.Fatalf("expected %q, got %q", expected, token)
+	}
+}
+
+func (parser *protoParser) typeName(name string) string {
+	return "." + parser.pkg + "." + name
+}
+
+func (parser *protoParser) service() *descriptorpb.ServiceDescriptorProto {
+	service := &descriptorpb.ServiceDescriptorProto{Name: proto.String(parser.next())}
+	parser.expect("{")
+	for token := parser.next(); token != "}"; token = parser.next() {
+		if token != "rpc" {
+			parser.t.Fatalf("unexpected %q in service %s", token, service.GetName())
+		}
+
+		method := &descriptorpb.MethodDescriptorProto{Name: proto.String(parser.next())}
+		method.InputType, method.ClientStreaming = parser.rpcType()
+		parser.expect("returns")
+		method.OutputType, method.ServerStreaming = parser.rpcType()
+		parser.expect(";")
+		service.Method = append(service.Method, method)
+	}
+
+	return service
+}
+
+func (parser *protoParser) rpcType() (*string, *bool) {
+	parser.expect("(")
+	token := parser.next()
+	stream := token == "stream"
+	if stream {
+		token = parser.next()
+	}
+	parser.expect(")")
+
+	return proto.String(parser.typeName(token)), proto.Bool(stream)
+}
+
+func (parser *protoParser) message() *descriptorpb.DescriptorProto {
+	message := &descriptorpb.DescriptorProto{Name: proto.String(parser.next())}
+	parser.expect("{")
+	for token := parser.next(); token != "}"; token = parser.next() {
+		field := &descriptorpb.FieldDescriptorProto{
+			Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		switch token {
+		case "repeated":
+			field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			parser.fieldType(field, parser.next())
+		case "map":
+			parser.expect("<")
+			key := &descriptorpb.FieldDescriptorProto{Name: proto.String("key"), Number: proto.Int32(1), Label: field.Label}
+			parser.fieldType(key, parser.next())
+			parser.expect(",")
+			value := &descriptorpb.FieldDescriptorProto{Name: proto.String("value"), Number: proto.Int32(2), Label: field.Label}
+			parser.fieldType(value, parser.next())
+			parser.expect(">")
+
+			// A map is a repeated entry message named after the field.
+			name := parser.tokens[0]
+			entry := &descriptorpb.DescriptorProto{
+				Name:    proto.String(strings.Title(name) + "Entry"),
+				Field:   []*descriptorpb.FieldDescriptorProto{key, value},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			}
+			message.NestedType = append(message.NestedType, entry)
+			field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			field.TypeName = proto.String(parser.typeName(message.GetName() + "." + entry.GetName()))
+		default:
+			parser.fieldType(field, token)
+		}
+
+		field.Name = proto.String(parser.next())
+		parser.expect("=")
+		number, err := strconv.Atoi(parser.next())
+		if err != nil {
+			parser.t.Fatal(err)
+		}
+		field.Number = proto.Int32(int32(number))
+		parser.expect(";")
+		message.Field = append(message.Field, field)
+	}
+
+	return message
+}
+
+func (parser *protoParser) fieldType(field *descriptorpb.FieldDescriptorProto, name string) {
+	if scalar, ok := protoScalars[name]; ok {
+		field.Type = scalar.Enum()
+		return
+	}
+
+	field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	field.TypeName = proto.String(parser.typeName(name))
+}
+
+// protoMessage is a message of messages.go.
+type protoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal(b []byte) error
+}
+
+// TestProtoMessages checks the hand-written messages against extractor.proto:
+// what they marshal decodes with the descriptors of the file to the golden
+// JSON, and what protobuf marshals from the golden JSON decodes to them.
+func TestProtoMessages(t *testing.T) {
+	fd := parseProto(t, "extractor.proto")
+
+	tests := []struct {
+		name    string
+		message protoMessage
+		golden  string
+	}{
+		{"InfoRequest", &infoRequest{ProtocolVersion: ProtocolVersion}, `{"protocolVersion": 1}`},
+		{"InfoResponse", &infoResponse{ProtocolVersion: 1, Description: "test", DataFeedTag: "tag", DataType: "record"},
+			`{"protocolVersion": 1, "description": "test", "dataFeedTag": "tag", "dataType": "record"}`},
+		{"ExtractRequest", &empty{}, `{}`},
+		{"SubscribeRequest", &empty{}, `{}`},
+		{"AggregateRequest", &aggregateRequest{Values: []extractor.Data{{Type: extractor.Int64, Value: "1"}, {Type: extractor.Uint256, Value: "7"}}},
+			`{"values": [{"type": "int64", "value": "1"}, {"type": "uint256", "value": "7"}]}`},
+		{"Data", &data{testRecord}, `{"type": "record", "fields": {
+			"price": {"type": "decimal", "value": "-1.50", "precision": 2},
+			"ticks": {"type": "array", "items": [{"type": "int64", "value": "1"}, {"type": "int64", "value": "0"}]},
+			"name": {"type": "string"}
+		}}`},
+	}
+
+	for _, test := range tests {
+		desc := fd.Messages().ByName(protoreflect.Name(test.name))
+		if desc == nil {
+			t.Fatalf("%s is not in extractor.proto", test.name)
+		}
+		golden := dynamicpb.NewMessage(desc)
+		if err := protojson.Unmarshal([]byte(test.golden), golden); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		b, err := test.message.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := dynamicpb.NewMessage(desc)
+		if err := proto.Unmarshal(b, decoded); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !proto.Equal(decoded, golden) {
+			t.Errorf("%s: expected %v, got %v", test.name, golden, decoded)
+		}
+
+		b, err = proto.Marshal(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unmarshaled := reflect.New(reflect.TypeOf(test.message).Elem()).Interface().(protoMessage)
+		if err := unmarshaled.Unmarshal(b); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(unmarshaled, test.message) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.message, unmarshaled)
+		}
+	}
+
+	// The service of extractor.proto is the one served and called.
+	service := fd.Services().ByName("Extractor")
+	if service == nil || string(service.FullName()) != serviceName {
+		t.Fatalf("service %s is not in extractor.proto", serviceName)
+	}
+	var methods []string
+	for i := 0; i < service.Methods().Len(); i++ {
+		method := service.Methods().Get(i)
+		if method.IsStreamingServer() {
+			methods = append(methods, "stream "+string(method.Name()))
+		} else {
+			methods = append(methods, string(method.Name()))
+		}
+	}
+	var served []string
+	for _, method := range serviceDesc.Methods {
+		served = append(served, method.MethodName)
+	}
+	for _, stream := range serviceDesc.Streams {
+		served = append(served, "stream "+stream.StreamName)
+	}
+	if !reflect.DeepEqual(methods, served) {
+		t.Errorf("methods: extractor.proto has %v, the service %v", methods, served)
+	}
+}
@@ -0,0 +1,135 @@
+package extractorgrpc
+
+import (
+	"context"
+
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ProtocolVersion is the version of extractor.proto, the oracles refuse
+	// extractors of other versions.
+	ProtocolVersion = 1
+
+	serviceName = "gravity.extractor.v1.Extractor"
+)
+
+// Service is an extractor served over grpc. Extract returns
+// extractor.NotFoundErr when there is no value and Subscribe sends the new
+// values until ctx is done or send fails.
+type Service interface {
+	Info(ctx context.Context) (*extractor.Info, error)
+	Extract(ctx context.Context) (*extractor.Data, error)
+	Aggregate(values []extractor.Data, ctx context.Context) (*extractor.Data, error)
+	Subscribe(send func(*extractor.Data) error, ctx context.Context) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*Service)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Info", Handler: infoHandler},
+		{MethodName: "Extract", Handler: extractHandler},
+		{MethodName: "Aggregate", Handler: aggregateHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Subscribe", Handler: subscribeHandler, ServerStreams: true},
+	},
+	Metadata: "extractor.proto",
+}
+
+// Register serves service on server.
+func Register(server *grpc.Server, service Service) {
+	server.RegisterService(&serviceDesc, service)
+}
+
+func method(name string) string {
+	return "/" + serviceName + "/" + name
+}
+
+// statusError passes extractor.NotFoundErr to the client as NotFound.
+func statusError(err error) error {
+	if err == extractor.NotFoundErr {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
+}
+
+func unary(ctx context.Context, name string, rq interface{}, interceptor grpc.UnaryServerInterceptor,
+	handler func(ctx context.Context, rq interface{}) (interface{}, error)) (interface{}, error) {
+	if interceptor == nil {
+		return handler(ctx, rq)
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: method(name)}
+	return interceptor(ctx, rq, info, handler)
+}
+
+func infoHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	rq := new(infoRequest)
+	if err := dec(rq); err != nil {
+		return nil, err
+	}
+
+	return unary(ctx, "Info", rq, interceptor, func(ctx context.Context, rq interface{}) (interface{}, error) {
+		info, err := srv.(Service).Info(ctx)
+		if err != nil {
+			return nil, statusError(err)
+		}
+
+		return &infoResponse{
+			ProtocolVersion: ProtocolVersion,
+			Description:     info.Description,
+			DataFeedTag:     info.DataFeedTag,
+			DataType:        string(info.DataType),
+		}, nil
+	})
+}
+
+func extractHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	rq := new(empty)
+	if err := dec(rq); err != nil {
+		return nil, err
+	}
+
+	return unary(ctx, "Extract", rq, interceptor, func(ctx context.Context, rq interface{}) (interface{}, error) {
+		value, err := srv.(Service).Extract(ctx)
+		if err != nil {
+			return nil, statusError(err)
+		}
+
+		return &data{Data: *value}, nil
+	})
+}
+
+func aggregateHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	rq := new(aggregateRequest)
+	if err := dec(rq); err != nil {
+		return nil, err
+	}
+
+	return unary(ctx, "Aggregate", rq, interceptor, func(ctx context.Context, rq interface{}) (interface{}, error) {
+		value, err := srv.(Service).Aggregate(rq.(*aggregateRequest).Values, ctx)
+		if err != nil {
+			return nil, statusError(err)
+		}
+
+		return &data{Data: *value}, nil
+	})
+}
+
+func subscribeHandler(srv interface{}, stream grpc.ServerStream) error {
+	rq := new(empty)
+	if err := stream.RecvMsg(rq); err != nil {
+		return err
+	}
+
+	err := srv.(Service).Subscribe(func(value *extractor.Data) error {
+		return stream.SendMsg(&data{Data: *value})
+	}, stream.Context())
+
+	return statusError(err)
+}
@@ -3,6 +3,8 @@ package extractor
 type Info struct {
 	Description string `json:"description"`
 	DataFeedTag string `json:"datafeedtag"`
+	// DataType is the type of the values, it is not checked when empty.
+	DataType DataType `json:"datatype,omitempty"`
 }
 
 type DataRs struct {
@@ -0,0 +1,90 @@
+package multisource
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// MetricsSubsystem names the metrics of the extractor sources.
+	MetricsSubsystem = "extractor"
+
+	NebulaLabel = "nebula"
+	SourceLabel = "source"
+)
+
+// Metrics contains the metrics of the sources of every nebula.
+type Metrics struct {
+	// Number of sources the last value was aggregated from.
+	Sources metrics.Gauge
+	// Whether the last query of a source succeeded.
+	SourceUp metrics.Gauge
+	// Whether a source is quarantined.
+	SourceQuarantined metrics.Gauge
+	// Number of failed queries of a source.
+	SourceErrors metrics.Counter
+	// Relative deviation of the last value of a source from the aggregated
+	// one.
+	SourceDeviation metrics.Gauge
+	// Number of values of a source that deviated more than allowed.
+	SourceDisagreements metrics.Counter
+}
+
+// PrometheusMetrics registers the metrics of the sources in the default
+// Prometheus registry under namespace. The metrics of a source are labeled
+// with its nebula and its name.
+func PrometheusMetrics(namespace string) *Metrics {
+	sourceLabels := []string{NebulaLabel, SourceLabel}
+	return &Metrics{
+		Sources: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "sources",
+			Help:      "Number of sources the last value was aggregated from.",
+		}, []string{NebulaLabel}),
+		SourceUp: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "source_up",
+			Help:      "Whether the last query of the source succeeded.",
+		}, sourceLabels),
+		SourceQuarantined: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "source_quarantined",
+			Help:      "Whether the source is quarantined.",
+		}, sourceLabels),
+		SourceErrors: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "source_errors",
+			Help:      "Number of failed queries of the source.",
+		}, sourceLabels),
+		SourceDeviation: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "source_deviation",
+			Help:      "Relative deviation of the last value of the source from the aggregated value.",
+		}, sourceLabels),
+		SourceDisagreements: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "source_disagreements",
+			Help:      "Number of values of the source that deviated more than allowed from the aggregated value.",
+		}, sourceLabels),
+	}
+}
+
+// NopMetrics discards the metrics of sources that do not serve them.
+func NopMetrics() *Metrics {
+	return &Metrics{
+		Sources:             discard.NewGauge(),
+		SourceUp:            discard.NewGauge(),
+		SourceQuarantined:   discard.NewGauge(),
+		SourceErrors:        discard.NewCounter(),
+		SourceDeviation:     discard.NewGauge(),
+		SourceDisagreements: discard.NewCounter(),
+	}
+}
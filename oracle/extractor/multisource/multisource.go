@@ -0,0 +1,411 @@
+package multisource
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/abi"
+	"github.com/Gravity-Tech/gravity-core/oracle/aggregator"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+)
+
+const (
+	DefaultTimeout      = 5 * time.Second
+	DefaultMaxDeviation = 0.05
+	DefaultMaxFailures  = 3
+	DefaultQuarantine   = 10 * time.Minute
+
+	// MinOutlierValues is the number of values it takes to tell an outlier
+	// from the others.
+	MinOutlierValues = 3
+)
+
+var (
+	ErrNoSources      = errors.New("no extractor sources")
+	ErrNoQuorum       = errors.New("too few extractor sources answered")
+	ErrMixedDataTypes = errors.New("extractor sources declare different data types")
+
+	errorLogger = log.New(os.Stdout,
+		"ERROR: ",
+		log.Ldate|log.Ltime|log.Lshortfile)
+)
+
+// Source is an extractor with its weight in the values of a MultiSource. A
+// source of zero weight is only checked against the others, its values do
+// not count toward the quorum.
+type Source struct {
+	Name    string
+	Source  extractor.Source
+	Weight  uint64
+	Timeout time.Duration
+}
+
+type source struct {
+	Source
+	failures         int
+	quarantinedUntil time.Time
+}
+
+// MultiSource is an extractor.Source that queries several extractors in
+// parallel and aggregates their values with aggregator.WeightedMedian. A
+// source that fails maxFailures times in a row, answers with a value that is
+// not of the type of the others or deviates more than maxDeviation from the
+// aggregated value is quarantined: it is not queried until the quarantine
+// is over.
+type MultiSource struct {
+	sources      []*source
+	quorum       int
+	maxDeviation float64
+	maxFailures  int
+	quarantine   time.Duration
+	metrics      *Metrics
+	nebula       string
+	now          func() time.Time
+
+	lock sync.Mutex
+	// dataType is the type the sources declared in Info.
+	dataType extractor.DataType
+}
+
+type Option func(*MultiSource)
+
+// WithQuorum is the number of values a value is aggregated from at least.
+func WithQuorum(quorum int) Option {
+	return func(ms *MultiSource) {
+		ms.quorum = quorum
+	}
+}
+
+// WithMaxDeviation is the relative deviation from the aggregated value that
+// quarantines a source, 0.05 for 5%.
+func WithMaxDeviation(maxDeviation float64) Option {
+	return func(ms *MultiSource) {
+		ms.maxDeviation = maxDeviation
+	}
+}
+
+// WithMaxFailures is the number of failures in a row that quarantines a
+// source.
+func WithMaxFailures(maxFailures int) Option {
+	return func(ms *MultiSource) {
+		ms.maxFailures = maxFailures
+	}
+}
+
+func WithQuarantine(quarantine time.Duration) Option {
+	return func(ms *MultiSource) {
+		ms.quarantine = quarantine
+	}
+}
+
+// WithMetrics reports the sources to metrics, labeled with nebula.
+func WithMetrics(metrics *Metrics, nebula string) Option {
+	return func(ms *MultiSource) {
+		ms.metrics = metrics
+		ms.nebula = nebula
+	}
+}
+
+func New(sources []Source, opts ...Option) (*MultiSource, error) {
+	if len(sources) == 0 {
+		return nil, ErrNoSources
+	}
+
+	ms := &MultiSource{
+		quorum:       1,
+		maxDeviation: DefaultMaxDeviation,
+		maxFailures:  DefaultMaxFailures,
+		quarantine:   DefaultQuarantine,
+		metrics:      NopMetrics(),
+		now:          time.Now,
+	}
+	for _, s := range sources {
+		if s.Timeout == 0 {
+			s.Timeout = DefaultTimeout
+		}
+		ms.sources = append(ms.sources, &source{Source: s})
+	}
+	for _, opt := range opts {
+		opt(ms)
+	}
+
+	return ms, nil
+}
+
+type result struct {
+	data *extractor.Data
+	info *extractor.Info
+	err  error
+}
+
+// query calls every source in parallel, each with its own timeout.
+func (ms *MultiSource) query(sources []*source, call func(s extractor.Source, ctx context.Context) result, ctx context.Context) []result {
+	results := make([]result, len(sources))
+
+	var wg sync.WaitGroup
+	for i, s := range sources {
+		wg.Add(1)
+		go func(i int, s *source) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+			defer cancel()
+			results[i] = call(s.Source.Source, ctx)
+		}(i, s)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Info declares the data type the sources agree on. Sources without info or
+// that fail are left to Extract.
+func (ms *MultiSource) Info(ctx context.Context) (*extractor.Info, error) {
+	results := ms.query(ms.sources, func(s extractor.Source, ctx context.Context) result {
+		info, err := s.Info(ctx)
+		return result{info: info, err: err}
+	}, ctx)
+
+	var names []string
+	info := &extractor.Info{}
+	for i, r := range results {
+		if r.err != nil {
+			continue
+		}
+		names = append(names, ms.sources[i].Name)
+		if r.info.DataType == "" {
+			continue
+		}
+		if info.DataType != "" && info.DataType != r.info.DataType {
+			return nil, ErrMixedDataTypes
+		}
+		info.DataType = r.info.DataType
+	}
+	if len(names) == 0 {
+		return nil, extractor.NotFoundErr
+	}
+	info.Description = "sources " + strings.Join(names, ", ")
+
+	ms.lock.Lock()
+	ms.dataType = info.DataType
+	ms.lock.Unlock()
+
+	return info, nil
+}
+
+func (ms *MultiSource) Extract(ctx context.Context) (*extractor.Data, error) {
+	active := ms.active()
+	results := ms.query(active, func(s extractor.Source, ctx context.Context) result {
+		data, err := s.Extract(ctx)
+		return result{data: data, err: err}
+	}, ctx)
+
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	var values []aggregator.Value
+	var answered []*source
+	notFound := false
+	for i, r := range results {
+		s := active[i]
+		if r.err == nil && r.data == nil {
+			r.err = extractor.NotFoundErr
+		}
+		if r.err == extractor.NotFoundErr {
+			notFound = true
+			ms.succeed(s)
+			continue
+		}
+		if r.err == nil {
+			r.err = validate(r.data)
+		}
+		if r.err != nil {
+			ms.fail(s, r.err)
+			continue
+		}
+
+		ms.succeed(s)
+		values = append(values, aggregator.Value{Data: *r.data, Weight: s.Weight})
+		answered = append(answered, s)
+	}
+
+	values, answered = ms.dropOtherTypes(values, answered)
+	ms.metrics.Sources.With(NebulaLabel, ms.nebula).Set(float64(len(values)))
+	if len(values) == 0 && notFound {
+		return nil, extractor.NotFoundErr
+	}
+	weighted := 0
+	for _, v := range values {
+		if v.Weight != 0 {
+			weighted++
+		}
+	}
+	if weighted < ms.quorum || weighted == 0 {
+		return nil, ErrNoQuorum
+	}
+
+	value, err := aggregator.WeightedMedian(values)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range values {
+		s := answered[i]
+		deviation, err := aggregator.Deviation(v.Data, *value)
+		if err != nil {
+			return nil, err
+		}
+		ms.metrics.SourceDeviation.With(ms.labels(s)...).Set(deviation)
+		if deviation > ms.maxDeviation {
+			ms.metrics.SourceDisagreements.With(ms.labels(s)...).Add(1)
+			if len(values) >= MinOutlierValues {
+				ms.quarantineSource(s, "deviates from the other sources")
+			}
+		}
+	}
+
+	return value, nil
+}
+
+// Aggregate asks the sources in turn until one aggregates the values.
+func (ms *MultiSource) Aggregate(values []extractor.Data, ctx context.Context) (*extractor.Data, error) {
+	err := ErrNoQuorum
+	for _, s := range ms.active() {
+		r := ms.query([]*source{s}, func(s extractor.Source, ctx context.Context) result {
+			value, err := s.Aggregate(values, ctx)
+			return result{data: value, err: err}
+		}, ctx)[0]
+		if r.err == nil {
+			return r.data, nil
+		}
+		err = r.err
+	}
+
+	return nil, err
+}
+
+// Close closes the sources that need it.
+func (ms *MultiSource) Close() error {
+	var result error
+	for _, s := range ms.sources {
+		if closer, ok := s.Source.Source.(io.Closer); ok {
+			err := closer.Close()
+			if err != nil && result == nil {
+				result = err
+			}
+		}
+	}
+
+	return result
+}
+
+// active returns the sources out of quarantine, all of them when every
+// source is quarantined.
+func (ms *MultiSource) active() []*source {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	now := ms.now()
+	var active []*source
+	for _, s := range ms.sources {
+		if now.Before(s.quarantinedUntil) {
+			continue
+		}
+		if !s.quarantinedUntil.IsZero() {
+			s.quarantinedUntil = time.Time{}
+			ms.metrics.SourceQuarantined.With(ms.labels(s)...).Set(0)
+		}
+		active = append(active, s)
+	}
+	if len(active) == 0 {
+		return ms.sources
+	}
+
+	return active
+}
+
+// dropOtherTypes keeps the values of the declared type, and of the
+// precision of the most weight, and quarantines the sources of the others.
+// Without a declared type the type of the most weight is kept.
+func (ms *MultiSource) dropOtherTypes(values []aggregator.Value, answered []*source) ([]aggregator.Value, []*source) {
+	type dataType struct {
+		name      extractor.DataType
+		precision uint8
+	}
+	weights := make(map[dataType]uint64)
+	var best dataType
+	found := false
+	for _, v := range values {
+		t := dataType{v.Data.Type, v.Data.Precision}
+		if ms.dataType != "" && t.name != ms.dataType {
+			weights[t] = 0
+			continue
+		}
+		weights[t] += v.Weight
+		if !found || weights[t] > weights[best] {
+			best, found = t, true
+		}
+	}
+	if len(weights) <= 1 && found {
+		return values, answered
+	}
+
+	var keptValues []aggregator.Value
+	var keptSources []*source
+	for i, v := range values {
+		if (dataType{v.Data.Type, v.Data.Precision}) != best {
+			ms.metrics.SourceDisagreements.With(ms.labels(answered[i])...).Add(1)
+			ms.quarantineSource(answered[i], "answers with a value of another type")
+			continue
+		}
+		keptValues = append(keptValues, v)
+		keptSources = append(keptSources, answered[i])
+	}
+
+	return keptValues, keptSources
+}
+
+func (ms *MultiSource) succeed(s *source) {
+	s.failures = 0
+	ms.metrics.SourceUp.With(ms.labels(s)...).Set(1)
+}
+
+func (ms *MultiSource) fail(s *source, err error) {
+	errorLogger.Printf("source %s: %s", s.Name, err)
+	ms.metrics.SourceUp.With(ms.labels(s)...).Set(0)
+	ms.metrics.SourceErrors.With(ms.labels(s)...).Add(1)
+
+	s.failures++
+	if s.failures >= ms.maxFailures {
+		s.failures = 0
+		ms.quarantineSource(s, "fails")
+	}
+}
+
+func (ms *MultiSource) quarantineSource(s *source, reason string) {
+	errorLogger.Printf("source %s %s, quarantined for %s", s.Name, reason, ms.quarantine)
+	s.quarantinedUntil = ms.now().Add(ms.quarantine)
+	ms.metrics.SourceQuarantined.With(ms.labels(s)...).Set(1)
+}
+
+func (ms *MultiSource) labels(s *source) []string {
+	return []string{NebulaLabel, ms.nebula, SourceLabel, s.Name}
+}
+
+// validate rejects the values that have no canonical encoding, they can not
+// be committed.
+func validate(data *extractor.Data) error {
+	t, err := abi.ExtractorTypeOf(data.Type)
+	if err != nil {
+		return err
+	}
+	_, err = abi.Encode(data, t)
+
+	return err
+}
@@ -0,0 +1,180 @@
+package multisource
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+)
+
+type testSource struct {
+	dataType extractor.DataType
+	value    atomic.Value
+	calls    int32
+}
+
+// set makes the source answer with value, or with the error.
+func (s *testSource) set(value interface{}) {
+	s.value.Store(&value)
+}
+
+func (s *testSource) Info(ctx context.Context) (*extractor.Info, error) {
+	return &extractor.Info{DataType: s.dataType}, nil
+}
+
+func (s *testSource) Extract(ctx context.Context) (*extractor.Data, error) {
+	atomic.AddInt32(&s.calls, 1)
+	switch v := (*s.value.Load().(*interface{})).(type) {
+	case error:
+		return nil, v
+	case string:
+		return &extractor.Data{Type: extractor.Int64, Value: v}, nil
+	default:
+		data := v.(extractor.Data)
+		return &data, nil
+	}
+}
+
+func (s *testSource) Aggregate(values []extractor.Data, ctx context.Context) (*extractor.Data, error) {
+	return s.Extract(ctx)
+}
+
+func newTestMultiSource(t *testing.T, weights []uint64, opts ...Option) (*MultiSource, []*testSource, *time.Time) {
+	var sources []Source
+	var testSources []*testSource
+	for i, weight := range weights {
+		s := &testSource{dataType: extractor.Int64}
+		s.set("0")
+		testSources = append(testSources, s)
+		sources = append(sources, Source{Name: string(rune('a' + i)), Source: s, Weight: weight})
+	}
+
+	ms, err := New(sources, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	ms.now = func() time.Time { return now }
+
+	return ms, testSources, &now
+}
+
+func extract(t *testing.T, ms *MultiSource) string {
+	value, err := ms.Extract(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return value.Value
+}
+
+func TestOutliers(t *testing.T) {
+	ms, sources, now := newTestMultiSource(t, []uint64{1, 1, 1, 2})
+	sources[0].set("100")
+	sources[1].set("101")
+	sources[2].set("103")
+	sources[3].set("1000")
+
+	if value := extract(t, ms); value != "103" {
+		t.Errorf("weighted median: expected 103, got %s", value)
+	}
+
+	// The outlier is not asked during its quarantine.
+	sources[3].set("104")
+	if value := extract(t, ms); value != "101" {
+		t.Errorf("without the outlier: expected 101, got %s", value)
+	}
+	if sources[3].calls != 1 {
+		t.Errorf("quarantined source: expected 1 call, got %d", sources[3].calls)
+	}
+
+	*now = now.Add(DefaultQuarantine)
+	if value := extract(t, ms); value != "103" {
+		t.Errorf("after the quarantine: expected 103, got %s", value)
+	}
+}
+
+func TestZeroWeight(t *testing.T) {
+	ms, sources, _ := newTestMultiSource(t, []uint64{1, 1, 0})
+	sources[0].set("100")
+	sources[1].set("101")
+	sources[2].set("1000")
+
+	// The source of zero weight does not move the value, but is quarantined
+	// when it deviates from it.
+	if value := extract(t, ms); value != "100" {
+		t.Errorf("weighted median: expected 100, got %s", value)
+	}
+	extract(t, ms)
+	if sources[2].calls != 1 {
+		t.Errorf("deviating source: expected 1 call, got %d", sources[2].calls)
+	}
+
+	ms, sources, _ = newTestMultiSource(t, []uint64{1, 0}, WithQuorum(2))
+	if _, err := ms.Extract(context.Background()); err != ErrNoQuorum {
+		t.Errorf("quorum of one weighted source: expected %v, got %v", ErrNoQuorum, err)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	ms, sources, _ := newTestMultiSource(t, []uint64{1, 1, 1}, WithQuorum(2), WithMaxFailures(2))
+	sources[0].set(errors.New("down"))
+	sources[1].set(extractor.Data{Type: extractor.String, Value: "garbage"})
+	sources[2].set("7")
+	if _, err := ms.Info(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The value of another type than the declared one is dropped and its
+	// source quarantined, so one value is left.
+	if _, err := ms.Extract(context.Background()); err != ErrNoQuorum {
+		t.Errorf("expected %v, got %v", ErrNoQuorum, err)
+	}
+	if _, err := ms.Extract(context.Background()); err != ErrNoQuorum {
+		t.Errorf("expected %v, got %v", ErrNoQuorum, err)
+	}
+	if sources[1].calls != 1 {
+		t.Errorf("source of another type: expected 1 call, got %d", sources[1].calls)
+	}
+
+	// The failing source is quarantined after two failures, and with every
+	// source but one quarantined the quorum is not reached.
+	if _, err := ms.Extract(context.Background()); err != ErrNoQuorum {
+		t.Errorf("expected %v, got %v", ErrNoQuorum, err)
+	}
+	if sources[0].calls != 2 {
+		t.Errorf("failing source: expected 2 calls, got %d", sources[0].calls)
+	}
+
+	ms, sources, _ = newTestMultiSource(t, []uint64{1, 1})
+	sources[0].set(extractor.NotFoundErr)
+	sources[1].set(extractor.NotFoundErr)
+	if _, err := ms.Extract(context.Background()); err != extractor.NotFoundErr {
+		t.Errorf("expected %v, got %v", extractor.NotFoundErr, err)
+	}
+
+	sources[0].set(extractor.Data{Type: extractor.Int64, Value: "not a number"})
+	sources[1].set("5")
+	if value := extract(t, ms); value != "5" {
+		t.Errorf("expected 5, got %s", value)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	ms, sources, _ := newTestMultiSource(t, []uint64{1, 1})
+	info, err := ms.Info(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DataType != extractor.Int64 {
+		t.Errorf("expected %s, got %s", extractor.Int64, info.DataType)
+	}
+
+	sources[1].dataType = extractor.String
+	if _, err := ms.Info(context.Background()); err != ErrMixedDataTypes {
+		t.Errorf("expected %v, got %v", ErrMixedDataTypes, err)
+	}
+}
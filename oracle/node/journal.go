@@ -0,0 +1,163 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"github.com/dgraph-io/badger"
+)
+
+const (
+	JournalRoundKey = "round"
+)
+
+var (
+	ErrRoundNotFound = errors.New("round state is not found")
+)
+
+// Journal keeps the round states of the oracle on disk, so a restarted node
+// still reveals and sends the pulses it committed to.
+type Journal struct {
+	db *badger.DB
+}
+
+type roundRecord struct {
+	IntervalId  uint64
+	Data        *extractor.Data
+	CommitHash  []byte
+	Salt        []byte
+	CommitSent  bool
+	RevealExist bool
+	ResultValue *extractor.Data
+	ResultHash  []byte
+	IsSent      bool
+	ExtractedAt time.Time
+	InBftSet    bool
+}
+
+func OpenJournal(dir string) (*Journal, error) {
+	db, err := badger.Open(badger.DefaultOptions(dir).WithTruncate(true).WithLogger(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Journal{db: db}, nil
+}
+
+func (journal *Journal) Close() error {
+	return journal.db.Close()
+}
+
+func roundPrefix(nebulaId account.NebulaId) []byte {
+	return []byte(fmt.Sprintf("%s_%x_", JournalRoundKey, nebulaId[:]))
+}
+
+// roundKey pads the pulse id, so the rounds of a nebula are iterated in the
+// order of their pulses.
+func roundKey(nebulaId account.NebulaId, pulseId uint64) []byte {
+	return append(roundPrefix(nebulaId), fmt.Sprintf("%020d", pulseId)...)
+}
+
+// Save journals the state of the round of the pulse in the interval.
+func (journal *Journal) Save(nebulaId account.NebulaId, pulseId uint64, intervalId uint64, roundState *RoundState) error {
+	b, err := json.Marshal(&roundRecord{
+		IntervalId:  intervalId,
+		Data:        roundState.data,
+		CommitHash:  roundState.commitHash,
+		Salt:        roundState.salt,
+		CommitSent:  roundState.commitSent,
+		RevealExist: roundState.RevealExist,
+		ResultValue: roundState.resultValue,
+		ResultHash:  roundState.resultHash,
+		IsSent:      roundState.isSent,
+		ExtractedAt: roundState.extractedAt,
+		InBftSet:    roundState.inBftSet,
+	})
+	if err != nil {
+		return err
+	}
+
+	return journal.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(roundKey(nebulaId, pulseId), b)
+	})
+}
+
+// Load returns the state journaled for the round of the pulse in the interval.
+func (journal *Journal) Load(nebulaId account.NebulaId, pulseId uint64, intervalId uint64) (*RoundState, error) {
+	var record roundRecord
+	err := journal.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(roundKey(nebulaId, pulseId))
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(value []byte) error {
+			return json.Unmarshal(value, &record)
+		})
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrRoundNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if record.IntervalId != intervalId {
+		return nil, ErrRoundNotFound
+	}
+
+	return &RoundState{
+		data:        record.Data,
+		commitHash:  record.CommitHash,
+		salt:        record.Salt,
+		commitSent:  record.CommitSent,
+		resultValue: record.ResultValue,
+		resultHash:  record.ResultHash,
+		isSent:      record.IsSent,
+		RevealExist: record.RevealExist,
+		extractedAt: record.ExtractedAt,
+		inBftSet:    record.InBftSet,
+	}, nil
+}
+
+// Prune drops the rounds of the pulses of the nebula before pulseId.
+func (journal *Journal) Prune(nebulaId account.NebulaId, pulseId uint64) error {
+	prefix := roundPrefix(nebulaId)
+	last := roundKey(nebulaId, pulseId)
+
+	var keys [][]byte
+	err := journal.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			key := it.Item().KeyCopy(nil)
+			if bytes.Compare(key, last) >= 0 {
+				break
+			}
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return journal.db.Update(func(txn *badger.Txn) error {
+		for _, key := range keys {
+			err := txn.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
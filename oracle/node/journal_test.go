@@ -0,0 +1,211 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/abi"
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/common/state"
+	"github.com/Gravity-Tech/gravity-core/common/transactions"
+	"github.com/Gravity-Tech/gravity-core/ledger/query"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	"github.com/tendermint/tendermint/p2p"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+func openTestJournal(t *testing.T, dir string) *Journal {
+	journal, err := OpenJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return journal
+}
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gravity-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	nebulaId := account.BytesToNebulaId([]byte("nebula"))
+	otherNebulaId := account.BytesToNebulaId([]byte("other nebula"))
+	roundState := &RoundState{
+		data:        &extractor.Data{Type: extractor.Int64, Value: "42"},
+		commitHash:  []byte("commit"),
+		salt:        []byte("salt"),
+		commitSent:  true,
+		RevealExist: true,
+		inBftSet:    true,
+	}
+
+	journal := openTestJournal(t, dir)
+	for pulseId := uint64(1); pulseId <= 3; pulseId++ {
+		if err := journal.Save(nebulaId, pulseId, 7, roundState); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := journal.Save(otherNebulaId, 1, 7, roundState); err != nil {
+		t.Fatal(err)
+	}
+	journal.Close()
+
+	// The rounds survive a restart.
+	journal = openTestJournal(t, dir)
+	defer journal.Close()
+
+	loaded, err := journal.Load(nebulaId, 2, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, roundState) {
+		t.Errorf("round state: expected %+v, got %+v", roundState, loaded)
+	}
+
+	if _, err := journal.Load(nebulaId, 2, 8); err != ErrRoundNotFound {
+		t.Errorf("round of another interval: expected %v, got %v", ErrRoundNotFound, err)
+	}
+
+	if err := journal.Prune(nebulaId, 3); err != nil {
+		t.Fatal(err)
+	}
+	for pulseId, exists := range map[uint64]bool{1: false, 2: false, 3: true} {
+		_, err := journal.Load(nebulaId, pulseId, 7)
+		if exists && err != nil {
+			t.Errorf("pulse %d: %v", pulseId, err)
+		} else if !exists && err != ErrRoundNotFound {
+			t.Errorf("pulse %d: expected %v, got %v", pulseId, ErrRoundNotFound, err)
+		}
+	}
+	if _, err := journal.Load(otherNebulaId, 1, 7); err != nil {
+		t.Errorf("other nebula: %v", err)
+	}
+}
+
+// roundRPC is a ledger that keeps the commit and the reveal sent to it.
+type roundRPC struct {
+	gravity.RPCClient
+	commits []*transactions.CommitArgs
+	reveals []*transactions.RevealArgs
+}
+
+func (rpc *roundRPC) Status(ctx context.Context) (*ctypes.ResultStatus, error) {
+	return &ctypes.ResultStatus{NodeInfo: p2p.DefaultNodeInfo{Network: "test"}}, nil
+}
+
+func (rpc *roundRPC) ABCIQueryWithOptions(ctx context.Context, path string, data tmbytes.HexBytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	rs := &ctypes.ResultABCIQuery{}
+	if query.Path(path) == query.CommitHashPath && len(rpc.commits) != 0 {
+		rs.Response.Value = rpc.commits[0].Commit
+	} else {
+		rs.Response.Code = gravity.NotFoundCode
+	}
+
+	return rs, nil
+}
+
+func (rpc *roundRPC) BroadcastTxSync(ctx context.Context, b types.Tx) (*ctypes.ResultBroadcastTx, error) {
+	tx, err := transactions.Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch args := tx.Payload.(type) {
+	case *transactions.CommitArgs:
+		rpc.commits = append(rpc.commits, args)
+	case *transactions.RevealArgs:
+		rpc.reveals = append(rpc.reveals, args)
+	}
+
+	return &ctypes.ResultBroadcastTx{Hash: b.Hash()}, nil
+}
+
+// Tx commits every transaction at once.
+func (rpc *roundRPC) Tx(ctx context.Context, hash []byte, prove bool) (*ctypes.ResultTx, error) {
+	return &ctypes.ResultTx{Hash: hash}, nil
+}
+
+// countingSource extracts a new value on every call.
+type countingSource struct {
+	extractor.Source
+	count int
+}
+
+func (source *countingSource) Extract(ctx context.Context) (*extractor.Data, error) {
+	source.count++
+	return &extractor.Data{Type: extractor.Int64, Value: strconv.Itoa(source.count)}, nil
+}
+
+func TestRestartAfterCommit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gravity-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rpc := &roundRPC{}
+	ghClient, err := gravity.NewWithRPC(rpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &countingSource{}
+	newNode := func(journal *Journal) *Node {
+		return &Node{
+			nebulaId:      account.BytesToNebulaId([]byte("nebula")),
+			chainType:     account.Ethereum,
+			validator:     NewValidator(ed25519.GenPrivKey()),
+			gravityClient: ghClient,
+			extractor:     &Extractor{Source: source, ExtractorType: abi.Int64Type},
+			Journal:       journal,
+			Metrics:       NopMetrics(),
+		}
+	}
+	ctx := context.Background()
+	const pulseId, intervalId = 1, 7
+	commitHeight := uint64(state.CommitSubRound) + state.SubRoundCount
+	revealHeight := uint64(state.RevealSubRound) + state.SubRoundCount
+
+	// The node stops after the commit is broadcast and before its round is
+	// saved.
+	journal := openTestJournal(t, dir)
+	node := newNode(journal)
+	err = node.execute(pulseId, commitHeight, 0, intervalId, node.loadRound(pulseId, intervalId), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	journal.Close()
+
+	journal = openTestJournal(t, dir)
+	defer journal.Close()
+	node = newNode(journal)
+	roundState := node.loadRound(pulseId, intervalId)
+	for _, height := range []uint64{commitHeight, revealHeight} {
+		err := node.execute(pulseId, height, 0, intervalId, roundState, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(rpc.commits) != 1 || source.count != 1 {
+		t.Fatalf("restarted node committed again: %d commits of %d values", len(rpc.commits), source.count)
+	}
+	if len(rpc.reveals) != 1 {
+		t.Fatalf("reveals: expected 1, got %d", len(rpc.reveals))
+	}
+	reveal := rpc.reveals[0]
+	if !bytes.Equal(transactions.CommitHash(reveal.Salt, reveal.Reveal), rpc.commits[0].Commit) {
+		t.Error("reveal does not match the commit")
+	}
+}
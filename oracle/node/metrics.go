@@ -0,0 +1,133 @@
+package node
+
+import (
+	"github.com/Gravity-Tech/gravity-core/common/state"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// MetricsSubsystem names the metrics of the nebula oracles.
+	MetricsSubsystem = "oracle"
+
+	NebulaLabel   = "nebula"
+	SubRoundLabel = "subround"
+	StatusLabel   = "status"
+
+	// The subrounds of the txs: the commit, reveal and result txs of the
+	// ledger and the pulse tx of the target chain.
+	CommitSubRound = "commit"
+	RevealSubRound = "reveal"
+	ResultSubRound = "result"
+	PulseSubRound  = "pulse"
+
+	SuccessStatus = "success"
+	FailureStatus = "failure"
+)
+
+// Metrics are the txs of the rounds of a nebula and its pulses.
+type Metrics struct {
+	// Number of txs sent by subround and status.
+	Txs metrics.Counter
+	// Time from the extraction of a value to its pulse on the target chain.
+	PulseLatency metrics.Histogram
+	// Time of the extractor calls.
+	ExtractorLatency metrics.Histogram
+	// Whether the oracle is in the BFT set of the nebula.
+	InBftSet metrics.Gauge
+	// Pulses missed in a row.
+	MissedPulses metrics.Gauge
+	// Subrounds of the ledger heights the oracle skipped.
+	SkippedSubRounds metrics.Counter
+	// Gas used and fee paid by the pulse txs on the target chain.
+	TxGas metrics.Counter
+	TxFee metrics.Counter
+}
+
+// PrometheusMetrics registers the metrics of the oracles in the default
+// Prometheus registry under namespace. They are labeled with the nebula.
+func PrometheusMetrics(namespace string) *Metrics {
+	labels := []string{NebulaLabel}
+	return &Metrics{
+		Txs: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "txs",
+			Help:      "Number of txs sent by the oracle by subround and status.",
+		}, []string{NebulaLabel, SubRoundLabel, StatusLabel}),
+		PulseLatency: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "pulse_latency_seconds",
+			Help:      "Time from the extraction of a value to its pulse on the target chain.",
+			Buckets:   stdprometheus.ExponentialBuckets(1, 2, 10),
+		}, labels),
+		ExtractorLatency: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "extractor_latency_seconds",
+			Help:      "Time of the extractor calls.",
+			Buckets:   stdprometheus.DefBuckets,
+		}, labels),
+		InBftSet: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "in_bft_set",
+			Help:      "Whether the oracle is in the BFT set of the nebula (1 if yes, 0 if no).",
+		}, labels),
+		MissedPulses: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "missed_pulses",
+			Help:      "Pulses the oracle missed in a row.",
+		}, labels),
+		SkippedSubRounds: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "skipped_subrounds",
+			Help:      "Subrounds of the ledger heights the oracle skipped by subround.",
+		}, []string{NebulaLabel, SubRoundLabel}),
+		TxGas: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "tx_gas",
+			Help:      "Gas used by the pulse txs on the target chain.",
+		}, labels),
+		TxFee: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "tx_fee",
+			Help:      "Fee paid by the pulse txs in the smallest unit of the target chain.",
+		}, labels),
+	}
+}
+
+// subRoundLabel is the label of the txs of a ledger subround.
+func subRoundLabel(subRound state.SubRound) string {
+	switch subRound {
+	case state.CommitSubRound:
+		return CommitSubRound
+	case state.RevealSubRound:
+		return RevealSubRound
+	case state.ResultSubRound:
+		return ResultSubRound
+	default:
+		return PulseSubRound
+	}
+}
+
+// NopMetrics discards the metrics of a node that does not serve them.
+func NopMetrics() *Metrics {
+	return &Metrics{
+		Txs:              discard.NewCounter(),
+		PulseLatency:     discard.NewHistogram(),
+		ExtractorLatency: discard.NewHistogram(),
+		InBftSet:         discard.NewGauge(),
+		MissedPulses:     discard.NewGauge(),
+		SkippedSubRounds: discard.NewCounter(),
+		TxGas:            discard.NewCounter(),
+		TxFee:            discard.NewCounter(),
+	}
+}
@@ -1,12 +1,25 @@
 package node
 
-import "github.com/Gravity-Tech/gravity-core/oracle/extractor"
+import (
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+)
 
 type RoundState struct {
-	data        *extractor.Data
-	commitHash  []byte
-	resultValue  *extractor.Data
+	data       *extractor.Data
+	commitHash []byte
+	salt       []byte
+	// commitSent is set once the commit tx is sent. The commit is
+	// journaled before.
+	commitSent  bool
+	resultValue *extractor.Data
 	resultHash  []byte
 	isSent      bool
+	// pulseTxId is the pulse tx that is being confirmed.
+	pulseTxId   string
 	RevealExist bool
+	extractedAt time.Time
+	// inBftSet is set when the oracle was in the BFT set during the round.
+	inBftSet bool
 }
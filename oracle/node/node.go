@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Gravity-Tech/gravity-core/abi"
+	"math/big"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -26,25 +28,32 @@ import (
 
 const (
 	TimeoutMs = 1000
+
+	DefaultMaxMissedPulses = 3
 )
 
 var (
+	ErrExtractorDataType      = errors.New("extractor data type does not match the nebula value type")
+	ErrLedgerUnreachable      = errors.New("ledger is unreachable")
+	ErrTargetChainUnreachable = errors.New("target chain is unreachable")
+	ErrMissedPulses           = errors.New("oracle missed too many pulses in a row")
+
 	errorLogger = log.New(os.Stdout,
 		"ERROR: ",
 		log.Ldate|log.Ltime|log.Lshortfile)
 )
 
 type Validator struct {
-	privKey tendermintCrypto.PrivKeyEd25519
+	privKey tendermintCrypto.PrivKey
 	pubKey  account.ConsulPubKey
 }
 
 func NewValidator(privKey []byte) *Validator {
-	validatorPrivKey := tendermintCrypto.PrivKeyEd25519{}
-	copy(validatorPrivKey[:], privKey)
+	validatorPrivKey := make(tendermintCrypto.PrivKey, tendermintCrypto.PrivateKeySize)
+	copy(validatorPrivKey, privKey)
 
 	var ghPubKey account.ConsulPubKey
-	copy(ghPubKey[:], validatorPrivKey.PubKey().Bytes()[5:])
+	copy(ghPubKey[:], validatorPrivKey.PubKey().Bytes())
 
 	return &Validator{
 		privKey: validatorPrivKey,
@@ -53,11 +62,15 @@ func NewValidator(privKey []byte) *Validator {
 }
 
 type Extractor struct {
-	*extractor.Client
+	extractor.Source
 	ExtractorType abi.ExtractorType
 }
 
 type Node struct {
+	// missedPulses is accessed atomically, it comes first to be aligned on
+	// 32-bit platforms.
+	missedPulses uint64
+
 	nebulaId  account.NebulaId
 	chainType account.ChainType
 
@@ -65,61 +78,119 @@ type Node struct {
 	oraclePubKey  account.OraclesPubKey
 	gravityClient *gravity.Client
 
-	adaptor   adaptors.IBlockchainAdaptor
-	extractor *Extractor
-	blocksInterval uint64
+	adaptor              adaptors.IBlockchainAdaptor
+	extractor            *Extractor
+	blocksInterval       uint64
 	MaxPulseCountInBlock uint64
+	// Precision is the precision of the Decimal values of the nebula.
+	Precision    uint8
+	PollInterval time.Duration
+	// Journal keeps the round states across restarts. They are kept in
+	// memory only when it is nil.
+	Journal *Journal
+	// Metrics are NopMetrics unless they are replaced before Start.
+	Metrics *Metrics
+	// MaxMissedPulses is the number of pulses missed in a row that fails
+	// Health, 0 to ignore the missed pulses.
+	MaxMissedPulses uint64
+
+	// pulseTxs reports the confirmed pulse txs to the loop of Start.
+	pulseTxs chan pulseTx
+}
+
+// pulseTx is the outcome of the pulse tx of a round.
+type pulseTx struct {
+	pulseId    uint64
+	intervalId uint64
+	err        error
 }
 
 func New(nebulaId account.NebulaId, chainType account.ChainType,
-	chainId byte, oracleSecretKey []byte, validator *Validator,
+	chainId string, oracleSecretKey []byte, validator *Validator,
 	extractorUrl string, gravityNodeUrl string, blocksInterval uint64,
-	targetChainNodeUrl string, ctx context.Context) (*Node, error) {
+	targetChainNodeUrl string, gasPrice string, gasPriceMultiplier int64,
+	ctx context.Context, ghClientOpts ...gravity.Option) (*Node, error) {
 
-	ghClient, err := gravity.New(gravityNodeUrl)
+	ghClient, err := gravity.New(gravityNodeUrl, ghClientOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	var adaptor adaptors.IBlockchainAdaptor
-	switch chainType {
-	case account.Binance:
-		adaptor, err = adaptors.NewBinanceAdaptor(oracleSecretKey, targetChainNodeUrl, ctx, adaptors.BinanceAdapterWithGhClient(ghClient))
-		if err != nil {
-			return nil, err
-		}
-	case account.Ethereum:
-		adaptor, err = adaptors.NewEthereumAdaptor(oracleSecretKey, targetChainNodeUrl, ctx, adaptors.EthAdapterWithGhClient(ghClient))
-		if err != nil {
-			return nil, err
-		}
-	case account.Waves:
-		adaptor, err = adaptors.NewWavesAdapter(oracleSecretKey, targetChainNodeUrl, chainId, adaptors.WavesAdapterWithGhClient(ghClient))
-		if err != nil {
-			return nil, err
-		}
+	adaptor, err := adaptors.New(adaptors.Config{
+		ChainType:          chainType,
+		PrivKey:            oracleSecretKey,
+		NodeUrl:            targetChainNodeUrl,
+		ChainId:            chainId,
+		GasPrice:           gasPrice,
+		GasPriceMultiplier: gasPriceMultiplier,
+		GhClient:           ghClient,
+	}, ctx)
+	if err != nil {
+		return nil, err
 	}
 
+	return NewWithAdaptor(nebulaId, chainType, adaptor, ghClient, validator, extractor.New(extractorUrl), blocksInterval, ctx)
+}
+
+// NewWithAdaptor returns a node that sends pulses through a prebuilt target
+// chain adaptor and gravity client. The extractor has to serve values of the
+// type of the nebula.
+func NewWithAdaptor(nebulaId account.NebulaId, chainType account.ChainType,
+	adaptor adaptors.IBlockchainAdaptor, ghClient *gravity.Client, validator *Validator,
+	extractorSource extractor.Source, blocksInterval uint64, ctx context.Context) (*Node, error) {
+
 	exType, err := adaptor.ValueType(nebulaId, ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	err = handshake(extractorSource, exType, ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Node{
 		validator: validator,
 		nebulaId:  nebulaId,
 		extractor: &Extractor{
 			ExtractorType: exType,
-			Client:        extractor.New(extractorUrl),
+			Source:        extractorSource,
 		},
-		chainType:     chainType,
-		adaptor:       adaptor,
-		gravityClient: ghClient,
-		oraclePubKey:  adaptor.PubKey(),
-		blocksInterval: blocksInterval,
+		chainType:       chainType,
+		adaptor:         adaptor,
+		gravityClient:   ghClient,
+		oraclePubKey:    adaptor.PubKey(),
+		blocksInterval:  blocksInterval,
+		PollInterval:    time.Duration(TimeoutMs) * time.Millisecond,
+		Metrics:         NopMetrics(),
+		MaxMissedPulses: DefaultMaxMissedPulses,
 	}, nil
 }
 
+// handshake checks the data type the extractor declares in its info. An
+// extractor without info or without a declared type is trusted.
+func handshake(source extractor.Source, exType abi.ExtractorType, ctx context.Context) error {
+	info, err := source.Info(ctx)
+	if err == extractor.NotFoundErr {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.DataType == "" {
+		return nil
+	}
+
+	dataType, err := exType.DataType()
+	if err != nil {
+		return err
+	}
+	if info.DataType != dataType {
+		return fmt.Errorf("%w: %s, nebula %s", ErrExtractorDataType, info.DataType, dataType)
+	}
+
+	return nil
+}
+
 func (node *Node) Init() error {
 	oraclesByValidator, err := node.gravityClient.OraclesByValidator(node.validator.pubKey)
 	if err != nil {
@@ -128,26 +199,17 @@ func (node *Node) Init() error {
 
 	oracle, ok := oraclesByValidator[node.chainType]
 	if !ok || oracle != node.oraclePubKey {
-		tx, err := transactions.New(node.validator.pubKey, transactions.AddOracle, node.validator.privKey)
-		if err != nil {
-			return err
-		}
-
-		tx.AddValues([]transactions.Value{
-			transactions.BytesValue{
-				Value: []byte{byte(node.chainType)},
-			},
-			transactions.BytesValue{
-				Value: node.oraclePubKey[:],
-			},
+		tx := transactions.New(node.validator.pubKey, &transactions.AddOracleArgs{
+			ChainType:    node.chainType,
+			OraclePubKey: node.oraclePubKey,
 		})
-		err = node.gravityClient.SendTx(tx)
+
+		err = node.gravityClient.SendTx(tx, node.validator.privKey)
 		if err != nil {
 			return err
 		}
 
 		fmt.Printf("Add oracle (TXID): %s\n", hexutil.Encode(tx.Id[:]))
-		time.Sleep(time.Duration(5) * time.Second)
 	}
 
 	oraclesByNebulaKey, err := node.gravityClient.OraclesByNebula(node.nebulaId, node.chainType)
@@ -157,27 +219,17 @@ func (node *Node) Init() error {
 
 	_, ok = oraclesByNebulaKey[node.oraclePubKey.ToString(node.chainType)]
 	if !ok {
-		tx, err := transactions.New(node.validator.pubKey, transactions.AddOracleInNebula, node.validator.privKey)
-		if err != nil {
-			return err
-		}
-
-		tx.AddValues([]transactions.Value{
-			transactions.BytesValue{
-				Value: node.nebulaId[:],
-			},
-			transactions.BytesValue{
-				Value: node.oraclePubKey[:],
-			},
+		tx := transactions.New(node.validator.pubKey, &transactions.AddOracleInNebulaArgs{
+			NebulaId:     node.nebulaId,
+			OraclePubKey: node.oraclePubKey,
 		})
 
-		err = node.gravityClient.SendTx(tx)
+		err = node.gravityClient.SendTx(tx, node.validator.privKey)
 		if err != nil {
 			return err
 		}
 
 		fmt.Printf("Add oracle in nebula (TXID): %s\n", hexutil.Encode(tx.Id[:]))
-		time.Sleep(time.Duration(5) * time.Second)
 	}
 
 	nebulaInfo, err := node.gravityClient.NebulaInfo(node.nebulaId, node.chainType)
@@ -188,46 +240,115 @@ func (node *Node) Init() error {
 	}
 
 	node.MaxPulseCountInBlock = nebulaInfo.MaxPulseCountInBlock
+	node.Precision = nebulaInfo.Precision
 	return nil
 }
 
+// Start runs the subround actions once for every new ledger block. The
+// heights come from the NewBlock events of the ledger and, when the adaptor
+// can push them, from the new heads of the target chain. Status and GetHeight
+// are polled instead when a subscription is not available. The pulse txs are
+// confirmed in the background, so a slow target chain does not hold up the
+// subrounds.
 func (node *Node) Start(ctx context.Context) {
-	var lastLedgerHeight uint64
+	var lastLedgerHeight int64
 	var lastTcHeight uint64
+	var lastIntervalId uint64
 	var pulseCountInBlock uint64
 	var lastPulseId uint64
 
-	roundState := new(RoundState)
+	ledgerHeights := node.watchLedger(ctx)
+	tcHeights := node.subscribeTargetChain(ctx)
+	var tcHeadHeight uint64
+	node.pulseTxs = make(chan pulseTx)
+
+	// The round state belongs to a pulse in a block interval of the target
+	// chain and is loaded from the journal when either changes.
+	var roundState *RoundState
+	var roundPulseId, roundIntervalId uint64
 	for {
-		time.Sleep(time.Duration(TimeoutMs) * time.Millisecond)
-		if pulseCountInBlock >= node.MaxPulseCountInBlock {
+		var ledgerHeight int64
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case tcHeadHeight, ok = <-tcHeights:
+			if !ok {
+				tcHeights = nil
+			}
 			continue
+		case tx := <-node.pulseTxs:
+			if roundState != nil && tx.pulseId == roundPulseId && tx.intervalId == roundIntervalId {
+				roundState.pulseTxId = ""
+				roundState.isSent = tx.err == nil
+				err := node.saveRound(roundPulseId, roundIntervalId, roundState)
+				if err != nil {
+					errorLogger.Print(err)
+				}
+			}
+			if tx.err != nil {
+				errorLogger.Print(tx.err)
+			}
+			continue
+		case ledgerHeight = <-ledgerHeights:
 		}
 
-		newLastPulseId, err := node.adaptor.LastPulseId(node.nebulaId, ctx)
-		if err != nil {
-			errorLogger.Print(err)
+		if ledgerHeight <= lastLedgerHeight {
 			continue
 		}
-
-		if lastPulseId != newLastPulseId {
-			lastPulseId = newLastPulseId
-			roundState = new(RoundState)
+		if lastLedgerHeight != 0 && ledgerHeight > lastLedgerHeight+1 {
+			node.skipHeights(lastLedgerHeight+1, ledgerHeight)
 		}
+		lastLedgerHeight = ledgerHeight
+		fmt.Printf("Ledger Height: %d\n", ledgerHeight)
 
-		tcHeight, err := node.adaptor.GetHeight(ctx)
-		if err != nil {
-			errorLogger.Print(err)
+		// The head of the target chain block may arrive with the ledger block.
+		select {
+		case tcHeadHeight, ok = <-tcHeights:
+			if !ok {
+				tcHeights = nil
+			}
+		default:
 		}
 
+		tcHeight := tcHeadHeight
+		if tcHeights == nil {
+			var err error
+			tcHeight, err = node.adaptor.GetHeight(ctx)
+			if err != nil {
+				errorLogger.Print(err)
+				continue
+			}
+		}
 		if tcHeight != lastTcHeight {
 			fmt.Printf("Tc Height: %d\n", tcHeight)
 			lastTcHeight = tcHeight
 		}
 
-		if tcHeight % node.blocksInterval == 0 {
+		intervalId := tcHeight / node.blocksInterval
+		if intervalId != lastIntervalId {
+			lastIntervalId = intervalId
 			pulseCountInBlock = 0
-			roundState = new(RoundState)
+		}
+
+		if pulseCountInBlock >= node.MaxPulseCountInBlock {
+			continue
+		}
+
+		newLastPulseId, err := node.adaptor.LastPulseId(node.nebulaId, ctx)
+		if err != nil {
+			errorLogger.Print(err)
+			continue
+		}
+
+		lastPulseId = newLastPulseId
+
+		if roundState == nil || roundPulseId != lastPulseId+1 || roundIntervalId != intervalId {
+			if roundState != nil {
+				node.endRound(roundState, roundPulseId, lastPulseId >= roundPulseId)
+			}
+			roundPulseId, roundIntervalId = lastPulseId+1, intervalId
+			roundState = node.loadRound(roundPulseId, roundIntervalId)
 		}
 
 		oraclesMap, err := node.gravityClient.BftOraclesByNebula(node.chainType, node.nebulaId)
@@ -236,71 +357,297 @@ func (node *Node) Start(ctx context.Context) {
 			continue
 		}
 		if _, ok := oraclesMap[node.oraclePubKey.ToString(node.chainType)]; !ok {
+			node.Metrics.InBftSet.With(NebulaLabel, node.nebulaLabel()).Set(0)
 			continue
 		}
+		node.Metrics.InBftSet.With(NebulaLabel, node.nebulaLabel()).Set(1)
+		roundState.inBftSet = true
 
-		info, err := node.gravityClient.HttpClient.Status()
+		err = node.execute(roundPulseId, uint64(ledgerHeight), tcHeight, roundIntervalId, roundState, ctx)
 		if err != nil {
 			errorLogger.Print(err)
-			continue
 		}
 
-		ledgerHeight := uint64(info.SyncInfo.LatestBlockHeight)
-		if lastLedgerHeight != ledgerHeight {
-			fmt.Printf("Ledger Height: %d\n", ledgerHeight)
-			lastLedgerHeight = ledgerHeight
+		if roundState.commitHash != nil {
+			err := node.saveRound(roundPulseId, roundIntervalId, roundState)
+			if err != nil {
+				errorLogger.Print(err)
+			}
 		}
+	}
+}
 
-		err = node.execute(lastPulseId + 1, ledgerHeight, tcHeight, tcHeight/node.blocksInterval, roundState, ctx)
+// skipHeights counts the subrounds of the ledger heights from up to to that
+// the node did not see, e.g. while the ledger was faster than the loop. Their
+// txs can not be sent late, the ledger checks them against the subround of
+// its last height, so the round goes on with the subrounds that are left.
+func (node *Node) skipHeights(from int64, to int64) {
+	fmt.Printf("Skipped ledger heights: %d - %d\n", from, to-1)
+	for height := from; height < to; height++ {
+		subRound := subRoundLabel(state.CalculateSubRound(uint64(height)))
+		node.Metrics.SkippedSubRounds.With(NebulaLabel, node.nebulaLabel(), SubRoundLabel, subRound).Add(1)
+	}
+}
+
+func (node *Node) saveRound(pulseId uint64, intervalId uint64, roundState *RoundState) error {
+	if node.Journal == nil {
+		return nil
+	}
+
+	return node.Journal.Save(node.nebulaId, pulseId, intervalId, roundState)
+}
+
+// commitExists tells whether the ledger has the commit of the oracle for the
+// round. A commit that is journaled but not marked as sent may have reached
+// the ledger before a restart, it is marked as sent then.
+func (node *Node) commitExists(intervalId uint64, pulseId uint64, roundState *RoundState) (bool, error) {
+	if roundState.commitSent {
+		return true, nil
+	}
+
+	_, err := node.gravityClient.CommitHash(node.chainType, node.nebulaId, int64(intervalId), int64(pulseId), node.oraclePubKey)
+	if err == gravity.ErrValueNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	roundState.commitSent = roundState.commitHash != nil
+	return true, nil
+}
+
+// endRound observes whether the oracle missed the pulse of a round that is
+// over while it was in the BFT set. The pulse is missed unless it reached the
+// target chain with the result of the oracle.
+func (node *Node) endRound(roundState *RoundState, pulseId uint64, confirmed bool) {
+	if !roundState.inBftSet {
+		return
+	}
+
+	var missedPulses uint64
+	if confirmed && node.resultIncluded(roundState, pulseId) {
+		atomic.StoreUint64(&node.missedPulses, 0)
+	} else {
+		missedPulses = atomic.AddUint64(&node.missedPulses, 1)
+	}
+	node.Metrics.MissedPulses.With(NebulaLabel, node.nebulaLabel()).Set(float64(missedPulses))
+}
+
+// resultIncluded reports whether the pulse carries the result of the oracle.
+// The ledger serves only the results of the agreed hash, which are the ones
+// the pulse is sent with.
+func (node *Node) resultIncluded(roundState *RoundState, pulseId uint64) bool {
+	if roundState.resultHash == nil {
+		return false
+	}
+
+	_, err := node.gravityClient.Result(node.chainType, node.nebulaId, int64(pulseId), node.oraclePubKey)
+	if err != nil && err != gravity.ErrValueNotFound {
+		errorLogger.Print(err)
+	}
+
+	return err == nil
+}
+
+// Health fails when the ledger or the target chain is unreachable or the
+// oracle missed MaxMissedPulses pulses in a row.
+func (node *Node) Health(ctx context.Context) error {
+	_, err := node.gravityClient.RPCClient.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrLedgerUnreachable, err)
+	}
+
+	_, err = node.adaptor.GetHeight(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrTargetChainUnreachable, err)
+	}
+
+	missedPulses := atomic.LoadUint64(&node.missedPulses)
+	if node.MaxMissedPulses != 0 && missedPulses >= node.MaxMissedPulses {
+		return fmt.Errorf("%w: %d", ErrMissedPulses, missedPulses)
+	}
+
+	return nil
+}
+
+// loadRound returns the journaled state of the round or a new one, and drops
+// the rounds of the earlier pulses from the journal.
+func (node *Node) loadRound(pulseId uint64, intervalId uint64) *RoundState {
+	if node.Journal == nil {
+		return new(RoundState)
+	}
+
+	err := node.Journal.Prune(node.nebulaId, pulseId)
+	if err != nil {
+		errorLogger.Print(err)
+	}
+
+	roundState, err := node.Journal.Load(node.nebulaId, pulseId, intervalId)
+	if err != nil {
+		if err != ErrRoundNotFound {
+			errorLogger.Print(err)
+		}
+		return new(RoundState)
+	}
+
+	fmt.Printf("Resume round of pulse %d\n", pulseId)
+	return roundState
+}
+
+// watchLedger sends the heights of the ledger blocks from the NewBlock
+// subscription. While the node can not subscribe, the heights are polled
+// every PollInterval, and the node subscribes again after a backoff that
+// doubles up to gravity.MaxResubscribeInterval. Like the subscription it
+// keeps only the latest height.
+func (node *Node) watchLedger(ctx context.Context) <-chan int64 {
+	heights := make(chan int64, 1)
+	go func() {
+		backoff := node.PollInterval
+		for {
+			subscription, err := node.gravityClient.SubscribeNewBlocks(ctx, "oracle-"+node.nebulaId.ToString(node.chainType))
+			if err == nil {
+				// The subscription is closed only when ctx is done.
+				for height := range subscription {
+					sendHeight(heights, height)
+				}
+				return
+			}
+			errorLogger.Print(err)
+
+			if !node.pollLedger(ctx, heights, backoff) {
+				return
+			}
+			backoff *= 2
+			if backoff > gravity.MaxResubscribeInterval {
+				backoff = gravity.MaxResubscribeInterval
+			}
+		}
+	}()
+
+	return heights
+}
+
+// pollLedger sends the height of the ledger every PollInterval for the
+// duration. It returns false when ctx is done.
+func (node *Node) pollLedger(ctx context.Context, heights chan int64, duration time.Duration) bool {
+	deadline := time.After(duration)
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline:
+			return true
+		case <-time.After(node.PollInterval):
+		}
+
+		info, err := node.gravityClient.RPCClient.Status(ctx)
 		if err != nil {
 			errorLogger.Print(err)
+			continue
 		}
+		sendHeight(heights, info.SyncInfo.LatestBlockHeight)
+	}
+}
+
+// sendHeight replaces the height the loop has not taken yet.
+func sendHeight(heights chan int64, height int64) {
+	select {
+	case <-heights:
+	default:
+	}
+	heights <- height
+}
+
+// subscribeTargetChain returns nil when the adaptor does not push its heads,
+// so the node asks for the height on every ledger block.
+func (node *Node) subscribeTargetChain(ctx context.Context) <-chan uint64 {
+	subscriber, ok := node.adaptor.(adaptors.IHeightSubscriber)
+	if !ok {
+		return nil
+	}
+
+	heights, err := subscriber.SubscribeHeight(ctx)
+	if err != nil {
+		errorLogger.Print(err)
+		return nil
 	}
+
+	return heights
 }
 
 func (node *Node) execute(pulseId uint64, ledgerHeight uint64, tcHeight uint64, intervalId uint64, roundState *RoundState, ctx context.Context) error {
 	switch state.CalculateSubRound(ledgerHeight) {
 	case state.CommitSubRound:
-		if roundState.commitHash != nil {
+		if roundState.commitSent {
 			return nil
 		}
-		_, err := node.gravityClient.CommitHash(node.chainType, node.nebulaId, int64(intervalId), int64(pulseId), node.oraclePubKey)
-		if err != nil && err != gravity.ErrValueNotFound {
+		exists, err := node.commitExists(intervalId, pulseId, roundState)
+		if err != nil || exists {
 			return err
-		} else if err == nil {
-			return nil
 		}
 
-		data, err := node.extractor.Extract(ctx)
-		if err != nil && err != extractor.NotFoundErr {
-			return err
-		} else if err == extractor.NotFoundErr {
-			return nil
-		}
+		if roundState.commitHash == nil {
+			extractedAt := time.Now()
+			data, err := node.extractor.Extract(ctx)
+			node.Metrics.ExtractorLatency.With(NebulaLabel, node.nebulaLabel()).Observe(time.Since(extractedAt).Seconds())
+			if err != nil && err != extractor.NotFoundErr {
+				return err
+			} else if err == extractor.NotFoundErr {
+				return nil
+			}
 
-		if data == nil {
-			return nil
+			if data == nil {
+				return nil
+			}
+
+			// The other oracles skip a reveal of another precision.
+			err = abi.CheckPrecision(data, node.Precision)
+			if err != nil {
+				return err
+			}
+
+			commit, salt, err := node.commitHash(data)
+			if err != nil {
+				return err
+			}
+
+			roundState.commitHash = commit
+			roundState.salt = salt
+			roundState.data = data
+			roundState.extractedAt = extractedAt
+
+			// The data and the salt are journaled before the commit is sent,
+			// so a node restarted after the broadcast reveals the value it
+			// committed to.
+			err = node.saveRound(pulseId, intervalId, roundState)
+			if err != nil {
+				return err
+			}
 		}
 
-		commit, err := node.commit(data, intervalId, pulseId)
+		err = node.commit(roundState.commitHash, intervalId, pulseId)
+		node.observeTx(CommitSubRound, err)
 		if err != nil {
 			return err
 		}
-
-		roundState.commitHash = commit
-		roundState.data = data
+		roundState.commitSent = true
 	case state.RevealSubRound:
 		if roundState.commitHash == nil || roundState.RevealExist {
 			return nil
 		}
-		_, err := node.gravityClient.Reveal(node.chainType, node.oraclePubKey, node.nebulaId, int64(intervalId), int64(pulseId), roundState.commitHash)
+		exists, err := node.commitExists(intervalId, pulseId, roundState)
+		if err != nil || !exists {
+			return err
+		}
+		_, err = node.gravityClient.Reveal(node.chainType, node.oraclePubKey, node.nebulaId, int64(intervalId), int64(pulseId), roundState.commitHash)
 		if err != nil && err != gravity.ErrValueNotFound {
 			return err
 		} else if err == nil {
 			return nil
 		}
 
-		err = node.reveal(intervalId, pulseId, roundState.data, roundState.commitHash)
+		err = node.reveal(intervalId, pulseId, roundState.data, roundState.commitHash, roundState.salt)
+		node.observeTx(RevealSubRound, err)
 		if err != nil {
 			return err
 		}
@@ -310,7 +657,8 @@ func (node *Node) execute(pulseId uint64, ledgerHeight uint64, tcHeight uint64,
 			return nil
 		}
 
-		value, hash, err := node.signResult(intervalId, pulseId, ctx)
+		value, hash, err := node.signResult(intervalId, pulseId, ledgerHeight, ctx)
+		node.observeTx(ResultSubRound, err)
 		if err != nil {
 			return err
 		}
@@ -321,7 +669,7 @@ func (node *Node) execute(pulseId uint64, ledgerHeight uint64, tcHeight uint64,
 		var oracles []account.OraclesPubKey
 		var myRound uint64
 
-		if roundState.isSent || roundState.resultValue == nil {
+		if roundState.isSent || roundState.pulseTxId != "" || roundState.resultValue == nil {
 			return nil
 		}
 
@@ -353,26 +701,83 @@ func (node *Node) execute(pulseId uint64, ledgerHeight uint64, tcHeight uint64,
 			return nil
 		}
 
+		// The ledger serves only the signs of the agreed hash, so the pulse
+		// is sent only if the result of this oracle is the agreed one.
+		_, err = node.gravityClient.Result(node.chainType, node.nebulaId, int64(pulseId), node.oraclePubKey)
+		if err == gravity.ErrValueNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
 		txId, err := node.adaptor.AddPulse(node.nebulaId, pulseId, oracles, roundState.resultHash, ctx)
 		if err != nil {
+			node.observeTx(PulseSubRound, err)
 			return err
 		}
 
 		if txId != "" {
-			err = node.adaptor.WaitTx(txId, ctx)
-			if err != nil {
-				return err
-			}
+			roundState.pulseTxId = txId
+			go node.confirmPulse(txId, pulseId, intervalId, roundState.resultValue, roundState.extractedAt, ctx)
+		}
+	}
+	return nil
+}
 
-			fmt.Printf("Result tx id : %s\n", txId)
+// confirmPulse waits for the pulse tx, which can take minutes on the target
+// chain, sends the value to the subscribers and reports the tx to Start. The
+// pulse latency is taken from extractedAt, zero when the oracle extracted no
+// value for the round.
+func (node *Node) confirmPulse(txId string, pulseId uint64, intervalId uint64, value *extractor.Data, extractedAt time.Time, ctx context.Context) {
+	err := node.adaptor.WaitTx(txId, ctx)
+	node.observeTx(PulseSubRound, err)
+	if err == nil {
+		fmt.Printf("Result tx id : %s\n", txId)
+		if !extractedAt.IsZero() {
+			node.Metrics.PulseLatency.With(NebulaLabel, node.nebulaLabel()).Observe(time.Since(extractedAt).Seconds())
+		}
+		node.observeTxFee(txId, ctx)
+
+		err := node.adaptor.SendValueToSubs(node.nebulaId, pulseId, value, ctx)
+		if err != nil {
+			errorLogger.Print(err)
+		}
+	}
 
-			roundState.isSent = true
+	select {
+	case node.pulseTxs <- pulseTx{pulseId: pulseId, intervalId: intervalId, err: err}:
+	case <-ctx.Done():
+	}
+}
 
-			err = node.adaptor.SendValueToSubs(node.nebulaId, pulseId, roundState.resultValue, ctx)
-			if err != nil {
-				return err
-			}
-		}
+// observeTx counts a tx of the subround by its status.
+func (node *Node) observeTx(subRound string, err error) {
+	status := SuccessStatus
+	if err != nil {
+		status = FailureStatus
 	}
-	return nil
+	node.Metrics.Txs.With(NebulaLabel, node.nebulaLabel(), SubRoundLabel, subRound, StatusLabel, status).Add(1)
+}
+
+// observeTxFee adds up what a tx cost when the adaptor can tell it.
+func (node *Node) observeTxFee(txId string, ctx context.Context) {
+	feeReader, ok := node.adaptor.(adaptors.ITxFeeReader)
+	if !ok {
+		return
+	}
+
+	gas, fee, err := feeReader.TxFee(txId, ctx)
+	if err != nil {
+		errorLogger.Print(err)
+		return
+	}
+	node.Metrics.TxGas.With(NebulaLabel, node.nebulaLabel()).Add(float64(gas))
+	if fee != nil {
+		value, _ := new(big.Float).SetInt(fee).Float64()
+		node.Metrics.TxFee.With(NebulaLabel, node.nebulaLabel()).Add(value)
+	}
+}
+
+func (node *Node) nebulaLabel() string {
+	return node.nebulaId.ToString(node.chainType)
 }
@@ -0,0 +1,161 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Gravity-Tech/gravity-core/abi"
+	"github.com/Gravity-Tech/gravity-core/common/adaptors"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/ledger/query"
+	"github.com/Gravity-Tech/gravity-core/oracle/aggregator"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+type infoSource struct {
+	extractor.Source
+	info *extractor.Info
+	err  error
+}
+
+func (source *infoSource) Info(ctx context.Context) (*extractor.Info, error) {
+	return source.info, source.err
+}
+
+func TestHandshake(t *testing.T) {
+	failed := errors.New("failed")
+	tests := []struct {
+		name   string
+		source *infoSource
+		err    error
+	}{
+		{"matching type", &infoSource{info: &extractor.Info{DataType: extractor.Decimal}}, nil},
+		{"undeclared type", &infoSource{info: &extractor.Info{}}, nil},
+		{"without info", &infoSource{err: extractor.NotFoundErr}, nil},
+		{"other type", &infoSource{info: &extractor.Info{DataType: extractor.Int64}}, ErrExtractorDataType},
+		{"failed info", &infoSource{err: failed}, failed},
+	}
+
+	for _, test := range tests {
+		err := handshake(test.source, abi.DecimalType, context.Background())
+		if !errors.Is(err, test.err) || err != nil && test.err == nil {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+// statusRPC serves the status of the ledger and the result of the oracle
+// when it is included.
+type statusRPC struct {
+	gravity.RPCClient
+	err      error
+	included bool
+}
+
+func (rpc *statusRPC) Status(ctx context.Context) (*ctypes.ResultStatus, error) {
+	return &ctypes.ResultStatus{}, rpc.err
+}
+
+func (rpc *statusRPC) ABCIQueryWithOptions(ctx context.Context, path string, data tmbytes.HexBytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	rs := &ctypes.ResultABCIQuery{}
+	if query.Path(path) == query.ResultPath && rpc.included {
+		rs.Response.Value = []byte{1}
+	} else {
+		rs.Response.Code = gravity.NotFoundCode
+	}
+
+	return rs, nil
+}
+
+type heightAdaptor struct {
+	adaptors.IBlockchainAdaptor
+	err error
+}
+
+func (adaptor *heightAdaptor) GetHeight(ctx context.Context) (uint64, error) {
+	return 1, adaptor.err
+}
+
+func TestHealth(t *testing.T) {
+	rpc := &statusRPC{}
+	ghClient, err := gravity.NewWithRPC(rpc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adaptor := &heightAdaptor{}
+	node := &Node{
+		gravityClient:   ghClient,
+		adaptor:         adaptor,
+		Metrics:         NopMetrics(),
+		MaxMissedPulses: 2,
+	}
+	ctx := context.Background()
+
+	// Rounds out of the BFT set and pulses with the result of the oracle are
+	// not missed.
+	result := &RoundState{inBftSet: true, RevealExist: true, resultHash: []byte{1}}
+	node.endRound(&RoundState{}, 1, false)
+	node.endRound(&RoundState{inBftSet: true}, 1, false)
+	rpc.included = true
+	node.endRound(result, 1, true)
+	node.endRound(&RoundState{inBftSet: true}, 2, false)
+	if err := node.Health(ctx); err != nil {
+		t.Fatalf("expected healthy, got %v", err)
+	}
+
+	// A revealed round is missed when the pulse is not confirmed or is sent
+	// without the result of the oracle.
+	node.endRound(result, 3, false)
+	if err := node.Health(ctx); !errors.Is(err, ErrMissedPulses) {
+		t.Errorf("unconfirmed pulse: expected %v, got %v", ErrMissedPulses, err)
+	}
+	node.endRound(result, 3, true)
+	rpc.included = false
+	node.endRound(result, 4, true)
+	node.endRound(result, 5, true)
+	if err := node.Health(ctx); !errors.Is(err, ErrMissedPulses) {
+		t.Errorf("pulse without the result: expected %v, got %v", ErrMissedPulses, err)
+	}
+	rpc.included = true
+	node.endRound(result, 6, true)
+
+	adaptor.err = errors.New("down")
+	if err := node.Health(ctx); !errors.Is(err, ErrTargetChainUnreachable) {
+		t.Errorf("expected %v, got %v", ErrTargetChainUnreachable, err)
+	}
+	rpc.err = errors.New("down")
+	if err := node.Health(ctx); !errors.Is(err, ErrLedgerUnreachable) {
+		t.Errorf("expected %v, got %v", ErrLedgerUnreachable, err)
+	}
+}
+
+func TestScoredValuesPrecision(t *testing.T) {
+	var reveals []query.ScoredReveal
+	for _, data := range []extractor.Data{
+		{Type: extractor.Decimal, Value: "1.50", Precision: 2},
+		{Type: extractor.Decimal, Value: "2.500", Precision: 3},
+		{Type: extractor.Decimal, Value: "3.50", Precision: 2},
+		{Type: extractor.Decimal, Value: "9.50", Precision: 2},
+	} {
+		value, err := toBytes(&data, abi.DecimalType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reveals = append(reveals, query.ScoredReveal{Value: value, Score: 1})
+	}
+
+	// The reveal of another precision is skipped, not failing the median of
+	// the others.
+	median, _ := aggregator.Local(aggregator.Median)
+	result, err := median(scoredValues(reveals, abi.DecimalType, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Value != "3.50" || result.Precision != 2 {
+		t.Errorf("expected 3.50, got %s with precision %d", result.Value, result.Precision)
+	}
+}
@@ -2,8 +2,13 @@ package node
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"github.com/Gravity-Tech/gravity-core/abi"
+	"github.com/Gravity-Tech/gravity-core/common/state"
+	"github.com/Gravity-Tech/gravity-core/ledger/query"
+	"github.com/Gravity-Tech/gravity-core/oracle/aggregator"
 	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
 
 	"github.com/Gravity-Tech/gravity-core/common/transactions"
@@ -11,73 +16,60 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-func (node *Node) commit(data *extractor.Data, tcHeight uint64, pulseId uint64) ([]byte, error) {
-	dataBytes := toBytes(data, node.extractor.ExtractorType)
-	commit := crypto.Keccak256(dataBytes)
-	fmt.Printf("Commit: %s - %s \n", hexutil.Encode(dataBytes), hexutil.Encode(commit[:]))
+// commitHash salts the data, the salt is revealed with it.
+func (node *Node) commitHash(data *extractor.Data) ([]byte, []byte, error) {
+	salt := make([]byte, transactions.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	tx, err := transactions.New(node.validator.pubKey, transactions.Commit, node.validator.privKey)
+	dataBytes, err := toBytes(data, node.extractor.ExtractorType)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	commit := transactions.CommitHash(salt, dataBytes)
+	fmt.Printf("Commit: %s - %s \n", hexutil.Encode(dataBytes), hexutil.Encode(commit[:]))
 
-	tx.AddValues([]transactions.Value{
-		transactions.BytesValue{
-			Value: node.nebulaId[:],
-		},
-		transactions.IntValue{
-			Value: int64(pulseId),
-		},
-		transactions.IntValue{
-			Value: int64(tcHeight),
-		},
-		transactions.BytesValue{
-			Value: commit,
-		},
-		transactions.BytesValue{
-			Value: node.oraclePubKey[:],
-		},
+	return commit, salt, nil
+}
+
+func (node *Node) commit(commit []byte, tcHeight uint64, pulseId uint64) error {
+	tx := transactions.New(node.validator.pubKey, &transactions.CommitArgs{
+		NebulaId:     node.nebulaId,
+		PulseId:      int64(pulseId),
+		Height:       int64(tcHeight),
+		Commit:       commit,
+		OraclePubKey: node.oraclePubKey,
 	})
 
-	err = node.gravityClient.SendTx(tx)
+	err := node.gravityClient.SendTx(tx, node.validator.privKey)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	fmt.Printf("Commit txId: %s\n", hexutil.Encode(tx.Id[:]))
 
-	return commit, nil
+	return nil
 }
-func (node *Node) reveal(tcHeight uint64, pulseId uint64, reveal *extractor.Data, commit []byte) error {
-	dataBytes := toBytes(reveal, node.extractor.ExtractorType)
-	fmt.Printf("Reveal: %s  - %s \n", hexutil.Encode(dataBytes), hexutil.Encode(commit))
-	println(base64.StdEncoding.EncodeToString(dataBytes))
-	tx, err := transactions.New(node.validator.pubKey, transactions.Reveal, node.validator.privKey)
+func (node *Node) reveal(tcHeight uint64, pulseId uint64, reveal *extractor.Data, commit []byte, salt []byte) error {
+	dataBytes, err := toBytes(reveal, node.extractor.ExtractorType)
 	if err != nil {
 		return err
 	}
-	tx.AddValues([]transactions.Value{
-		transactions.BytesValue{
-			Value: commit,
-		},
-		transactions.BytesValue{
-			Value: node.nebulaId[:],
-		},
-		transactions.IntValue{
-			Value: int64(pulseId),
-		},
-		transactions.IntValue{
-			Value: int64(tcHeight),
-		},
-		transactions.BytesValue{
-			Value: dataBytes,
-		},
-		transactions.BytesValue{
-			Value: node.oraclePubKey[:],
-		},
+	fmt.Printf("Reveal: %s  - %s \n", hexutil.Encode(dataBytes), hexutil.Encode(commit))
+	println(base64.StdEncoding.EncodeToString(dataBytes))
+	tx := transactions.New(node.validator.pubKey, &transactions.RevealArgs{
+		Commit:       commit,
+		NebulaId:     node.nebulaId,
+		PulseId:      int64(pulseId),
+		Height:       int64(tcHeight),
+		Reveal:       dataBytes,
+		OraclePubKey: node.oraclePubKey,
+		Salt:         salt,
 	})
 
-	err = node.gravityClient.SendTx(tx)
+	err = node.gravityClient.SendTx(tx, node.validator.privKey)
 	if err != nil {
 		return err
 	}
@@ -85,56 +77,32 @@ func (node *Node) reveal(tcHeight uint64, pulseId uint64, reveal *extractor.Data
 
 	return nil
 }
-func (node *Node) signResult(tcHeight uint64, pulseId uint64, ctx context.Context) (*extractor.Data, []byte, error) {
-	var values []extractor.Data
-	bytesValues, err := node.gravityClient.Reveals(node.chainType, node.nebulaId, int64(tcHeight), int64(pulseId))
+func (node *Node) signResult(tcHeight uint64, pulseId uint64, ledgerHeight uint64, ctx context.Context) (*extractor.Data, []byte, error) {
+	result, err := node.aggregate(tcHeight, pulseId, ledgerHeight, ctx)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	for _, v := range bytesValues {
-		b, err := base64.StdEncoding.DecodeString(v)
-		if err != nil {
-			continue
-		}
-		values = append(values, *fromBytes(b, node.extractor.ExtractorType))
-	}
-
-	result, err := node.extractor.Aggregate(values, ctx)
+	resultBytes, err := toBytes(result, node.extractor.ExtractorType)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	hash := crypto.Keccak256(toBytes(result, node.extractor.ExtractorType))
+	hash := crypto.Keccak256(resultBytes)
 	sign, err := node.adaptor.Sign(hash)
 	if err != nil {
 		return nil, nil, err
 	}
 	fmt.Printf("Result hash: %s \n", hexutil.Encode(hash))
 
-	tx, err := transactions.New(node.validator.pubKey, transactions.Result, node.validator.privKey)
-	if err != nil {
-		return nil, nil, err
-	}
-	tx.AddValues([]transactions.Value{
-		transactions.BytesValue{
-			Value: node.nebulaId[:],
-		},
-		transactions.IntValue{
-			Value: int64(pulseId),
-		},
-		transactions.BytesValue{
-			Value: sign,
-		},
-		transactions.BytesValue{
-			Value: []byte{byte(node.chainType)},
-		},
-		transactions.BytesValue{
-			Value: node.oraclePubKey[:],
-		},
+	tx := transactions.New(node.validator.pubKey, &transactions.ResultArgs{
+		NebulaId:  node.nebulaId,
+		PulseId:   int64(pulseId),
+		Sign:      sign,
+		ChainType: node.chainType,
+		Hash:      hash,
 	})
 
-	err = node.gravityClient.SendTx(tx)
+	err = node.gravityClient.SendTx(tx, node.validator.privKey)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -142,3 +110,67 @@ func (node *Node) signResult(tcHeight uint64, pulseId uint64, ctx context.Contex
 	fmt.Printf("Sign result txId: %s\n", hexutil.Encode(tx.Id[:]))
 	return result, hash, nil
 }
+
+// aggregate computes the result of the reveals with the aggregator of the
+// nebula. Reveals that are not values of the nebula type or its precision
+// are skipped. The
+// reveals and their scores are read at the block that closes the reveal
+// subround of ledgerHeight, so every oracle aggregates the same values with
+// the same weights.
+func (node *Node) aggregate(tcHeight uint64, pulseId uint64, ledgerHeight uint64, ctx context.Context) (*extractor.Data, error) {
+	nebulaInfo, err := node.gravityClient.NebulaInfo(node.nebulaId, node.chainType)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregatorType, err := aggregator.ParseType(nebulaInfo.Aggregator)
+	if err != nil {
+		return nil, err
+	}
+
+	revealsClient := node.gravityClient.AtHeight(int64(state.NewPulseWindow(ledgerHeight).Reveal + 1))
+	aggregate, ok := aggregator.Local(aggregatorType)
+	if !ok {
+		var values []extractor.Data
+		reveals, err := revealsClient.Reveals(node.chainType, node.nebulaId, int64(tcHeight), int64(pulseId))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range reveals {
+			value, err := revealedData(v.Value, node.extractor.ExtractorType, nebulaInfo.Precision)
+			if err != nil {
+				errorLogger.Print(err)
+				continue
+			}
+			values = append(values, *value)
+		}
+
+		return node.extractor.Aggregate(values, ctx)
+	}
+
+	reveals, err := revealsClient.ScoredReveals(node.chainType, node.nebulaId, int64(tcHeight), int64(pulseId))
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregate(scoredValues(reveals, node.extractor.ExtractorType, nebulaInfo.Precision))
+}
+
+// scoredValues are the reveals weighted by the scores of their oracles.
+func scoredValues(reveals []query.ScoredReveal, extractorType abi.ExtractorType, precision uint8) []aggregator.Value {
+	var values []aggregator.Value
+	for _, v := range reveals {
+		value, err := revealedData(v.Value, extractorType, precision)
+		if err != nil {
+			errorLogger.Print(err)
+			continue
+		}
+		values = append(values, aggregator.Value{
+			Data:   *value,
+			Weight: v.Score,
+		})
+	}
+
+	return values
+}
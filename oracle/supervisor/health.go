@@ -0,0 +1,64 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/oracle/node"
+)
+
+const (
+	DefaultHealthTimeout = 10 * time.Second
+)
+
+var (
+	ErrNoNebulae = errors.New("no nebula is running")
+)
+
+// Health checks the node of every running nebula and returns the failure
+// of the first unhealthy one.
+func (supervisor *Supervisor) Health(ctx context.Context) error {
+	supervisor.lock.Lock()
+	nodes := make(map[string]*node.Node, len(supervisor.nebulae))
+	var nebulaIds []string
+	for nebulaId, running := range supervisor.nebulae {
+		nodes[nebulaId] = running.node
+		nebulaIds = append(nebulaIds, nebulaId)
+	}
+	supervisor.lock.Unlock()
+
+	if len(nebulaIds) == 0 {
+		return ErrNoNebulae
+	}
+
+	sort.Strings(nebulaIds)
+	for _, nebulaId := range nebulaIds {
+		err := nodes[nebulaId].Health(ctx)
+		if err != nil {
+			return fmt.Errorf("nebula %s: %w", nebulaId, err)
+		}
+	}
+
+	return nil
+}
+
+// HealthHandler serves the health of the supervisor: 200 when every nebula
+// is healthy, 503 with the failure otherwise. A check lasts timeout at most.
+func (supervisor *Supervisor) HealthHandler(timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		err := supervisor.Health(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprintln(w, "ok")
+	})
+}
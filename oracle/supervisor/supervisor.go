@@ -0,0 +1,568 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/adaptors"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/config"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor/extractorgrpc"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor/multisource"
+	"github.com/Gravity-Tech/gravity-core/oracle/node"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	ConfigExt = ".json"
+
+	// GrpcScheme and GrpcsScheme are the schemes of the extractor urls of the
+	// grpc extractor protocol, GrpcsScheme over TLS.
+	GrpcScheme  = "grpc"
+	GrpcsScheme = "grpcs"
+)
+
+var (
+	ErrTargetChainKeyNotFound = errors.New("target chain key is not found")
+	ErrSubscriptionNotGrpc    = errors.New("only grpc extractors push values")
+
+	errorLogger = log.New(os.Stdout,
+		"ERROR: ",
+		log.Ldate|log.Ltime|log.Lshortfile)
+)
+
+// Supervisor runs an oracle node for every nebula config in a directory.
+// The nodes of one target chain share its adaptor and the nodes of one ledger
+// share its client, while every node runs its own pulse loop.
+type Supervisor struct {
+	dir       string
+	keys      *config.Keys
+	validator *node.Validator
+	journal   *node.Journal
+	ctx       context.Context
+
+	whitelist        map[string]bool
+	newAdaptor       adaptors.Factory
+	extractorMetrics *multisource.Metrics
+	nodeMetrics      *node.Metrics
+
+	// reload serializes Reload and Stop. The clients and the adaptors are
+	// built under it, and the nodes are started and stopped under it, so
+	// lock guards only the map of the running nebulae.
+	reload    sync.Mutex
+	ghClients map[string]*gravity.Client
+	adaptors  map[adaptorKey]adaptors.IBlockchainAdaptor
+
+	lock    sync.Mutex
+	nebulae map[string]*nebula
+}
+
+// adaptorKey is everything the adaptor of a nebula is built from, so nebulae
+// that agree on it share the adaptor.
+type adaptorKey struct {
+	chainType          account.ChainType
+	nodeUrl            string
+	chainId            string
+	gasPrice           string
+	gasPriceMultiplier int64
+	gravityNodeUrl     string
+}
+
+type nebula struct {
+	cfg       config.OracleConfig
+	node      *node.Node
+	extractor extractor.Source
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+type Option func(*Supervisor) error
+
+// WithNebulae limits the supervisor to the nebulae with the ids, the other
+// configs of the directory are ignored.
+func WithNebulae(nebulaIds ...string) Option {
+	return func(s *Supervisor) error {
+		for _, nebulaId := range nebulaIds {
+			s.whitelist[nebulaId] = true
+		}
+		return nil
+	}
+}
+
+// WithAdaptorFactory replaces adaptors.New as the constructor of the target
+// chain adaptors.
+func WithAdaptorFactory(factory adaptors.Factory) Option {
+	return func(s *Supervisor) error {
+		s.newAdaptor = factory
+		return nil
+	}
+}
+
+// WithGravityClient makes the nebulae with gravityNodeUrl talk to the ledger
+// through client.
+func WithGravityClient(gravityNodeUrl string, client *gravity.Client) Option {
+	return func(s *Supervisor) error {
+		s.ghClients[gravityNodeUrl] = client
+		return nil
+	}
+}
+
+// WithExtractorMetrics reports the sources of the multi-source extractors
+// to metrics.
+func WithExtractorMetrics(metrics *multisource.Metrics) Option {
+	return func(s *Supervisor) error {
+		s.extractorMetrics = metrics
+		return nil
+	}
+}
+
+// WithNodeMetrics reports the pulses of the nodes to metrics.
+func WithNodeMetrics(metrics *node.Metrics) Option {
+	return func(s *Supervisor) error {
+		s.nodeMetrics = metrics
+		return nil
+	}
+}
+
+// New returns a supervisor of the nebula configs in dir. The nodes sign with
+// keys, journal their rounds in journal when it is not nil and stop with ctx.
+func New(dir string, keys *config.Keys, journal *node.Journal, ctx context.Context, opts ...Option) (*Supervisor, error) {
+	validatorPrivKey, err := hexutil.Decode(keys.Validator.PrivKey)
+	if err != nil {
+		return nil, err
+	}
+
+	supervisor := &Supervisor{
+		dir:              dir,
+		keys:             keys,
+		validator:        node.NewValidator(validatorPrivKey),
+		journal:          journal,
+		ctx:              ctx,
+		whitelist:        make(map[string]bool),
+		newAdaptor:       adaptors.New,
+		extractorMetrics: multisource.NopMetrics(),
+		nodeMetrics:      node.NopMetrics(),
+		ghClients:        make(map[string]*gravity.Client),
+		adaptors:         make(map[adaptorKey]adaptors.IBlockchainAdaptor),
+		nebulae:          make(map[string]*nebula),
+	}
+	for _, opt := range opts {
+		err := opt(supervisor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return supervisor, nil
+}
+
+// Nebulae returns the ids of the running nebulae.
+func (supervisor *Supervisor) Nebulae() []string {
+	supervisor.lock.Lock()
+	defer supervisor.lock.Unlock()
+
+	var nebulaIds []string
+	for nebulaId := range supervisor.nebulae {
+		nebulaIds = append(nebulaIds, nebulaId)
+	}
+	sort.Strings(nebulaIds)
+
+	return nebulaIds
+}
+
+// Reload brings the running nebulae in line with the configs in the
+// directory. Nebulae without a config are stopped, nebulae with a changed
+// config are restarted and new configs are started. A nebula that fails to
+// start is logged and tried again on the next reload.
+func (supervisor *Supervisor) Reload() error {
+	files, err := ioutil.ReadDir(supervisor.dir)
+	if err != nil {
+		return err
+	}
+
+	configs := make(map[string]config.OracleConfig)
+	// A config that can not be parsed keeps its nebula running as it is.
+	broken := make(map[string]bool)
+	for _, file := range files {
+		if file.IsDir() || path.Ext(file.Name()) != ConfigExt {
+			continue
+		}
+
+		nebulaId := strings.TrimSuffix(file.Name(), ConfigExt)
+		if len(supervisor.whitelist) > 0 && !supervisor.whitelist[nebulaId] {
+			continue
+		}
+
+		var cfg config.OracleConfig
+		err := config.ParseConfig(path.Join(supervisor.dir, file.Name()), &cfg)
+		if err != nil {
+			errorLogger.Printf("nebula %s: %s", nebulaId, err)
+			broken[nebulaId] = true
+			continue
+		}
+		configs[nebulaId] = cfg
+	}
+
+	supervisor.reload.Lock()
+	defer supervisor.reload.Unlock()
+
+	// The nodes are stopped and started outside of lock, which would hold
+	// up Health for the network calls of the new nodes.
+	stopped := make(map[string]*nebula)
+	supervisor.lock.Lock()
+	for nebulaId, running := range supervisor.nebulae {
+		cfg, ok := configs[nebulaId]
+		if broken[nebulaId] || ok && reflect.DeepEqual(cfg, running.cfg) {
+			delete(configs, nebulaId)
+			continue
+		}
+
+		stopped[nebulaId] = running
+		delete(supervisor.nebulae, nebulaId)
+	}
+	supervisor.lock.Unlock()
+
+	for nebulaId, running := range stopped {
+		fmt.Printf("Stop nebula %s\n", nebulaId)
+		running.stop()
+	}
+
+	for nebulaId, cfg := range configs {
+		started, err := supervisor.start(nebulaId, cfg)
+		if err != nil {
+			errorLogger.Printf("nebula %s: %s", nebulaId, err)
+			continue
+		}
+		fmt.Printf("Start nebula %s\n", nebulaId)
+
+		supervisor.lock.Lock()
+		supervisor.nebulae[nebulaId] = started
+		supervisor.lock.Unlock()
+	}
+
+	return nil
+}
+
+// Watch reloads the directory every interval until the context of the
+// supervisor is done.
+func (supervisor *Supervisor) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-supervisor.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := supervisor.Reload()
+		if err != nil {
+			errorLogger.Print(err)
+		}
+	}
+}
+
+// Stop stops all nebulae and waits for their pulse loops to return.
+func (supervisor *Supervisor) Stop() {
+	supervisor.reload.Lock()
+	defer supervisor.reload.Unlock()
+
+	supervisor.lock.Lock()
+	nebulae := supervisor.nebulae
+	supervisor.nebulae = make(map[string]*nebula)
+	supervisor.lock.Unlock()
+
+	for _, running := range nebulae {
+		running.stop()
+	}
+}
+
+func (supervisor *Supervisor) start(nebulaIdStr string, cfg config.OracleConfig) (*nebula, error) {
+	err := config.RegisterChainTypes(cfg.ChainTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	chainType, err := account.ParseChainType(cfg.ChainType)
+	if err != nil {
+		return nil, err
+	}
+
+	nebulaId, err := account.StringToNebulaId(nebulaIdStr, chainType)
+	if err != nil {
+		return nil, err
+	}
+
+	ghClient, err := supervisor.gravityClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	adaptor, err := supervisor.adaptor(chainType, cfg, ghClient)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := supervisor.openExtractor(nebulaIdStr, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	oracleNode, err := node.NewWithAdaptor(nebulaId, chainType, adaptor, ghClient, supervisor.validator,
+		source, cfg.BlocksInterval, supervisor.ctx)
+	if err == nil {
+		err = oracleNode.Init()
+	}
+	if err != nil {
+		closeExtractor(source)
+		return nil, err
+	}
+	oracleNode.Journal = supervisor.journal
+	oracleNode.Metrics = supervisor.nodeMetrics
+	if cfg.MaxMissedPulses != 0 {
+		oracleNode.MaxMissedPulses = cfg.MaxMissedPulses
+	}
+
+	ctx, cancel := context.WithCancel(supervisor.ctx)
+	started := &nebula{
+		cfg:       cfg,
+		node:      oracleNode,
+		extractor: source,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go func() {
+		defer close(started.done)
+		oracleNode.Start(ctx)
+	}()
+
+	return started, nil
+}
+
+// gravityClient returns the client of the ledger of cfg. The light client
+// settings of the first nebula of a ledger apply to all its nebulae. The
+// client keeps the nonces locked only until CheckTx accepts a tx, so the txs
+// of the nebulae reach the same block and meet their subround.
+func (supervisor *Supervisor) gravityClient(cfg config.OracleConfig) (*gravity.Client, error) {
+	if ghClient, ok := supervisor.ghClients[cfg.GravityNodeUrl]; ok {
+		return ghClient, nil
+	}
+
+	var ghClientOpts []gravity.Option
+	if cfg.LightClient != nil {
+		trustOptions, err := cfg.LightClient.TrustOptions()
+		if err != nil {
+			return nil, err
+		}
+
+		lightClient, err := gravity.NewLightClient(supervisor.ctx, cfg.GravityNodeUrl, cfg.LightClient.ChainId, trustOptions, cfg.LightClient.Witnesses)
+		if err != nil {
+			return nil, err
+		}
+		ghClientOpts = append(ghClientOpts, gravity.WithLightClient(lightClient))
+	}
+
+	ghClient, err := gravity.New(cfg.GravityNodeUrl, ghClientOpts...)
+	if err != nil {
+		return nil, err
+	}
+	supervisor.ghClients[cfg.GravityNodeUrl] = ghClient
+
+	return ghClient, nil
+}
+
+func (supervisor *Supervisor) adaptor(chainType account.ChainType, cfg config.OracleConfig, ghClient *gravity.Client) (adaptors.IBlockchainAdaptor, error) {
+	key := adaptorKey{
+		chainType:          chainType,
+		nodeUrl:            cfg.TargetChainNodeUrl,
+		chainId:            cfg.ChainId,
+		gasPrice:           cfg.GasPrice,
+		gasPriceMultiplier: cfg.GasPriceMultiplier,
+		gravityNodeUrl:     cfg.GravityNodeUrl,
+	}
+	if adaptor, ok := supervisor.adaptors[key]; ok {
+		return adaptor, nil
+	}
+
+	targetChainKey, ok := supervisor.keys.TargetChains[chainType.String()]
+	if !ok {
+		return nil, ErrTargetChainKeyNotFound
+	}
+	privKey, err := account.StringToPrivKey(targetChainKey.PrivKey, chainType)
+	if err != nil {
+		return nil, err
+	}
+
+	adaptor, err := supervisor.newAdaptor(adaptors.Config{
+		ChainType:          chainType,
+		PrivKey:            privKey,
+		NodeUrl:            cfg.TargetChainNodeUrl,
+		ChainId:            cfg.ChainId,
+		GasPrice:           cfg.GasPrice,
+		GasPriceMultiplier: cfg.GasPriceMultiplier,
+		GhClient:           ghClient,
+	}, supervisor.ctx)
+	if err != nil {
+		return nil, err
+	}
+	supervisor.adaptors[key] = adaptor
+
+	return adaptor, nil
+}
+
+// openExtractor returns the client of the extractor of cfg, by the scheme of
+// its url, or the multi-source extractor of its sources.
+func (supervisor *Supervisor) openExtractor(nebulaId string, cfg config.OracleConfig) (extractor.Source, error) {
+	if cfg.Sources == nil {
+		var extractorCfg config.ExtractorConfig
+		if cfg.Extractor != nil {
+			extractorCfg = *cfg.Extractor
+		}
+
+		source, _, err := openSource(cfg.ExtractorUrl, extractorCfg)
+		return source, err
+	}
+
+	var sources []multisource.Source
+	closeSources := func() {
+		for _, s := range sources {
+			closeExtractor(s.Source)
+		}
+	}
+	for _, sourceCfg := range cfg.Sources.Extractors {
+		source, timeout, err := openSource(sourceCfg.Url, sourceCfg.ExtractorConfig)
+		if err != nil {
+			closeSources()
+			return nil, err
+		}
+
+		weight := uint64(1)
+		if sourceCfg.Weight != nil {
+			weight = *sourceCfg.Weight
+		}
+		name := sourceCfg.Name
+		if name == "" {
+			name = sourceCfg.Url
+		}
+		sources = append(sources, multisource.Source{
+			Name:    name,
+			Source:  source,
+			Weight:  weight,
+			Timeout: timeout,
+		})
+	}
+
+	opts := []multisource.Option{multisource.WithMetrics(supervisor.extractorMetrics, nebulaId)}
+	if cfg.Sources.Quorum != 0 {
+		opts = append(opts, multisource.WithQuorum(cfg.Sources.Quorum))
+	}
+	if cfg.Sources.MaxDeviation != 0 {
+		opts = append(opts, multisource.WithMaxDeviation(cfg.Sources.MaxDeviation))
+	}
+	if cfg.Sources.MaxFailures != 0 {
+		opts = append(opts, multisource.WithMaxFailures(cfg.Sources.MaxFailures))
+	}
+	if cfg.Sources.Quarantine != "" {
+		quarantine, err := time.ParseDuration(cfg.Sources.Quarantine)
+		if err != nil {
+			closeSources()
+			return nil, err
+		}
+		opts = append(opts, multisource.WithQuarantine(quarantine))
+	}
+
+	source, err := multisource.New(sources, opts...)
+	if err != nil {
+		closeSources()
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// openSource returns the client of the extractor at extractorUrl and the
+// timeout of its calls, zero when it is not set.
+func openSource(rawUrl string, extractorCfg config.ExtractorConfig) (extractor.Source, time.Duration, error) {
+	extractorUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var timeout time.Duration
+	if extractorCfg.Timeout != "" {
+		timeout, err = time.ParseDuration(extractorCfg.Timeout)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if extractorUrl.Scheme != GrpcScheme && extractorUrl.Scheme != GrpcsScheme {
+		if extractorCfg.Subscribe {
+			return nil, 0, ErrSubscriptionNotGrpc
+		}
+
+		var opts []extractor.Option
+		if extractorCfg.Timeout != "" {
+			opts = append(opts, extractor.WithTimeout(timeout))
+		}
+		if extractorCfg.Token != "" {
+			opts = append(opts, extractor.WithBearerToken(extractorCfg.Token))
+		}
+		return extractor.New(rawUrl, opts...), timeout, nil
+	}
+
+	var opts []extractorgrpc.Option
+	if extractorCfg.Timeout != "" {
+		opts = append(opts, extractorgrpc.WithTimeout(timeout))
+	}
+	if extractorCfg.Token != "" {
+		opts = append(opts, extractorgrpc.WithBearerToken(extractorCfg.Token))
+	}
+	if extractorCfg.Subscribe {
+		opts = append(opts, extractorgrpc.WithSubscription())
+	}
+	if extractorUrl.Scheme == GrpcsScheme {
+		creds, err := extractorgrpc.ClientTLS(extractorCfg.CAFile, extractorCfg.CertFile, extractorCfg.KeyFile)
+		if err != nil {
+			return nil, 0, err
+		}
+		opts = append(opts, extractorgrpc.WithTransportCredentials(creds))
+	}
+
+	client, err := extractorgrpc.Dial(extractorUrl.Host, opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return client, timeout, nil
+}
+
+func closeExtractor(source extractor.Source) {
+	if closer, ok := source.(io.Closer); ok {
+		err := closer.Close()
+		if err != nil {
+			errorLogger.Print(err)
+		}
+	}
+}
+
+func (nebula *nebula) stop() {
+	nebula.cancel()
+	<-nebula.done
+	closeExtractor(nebula.extractor)
+}
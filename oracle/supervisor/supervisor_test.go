@@ -0,0 +1,401 @@
+package supervisor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/abi"
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/adaptors"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/common/state"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/Gravity-Tech/gravity-core/common/transactions"
+	"github.com/Gravity-Tech/gravity-core/config"
+	"github.com/Gravity-Tech/gravity-core/oracle/aggregator"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor/extractorgrpc"
+	"github.com/Gravity-Tech/gravity-core/simulation"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"google.golang.org/grpc"
+)
+
+const testGravityNodeUrl = "simulation"
+
+func newTestExtractor() *httptest.Server {
+	value := extractor.Data{Type: extractor.Int64, Value: "42"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+extractor.ExtractPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(value)
+	})
+	mux.HandleFunc("/"+extractor.AggregatePath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(value)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+type testGrpcExtractor struct{}
+
+func (testGrpcExtractor) Info(ctx context.Context) (*extractor.Info, error) {
+	return &extractor.Info{DataType: extractor.Int64}, nil
+}
+
+func (testGrpcExtractor) Extract(ctx context.Context) (*extractor.Data, error) {
+	return &extractor.Data{Type: extractor.Int64, Value: "42"}, nil
+}
+
+func (testGrpcExtractor) Aggregate(values []extractor.Data, ctx context.Context) (*extractor.Data, error) {
+	return &values[0], nil
+}
+
+func (testGrpcExtractor) Subscribe(send func(*extractor.Data) error, ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// newTestGrpcExtractor returns the url of an extractor of the grpc protocol.
+func newTestGrpcExtractor(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	extractorgrpc.Register(server, testGrpcExtractor{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return GrpcScheme + "://" + listener.Addr().String()
+}
+
+// addNebula registers the nebula on the mock chain and in the ledger.
+func addNebula(t *testing.T, network *simulation.Network, name string, aggregatorType aggregator.Type) account.NebulaId {
+	nebulaId := account.BytesToNebulaId(crypto.Keccak256([]byte(name))[:account.EthereumAddressLength])
+	network.Chain().AddNebula(nebulaId, abi.Int64Type, network.Chain().Consuls(0), simulation.Bft(len(network.Validators())))
+
+	owner := network.Validators()[0]
+	tx := transactions.New(owner.PubKey, &transactions.SetNebulaArgs{
+		NebulaId: nebulaId,
+		Info: storage.NebulaInfo{
+			MaxPulseCountInBlock: 1,
+			ChainType:            account.Ethereum,
+			Owner:                owner.PubKey,
+			Aggregator:           string(aggregatorType),
+		},
+	})
+	if err := owner.Client.SendTx(tx, owner.PrivKey); err != nil {
+		t.Fatal(err)
+	}
+
+	return nebulaId
+}
+
+// writeConfig writes the config of the nebula, with the extractor urls as
+// its sources when there are more than one.
+func writeConfig(t *testing.T, dir string, nebulaId account.NebulaId, blocksInterval uint64, extractorUrls ...string) {
+	cfg := &config.OracleConfig{
+		GravityNodeUrl: testGravityNodeUrl,
+		ChainType:      account.Ethereum.String(),
+		ExtractorUrl:   extractorUrls[0],
+		BlocksInterval: blocksInterval,
+	}
+	if len(extractorUrls) > 1 {
+		cfg.ExtractorUrl = ""
+		cfg.Sources = &config.SourcesConfig{}
+		for _, extractorUrl := range extractorUrls {
+			cfg.Sources.Extractors = append(cfg.Sources.Extractors, config.ExtractorSourceConfig{Url: extractorUrl})
+		}
+	}
+
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, nebulaId.ToString(account.Ethereum)+ConfigExt), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestSupervisor returns a supervisor of the validator at index and counts
+// the adaptors it builds.
+func newTestSupervisor(t *testing.T, network *simulation.Network, index int, dir string, ctx context.Context, built *int32) *Supervisor {
+	v := network.Validators()[index]
+	keys := &config.Keys{
+		Validator: config.Key{PrivKey: hexutil.Encode(v.PrivKey[:])},
+		TargetChains: map[string]config.Key{
+			account.Ethereum.String(): {PrivKey: hexutil.Encode(v.OracleKey)},
+		},
+	}
+
+	// The supervisor sends the txs of the validator key with a client of its
+	// own, like the oracle process does next to the ledger.
+	ghClient, err := network.NewClient(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	supervisor, err := New(dir, keys, nil, ctx,
+		WithGravityClient(testGravityNodeUrl, ghClient),
+		WithAdaptorFactory(func(cfg adaptors.Config, ctx context.Context) (adaptors.IBlockchainAdaptor, error) {
+			atomic.AddInt32(built, 1)
+			return adaptors.NewMockAdaptor(cfg.PrivKey, network.Chain(), adaptors.MockAdaptorWithGhClient(cfg.GhClient))
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(supervisor.Stop)
+
+	return supervisor
+}
+
+// deliveredHeights counts the commits and the reveals of the first pulses
+// of the nebula by the ledger height they were delivered at.
+func deliveredHeights(t *testing.T, network *simulation.Network, nebulaId account.NebulaId) (commits map[int64]int, reveals map[int64]int) {
+	commits, reveals = make(map[int64]int), make(map[int64]int)
+	client := network.Validators()[0].Client
+	for _, v := range network.Validators() {
+		oracleKey, err := crypto.ToECDSA(v.OracleKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		oracle := account.BytesToOraclePubKey(crypto.CompressPubkey(&oracleKey.PublicKey), account.Ethereum)
+
+		for pulseId := int64(1); pulseId <= 3; pulseId++ {
+			var commit []byte
+			for height := int64(1); height <= network.Height(); height++ {
+				ledger := client.AtHeight(height)
+				if commit == nil {
+					commit, err = ledger.CommitHash(account.Ethereum, nebulaId, 0, pulseId, oracle)
+					if err == gravity.ErrValueNotFound {
+						continue
+					} else if err != nil {
+						t.Fatal(err)
+					}
+					commits[height]++
+				}
+
+				_, err = ledger.Reveal(account.Ethereum, oracle, nebulaId, 0, pulseId, commit)
+				if err == gravity.ErrValueNotFound {
+					continue
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				reveals[height]++
+				break
+			}
+		}
+	}
+
+	return commits, reveals
+}
+
+func TestSupervisor(t *testing.T) {
+	network, err := simulation.New(simulation.Config{Validators: 4, ChainType: account.Ethereum})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Close()
+
+	server := newTestExtractor()
+	defer server.Close()
+	grpcExtractorUrl := newTestGrpcExtractor(t)
+
+	dir, err := ioutil.TempDir("", "gravity-nebulae")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	network.Start(time.Millisecond)
+	if err := network.WaitHeight(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	first := addNebula(t, network, "first nebula", aggregator.Remote)
+	second := addNebula(t, network, "second nebula", aggregator.ScoreWeighted)
+	unknown := account.BytesToNebulaId(crypto.Keccak256([]byte("unknown nebula"))[:account.EthereumAddressLength])
+	writeConfig(t, dir, first, 1<<20, server.URL)
+	writeConfig(t, dir, second, 1<<20, grpcExtractorUrl, server.URL)
+	writeConfig(t, dir, unknown, 1<<20, server.URL)
+	if err := ioutil.WriteFile(path.Join(dir, "README"), []byte("not a config"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var built int32
+	var supervisors []*Supervisor
+	for i := range network.Validators() {
+		supervisor := newTestSupervisor(t, network, i, dir, ctx, &built)
+		if err := supervisor.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		supervisors = append(supervisors, supervisor)
+	}
+
+	// The unknown nebula fails to start and the others share one adaptor.
+	expected := []string{first.ToString(account.Ethereum), second.ToString(account.Ethereum)}
+	sort.Strings(expected)
+	if nebulae := supervisors[0].Nebulae(); !reflect.DeepEqual(nebulae, expected) {
+		t.Fatalf("nebulae: expected %v, got %v", expected, nebulae)
+	}
+	if built != int32(len(supervisors)) {
+		t.Fatalf("adaptors: expected %d, got %d", len(supervisors), built)
+	}
+
+	// Both nebulae get pulses from their own loops, the second takes the
+	// values from both extractors and aggregates the reveals locally.
+	if err := network.WaitHeight(ctx, state.CalculateScoreInterval); err != nil {
+		t.Fatal(err)
+	}
+	network.SetBlockInterval(100 * time.Millisecond)
+	for _, nebulaId := range []account.NebulaId{first, second} {
+		for {
+			lastPulseId, err := network.Chain().LastPulseId(nebulaId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lastPulseId >= 1 {
+				break
+			}
+			if err := network.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case <-ctx.Done():
+				t.Fatalf("nebula %s: no pulses", nebulaId.ToString(account.Ethereum))
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	}
+
+	// The nebulae share the client of the validator, and still all of their
+	// oracles commit and reveal in the same round.
+	firstCommits, firstReveals := deliveredHeights(t, network, first)
+	secondCommits, secondReveals := deliveredHeights(t, network, second)
+	var height int64
+	for _, commits := range []map[int64]int{firstCommits, secondCommits} {
+		for h := range commits {
+			if height == 0 || h < height {
+				height = h
+			}
+		}
+	}
+	validators := len(network.Validators())
+	if firstCommits[height] != validators || secondCommits[height] != validators {
+		t.Errorf("commits at %d: expected %d of each nebula, got %v and %v", height, validators, firstCommits, secondCommits)
+	}
+	if firstReveals[height+1] != validators || secondReveals[height+1] != validators {
+		t.Errorf("reveals at %d: expected %d of each nebula, got %v and %v", height+1, validators, firstReveals, secondReveals)
+	}
+
+	health := httptest.NewRecorder()
+	supervisors[0].HealthHandler(DefaultHealthTimeout).ServeHTTP(health, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if health.Code != http.StatusOK {
+		t.Errorf("health: expected %d, got %d %s", http.StatusOK, health.Code, health.Body)
+	}
+
+	// A removed config stops its nebula and a changed one restarts it.
+	if err := os.Remove(path.Join(dir, second.ToString(account.Ethereum)+ConfigExt)); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig(t, dir, first, 1<<21, server.URL)
+	if err := supervisors[0].Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if nebulae := supervisors[0].Nebulae(); !reflect.DeepEqual(nebulae, []string{first.ToString(account.Ethereum)}) {
+		t.Fatalf("nebulae: expected %s, got %v", first.ToString(account.Ethereum), nebulae)
+	}
+	if cfg := supervisors[0].nebulae[first.ToString(account.Ethereum)].cfg; cfg.BlocksInterval != 1<<21 {
+		t.Errorf("blocks interval: expected %d, got %d", 1<<21, cfg.BlocksInterval)
+	}
+	if built != int32(len(supervisors)) {
+		t.Errorf("adaptors: expected %d, got %d", len(supervisors), built)
+	}
+
+	supervisors[0].Stop()
+	if err := supervisors[0].Health(ctx); err != ErrNoNebulae {
+		t.Errorf("health: expected %v, got %v", ErrNoNebulae, err)
+	}
+}
+
+func TestReloadDoesNotBlockHealth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gravity-nebulae")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	nebulaId := account.BytesToNebulaId(crypto.Keccak256([]byte("nebula"))[:account.EthereumAddressLength])
+	writeConfig(t, dir, nebulaId, 1, "http://127.0.0.1:0")
+
+	oracleKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := &config.Keys{
+		Validator: config.Key{PrivKey: hexutil.Encode(ed25519.GenPrivKey())},
+		TargetChains: map[string]config.Key{
+			account.Ethereum.String(): {PrivKey: hexutil.Encode(crypto.FromECDSA(oracleKey))},
+		},
+	}
+	ghClient, err := gravity.NewWithRPC(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The target chain answers only when the test releases it.
+	building := make(chan struct{})
+	release := make(chan struct{})
+	supervisor, err := New(dir, keys, nil, context.Background(),
+		WithGravityClient(testGravityNodeUrl, ghClient),
+		WithAdaptorFactory(func(cfg adaptors.Config, ctx context.Context) (adaptors.IBlockchainAdaptor, error) {
+			close(building)
+			<-release
+			return nil, errors.New("target chain is down")
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := make(chan error)
+	go func() {
+		reloaded <- supervisor.Reload()
+	}()
+	<-building
+
+	checked := make(chan error)
+	go func() {
+		checked <- supervisor.Health(context.Background())
+	}()
+	select {
+	case err := <-checked:
+		if err != ErrNoNebulae {
+			t.Errorf("health: expected %v, got %v", ErrNoNebulae, err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("health waits for the reload")
+	}
+
+	close(release)
+	if err := <-reloaded; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -15,7 +15,7 @@ type Config struct {
 
 func NewConfig(host string, ghClientUrl string, privKey crypto.PrivKey) (*Config, error) {
 	var ghPubKey account.ConsulPubKey
-	copy(ghPubKey[:], privKey.PubKey().Bytes()[5:])
+	copy(ghPubKey[:], privKey.PubKey().Bytes())
 
 	ghClient, err := gravity.New(ghClientUrl)
 	if err != nil {
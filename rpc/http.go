@@ -19,6 +19,8 @@ type SetNebulaRq struct {
 	ChainType            string
 	MaxPulseCountInBlock uint64
 	MinScore             uint64
+	Aggregator           string
+	Precision            uint8
 }
 type VotesRq struct {
 	Votes []VoteRq
@@ -47,12 +49,6 @@ func vote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tx, err := transactions.New(cfg.pubKey, transactions.Vote, cfg.privKey)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
 	var votes []storage.Vote
 	for _, v := range request.Votes {
 		pubKey, err := account.HexToValidatorPubKey(v.PubKey)
@@ -66,13 +62,8 @@ func vote(w http.ResponseWriter, r *http.Request) {
 			Score:  v.Score,
 		})
 	}
-	b, err := json.Marshal(votes)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	tx.AddValue(transactions.BytesValue{Value: b})
-	err = cfg.client.SendTx(tx)
+	tx := transactions.New(cfg.pubKey, &transactions.VoteArgs{Votes: votes})
+	err = cfg.client.SendTx(tx, cfg.privKey)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -97,16 +88,16 @@ func setNebula(r *http.Request) error {
 		return err
 	}
 
-	tx, err := transactions.New(cfg.pubKey, transactions.SetNebula, cfg.privKey)
+	chainType, err := account.ParseChainType(request.ChainType)
 	if err != nil {
 		return err
 	}
-
-	chainType, err := account.ParseChainType(request.ChainType)
+	nebulaId, err := account.StringToNebulaId(request.NebulaId, chainType)
 	if err != nil {
 		return err
 	}
-	nebulaId, err := account.StringToNebulaId(request.NebulaId, chainType)
+
+	_, err = storage.ParseAggregatorType(request.Aggregator)
 	if err != nil {
 		return err
 	}
@@ -116,17 +107,14 @@ func setNebula(r *http.Request) error {
 		MinScore:             request.MinScore,
 		ChainType:            chainType,
 		Owner:                cfg.pubKey,
+		Aggregator:           request.Aggregator,
+		Precision:            request.Precision,
 	}
-	b, err := json.Marshal(nebulaInfo)
-	if err != nil {
-		return err
-	}
-
-	tx.AddValues([]transactions.Value{
-		transactions.BytesValue{Value: nebulaId[:]},
-		transactions.BytesValue{Value: b},
+	tx := transactions.New(cfg.pubKey, &transactions.SetNebulaArgs{
+		NebulaId: nebulaId,
+		Info:     nebulaInfo,
 	})
-	err = cfg.client.SendTx(tx)
+	err = cfg.client.SendTx(tx, cfg.privKey)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,141 @@
+package simulation
+
+import (
+	"context"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	"github.com/tendermint/tendermint/light"
+	"github.com/tendermint/tendermint/light/provider"
+	"github.com/tendermint/tendermint/light/store/db"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmversion "github.com/tendermint/tendermint/proto/tendermint/version"
+	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/version"
+	dbm "github.com/tendermint/tm-db"
+)
+
+const (
+	lightTrustingPeriod = time.Hour
+)
+
+// blockHeader is what the network keeps of a block to serve it to light
+// clients. The app hash is the one of the previous block, as in tendermint.
+type blockHeader struct {
+	appHash []byte
+	time    time.Time
+}
+
+// nextHeader returns the header of the block at height. Light clients require
+// the block times to increase.
+func (network *Network) nextHeader(height int64) blockHeader {
+	now := time.Now()
+	if prev, ok := network.headers[height-1]; ok && !now.After(prev.time) {
+		now = prev.time.Add(time.Millisecond)
+	}
+
+	return blockHeader{
+		appHash: network.appHash,
+		time:    now,
+	}
+}
+
+// RPC returns the rpc endpoint of the validator at index.
+func (network *Network) RPC(index int) gravity.RPCClient {
+	return &localRPC{network: network, index: index}
+}
+
+// LightProvider serves the blocks of the network signed by all of its
+// validators.
+func (network *Network) LightProvider() provider.Provider {
+	return &lightProvider{network: network}
+}
+
+// NewLightClient returns a light client that trusts the first block of the
+// network and verifies the others with LightProvider.
+func (network *Network) NewLightClient(ctx context.Context) (*light.Client, error) {
+	p := network.LightProvider()
+	first, err := p.LightBlock(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return light.NewClient(ctx, network.chainId, light.TrustOptions{
+		Period: lightTrustingPeriod,
+		Height: 1,
+		Hash:   first.Hash(),
+	}, p, []provider.Provider{p}, db.New(dbm.NewMemDB(), network.chainId))
+}
+
+type lightProvider struct {
+	network *Network
+}
+
+var _ provider.Provider = (*lightProvider)(nil)
+
+func (p *lightProvider) ChainID() string {
+	return p.network.chainId
+}
+
+func (p *lightProvider) LightBlock(ctx context.Context, height int64) (*types.LightBlock, error) {
+	network := p.network
+	network.mu.Lock()
+	if height == 0 {
+		height = network.height
+	}
+	header, ok := network.headers[height]
+	network.mu.Unlock()
+	if !ok {
+		return nil, provider.ErrLightBlockNotFound
+	}
+
+	vals, privVals := network.validatorSet()
+	h := &types.Header{
+		Version:            tmversion.Consensus{Block: version.BlockProtocol},
+		ChainID:            network.chainId,
+		Height:             height,
+		Time:               header.time,
+		AppHash:            header.appHash,
+		ValidatorsHash:     vals.Hash(),
+		NextValidatorsHash: vals.Hash(),
+		ProposerAddress:    vals.GetProposer().Address,
+	}
+	blockID := types.BlockID{
+		Hash:          h.Hash(),
+		PartSetHeader: types.PartSetHeader{Total: 1, Hash: tmhash.Sum(h.Hash())},
+	}
+
+	voteSet := types.NewVoteSet(network.chainId, height, 0, tmproto.PrecommitType, vals)
+	commit, err := types.MakeCommit(blockID, height, 0, voteSet, privVals, header.time)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.LightBlock{
+		SignedHeader: &types.SignedHeader{Header: h, Commit: commit},
+		ValidatorSet: vals,
+	}, nil
+}
+
+func (p *lightProvider) ReportEvidence(ctx context.Context, evidence types.Evidence) error {
+	return nil
+}
+
+// validatorSet returns the validators of the network with their signers in
+// the order of the set.
+func (network *Network) validatorSet() (*types.ValidatorSet, []types.PrivValidator) {
+	var validators []*types.Validator
+	for _, v := range network.validators {
+		validators = append(validators, types.NewValidator(v.PrivKey.PubKey(), ValidatorPower))
+	}
+	vals := types.NewValidatorSet(validators)
+
+	privVals := make([]types.PrivValidator, len(vals.Validators))
+	for _, v := range network.validators {
+		index, _ := vals.GetByAddress(v.PrivKey.PubKey().Address())
+		privVals[index] = types.NewMockPVWithParams(v.PrivKey, false, false)
+	}
+
+	return vals, privVals
+}
@@ -0,0 +1,457 @@
+// Package simulation runs ledger validators and oracle nodes in one process
+// against an in-memory target chain.
+package simulation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/adaptors"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/config"
+	"github.com/Gravity-Tech/gravity-core/ledger/app"
+	"github.com/Gravity-Tech/gravity-core/ledger/scheduler"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"github.com/Gravity-Tech/gravity-core/oracle/node"
+	"github.com/dgraph-io/badger"
+	"github.com/ethereum/go-ethereum/crypto"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	DefaultChainId = "gravity-simulation"
+	ValidatorPower = 100
+)
+
+var (
+	ErrNetworkClosed   = errors.New("network is closed")
+	ErrAppHashMismatch = errors.New("app hash mismatch")
+)
+
+type Config struct {
+	ChainId    string
+	Validators int
+	// ChainType of the mock target chain, it must be of the EVM kind.
+	ChainType account.ChainType
+}
+
+// Validator is a ledger validator with its target chain key. Its scheduler
+// and oracles send transactions with their own clients, like the processes
+// of a consul do, and Client is one more of them.
+type Validator struct {
+	PubKey    account.ConsulPubKey
+	PrivKey   ed25519.PrivKey
+	OracleKey []byte
+	Client    *gravity.Client
+
+	// lock serializes the calls to app like the local ABCI client of
+	// tendermint does.
+	lock   sync.Mutex
+	app    *app.GHApplication
+	db     *badger.DB
+	dir    string
+	closed bool
+}
+
+// Network produces blocks for its validators. Every block is executed by all
+// of them and their app hashes must match.
+type Network struct {
+	chainId    string
+	chain      *adaptors.MockChain
+	validators []*Validator
+	eventBus   *types.EventBus
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	height   int64
+	appHash  []byte
+	headers  map[int64]blockHeader
+	mempool  []*pendingTx
+	txs      map[string]*pendingTx
+	interval time.Duration
+	started  bool
+	err      error
+
+	blockLock sync.Mutex
+	stopped   chan struct{}
+	done      chan struct{}
+}
+
+type pendingTx struct {
+	tx        []byte
+	validator int
+	checkTx   abcitypes.ResponseCheckTx
+	deliverTx abcitypes.ResponseDeliverTx
+	height    int64
+	included  chan struct{}
+}
+
+// New starts the validators from a genesis where every validator is a consul
+// and has an oracle of cfg.ChainType.
+func New(cfg Config) (*Network, error) {
+	if cfg.ChainId == "" {
+		cfg.ChainId = DefaultChainId
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	network := &Network{
+		chainId:  cfg.ChainId,
+		eventBus: types.NewEventBus(),
+		ctx:      ctx,
+		cancel:   cancel,
+		headers:  make(map[int64]blockHeader),
+		txs:      make(map[string]*pendingTx),
+		stopped:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	err := network.eventBus.Start()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	genesis := &app.Genesis{
+		ChainId:                   cfg.ChainId,
+		ConsulsCount:              cfg.Validators,
+		OraclesAddressByValidator: make(map[account.ConsulPubKey][]app.OraclesAddresses),
+	}
+	var consuls []account.OraclesPubKey
+	var validatorUpdates []abcitypes.ValidatorUpdate
+	for i := 0; i < cfg.Validators; i++ {
+		privKey := ed25519.GenPrivKey()
+		var pubKey account.ConsulPubKey
+		copy(pubKey[:], privKey.PubKey().Bytes())
+
+		oracleKey, err := crypto.GenerateKey()
+		if err != nil {
+			network.Close()
+			return nil, err
+		}
+		oracle := account.BytesToOraclePubKey(crypto.CompressPubkey(&oracleKey.PublicKey), cfg.ChainType)
+
+		network.validators = append(network.validators, &Validator{
+			PubKey:    pubKey,
+			PrivKey:   privKey,
+			OracleKey: crypto.FromECDSA(oracleKey),
+		})
+		genesis.OraclesAddressByValidator[pubKey] = []app.OraclesAddresses{
+			{ChainType: cfg.ChainType, OraclesPubKey: oracle},
+		}
+		consuls = append(consuls, oracle)
+		validatorUpdates = append(validatorUpdates, abcitypes.Ed25519ValidatorUpdate(pubKey[:], ValidatorPower))
+	}
+
+	chain, err := adaptors.NewMockChain(cfg.ChainType, consuls, Bft(cfg.Validators))
+	if err != nil {
+		network.Close()
+		return nil, err
+	}
+	network.chain = chain
+
+	for i, v := range network.validators {
+		err := network.startValidator(i, v, genesis)
+		if err != nil {
+			network.Close()
+			return nil, err
+		}
+
+		v.app.InitChain(abcitypes.RequestInitChain{
+			ChainId:    cfg.ChainId,
+			Validators: validatorUpdates,
+		})
+	}
+
+	return network, nil
+}
+
+// Bft is the number of signs a mock contract needs out of count.
+func Bft(count int) int {
+	return count*2/3 + 1
+}
+
+func (network *Network) startValidator(index int, v *Validator, genesis *app.Genesis) error {
+	dir, err := ioutil.TempDir("", "gravity-simulation")
+	if err != nil {
+		return err
+	}
+	v.dir = dir
+
+	v.db, err = badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		return err
+	}
+
+	v.Client, err = network.NewClient(index)
+	if err != nil {
+		return err
+	}
+	schedulerClient, err := network.NewClient(index)
+	if err != nil {
+		return err
+	}
+
+	adaptor, err := adaptors.NewMockAdaptor(v.OracleKey, network.chain, adaptors.MockAdaptorWithGhClient(schedulerClient))
+	if err != nil {
+		return err
+	}
+
+	ledger := &account.LedgerValidator{
+		PrivKey: v.PrivKey,
+		PubKey:  v.PubKey,
+	}
+	blockScheduler, err := scheduler.New(map[account.ChainType]adaptors.IBlockchainAdaptor{
+		network.chain.ChainType(): adaptor,
+	}, ledger, schedulerClient, network.ctx)
+	if err != nil {
+		return err
+	}
+
+	ledgerConfig := config.DefaultLedgerConfig()
+	v.app, err = app.NewGHApplication(blockScheduler, v.db, nil, genesis, &ledgerConfig)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NewClient returns a client of the validator at index with nonces of its
+// own, like the client of another process.
+func (network *Network) NewClient(index int) (*gravity.Client, error) {
+	return gravity.NewWithRPC(network.RPC(index), gravity.WithTxPolling(gravity.DefaultTxTimeout, time.Millisecond))
+}
+
+func (network *Network) Chain() *adaptors.MockChain {
+	return network.chain
+}
+
+func (network *Network) Validators() []*Validator {
+	return network.validators
+}
+
+// NewOracle returns an oracle node of the validator that extracts data from
+// extractorUrl and sends pulses to the mock chain.
+func (network *Network) NewOracle(index int, nebulaId account.NebulaId, extractorUrl string, blocksInterval uint64) (*node.Node, error) {
+	v := network.validators[index]
+	client, err := network.NewClient(index)
+	if err != nil {
+		return nil, err
+	}
+	adaptor, err := adaptors.NewMockAdaptor(v.OracleKey, network.chain, adaptors.MockAdaptorWithGhClient(client))
+	if err != nil {
+		return nil, err
+	}
+
+	return node.NewWithAdaptor(nebulaId, network.chain.ChainType(), adaptor, client,
+		node.NewValidator(v.PrivKey), extractor.New(extractorUrl), blocksInterval, network.ctx)
+}
+
+func (network *Network) Height() int64 {
+	network.mu.Lock()
+	defer network.mu.Unlock()
+
+	return network.height
+}
+
+// Err returns the error that stopped block production.
+func (network *Network) Err() error {
+	network.mu.Lock()
+	defer network.mu.Unlock()
+
+	return network.err
+}
+
+// SetBlockInterval changes the pause between the blocks produced after Start.
+func (network *Network) SetBlockInterval(interval time.Duration) {
+	network.mu.Lock()
+	defer network.mu.Unlock()
+
+	network.interval = interval
+}
+
+// Start produces a block every interval until Close or a failed block.
+func (network *Network) Start(interval time.Duration) {
+	network.mu.Lock()
+	network.interval = interval
+	network.started = true
+	network.mu.Unlock()
+
+	go func() {
+		defer close(network.done)
+		for {
+			network.mu.Lock()
+			interval := network.interval
+			network.mu.Unlock()
+
+			select {
+			case <-network.stopped:
+				return
+			case <-time.After(interval):
+			}
+
+			err := network.Block()
+			if err != nil {
+				network.mu.Lock()
+				network.err = err
+				network.mu.Unlock()
+				return
+			}
+		}
+	}()
+}
+
+// WaitHeight blocks until the network commits the block at height.
+func (network *Network) WaitHeight(ctx context.Context, height int64) error {
+	for network.Height() < height {
+		if err := network.Err(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-network.stopped:
+			return ErrNetworkClosed
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	return nil
+}
+
+// Block executes the next block with every transaction in the mempool, mines
+// a block on the mock chain and publishes a NewBlock event.
+func (network *Network) Block() error {
+	network.blockLock.Lock()
+	defer network.blockLock.Unlock()
+
+	network.mu.Lock()
+	height := network.height + 1
+	txs := network.mempool
+	network.mempool = nil
+	network.headers[height] = network.nextHeader(height)
+	network.mu.Unlock()
+
+	var appHash []byte
+	for i, v := range network.validators {
+		hash, err := v.executeBlock(network.chainId, height, i, txs)
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			appHash = hash
+		} else if !bytes.Equal(appHash, hash) {
+			return ErrAppHashMismatch
+		}
+	}
+
+	network.chain.Mine()
+
+	network.mu.Lock()
+	network.height = height
+	network.appHash = appHash
+	for _, tx := range txs {
+		tx.height = height
+		network.txs[string(types.Tx(tx.tx).Hash())] = tx
+		close(tx.included)
+	}
+	network.mu.Unlock()
+
+	return network.eventBus.PublishEventNewBlock(types.EventDataNewBlock{
+		Block: &types.Block{Header: types.Header{ChainID: network.chainId, Height: height, AppHash: appHash}},
+	})
+}
+
+func (v *Validator) executeBlock(chainId string, height int64, index int, txs []*pendingTx) ([]byte, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.closed {
+		return nil, ErrNetworkClosed
+	}
+
+	v.app.BeginBlock(abcitypes.RequestBeginBlock{
+		Header: tmproto.Header{ChainID: chainId, Height: height},
+	})
+	for _, tx := range txs {
+		rs := v.app.DeliverTx(abcitypes.RequestDeliverTx{Tx: tx.tx})
+		if tx.validator == index {
+			tx.deliverTx = rs
+		}
+	}
+	v.app.EndBlock(abcitypes.RequestEndBlock{Height: height})
+
+	return v.app.Commit().Data, nil
+}
+
+// checkTx runs CheckTx on the validator and adds the transaction to the
+// mempool when it passes.
+func (network *Network) checkTx(index int, tx []byte) (*pendingTx, error) {
+	v := network.validators[index]
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.closed {
+		return nil, ErrNetworkClosed
+	}
+
+	pending := &pendingTx{
+		tx:        tx,
+		validator: index,
+		checkTx:   v.app.CheckTx(abcitypes.RequestCheckTx{Tx: tx}),
+		included:  make(chan struct{}),
+	}
+	if pending.checkTx.Code != app.Success {
+		return pending, nil
+	}
+
+	network.mu.Lock()
+	network.mempool = append(network.mempool, pending)
+	network.mu.Unlock()
+
+	return pending, nil
+}
+
+// Close stops block production, the schedulers and oracle nodes and removes
+// the validator databases.
+func (network *Network) Close() {
+	select {
+	case <-network.stopped:
+		return
+	default:
+	}
+	close(network.stopped)
+	network.cancel()
+
+	network.mu.Lock()
+	started := network.started
+	network.mu.Unlock()
+	if started {
+		<-network.done
+	}
+	network.eventBus.Stop()
+
+	for _, v := range network.validators {
+		v.lock.Lock()
+		v.closed = true
+		if v.db != nil {
+			v.db.Close()
+		}
+		if v.dir != "" {
+			os.RemoveAll(v.dir)
+		}
+		v.lock.Unlock()
+	}
+}
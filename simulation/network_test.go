@@ -0,0 +1,144 @@
+package simulation
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Gravity-Tech/gravity-core/abi"
+	"github.com/Gravity-Tech/gravity-core/common/account"
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/common/state"
+	"github.com/Gravity-Tech/gravity-core/common/storage"
+	"github.com/Gravity-Tech/gravity-core/common/transactions"
+	"github.com/Gravity-Tech/gravity-core/oracle/extractor"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testValue = 42
+
+func newTestExtractor() *httptest.Server {
+	value := extractor.Data{Type: extractor.Int64, Value: strconv.Itoa(testValue)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+extractor.ExtractPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(value)
+	})
+	mux.HandleFunc("/"+extractor.AggregatePath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(value)
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestPulses(t *testing.T) {
+	network, err := New(Config{Validators: 4, ChainType: account.Ethereum})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer network.Close()
+
+	server := newTestExtractor()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	nebulaId := account.BytesToNebulaId(crypto.Keccak256([]byte("nebula"))[:account.EthereumAddressLength])
+	network.Chain().AddNebula(nebulaId, abi.Int64Type, network.Chain().Consuls(0), Bft(len(network.Validators())))
+	network.Start(time.Millisecond)
+	if err := network.WaitHeight(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := network.Validators()[0]
+	tx := transactions.New(owner.PubKey, &transactions.SetNebulaArgs{
+		NebulaId: nebulaId,
+		Info: storage.NebulaInfo{
+			MaxPulseCountInBlock: 1,
+			ChainType:            account.Ethereum,
+			Owner:                owner.PubKey,
+		},
+	})
+	if err := owner.Client.SendTx(tx, owner.PrivKey); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range network.Validators() {
+		oracle, err := network.NewOracle(i, nebulaId, server.URL, 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := oracle.Init(); err != nil {
+			t.Fatal(err)
+		}
+		oracle.PollInterval = 10 * time.Millisecond
+		go oracle.Start(ctx)
+	}
+
+	// The oracles of a nebula are elected with the scores.
+	if err := network.WaitHeight(ctx, state.CalculateScoreInterval); err != nil {
+		t.Fatal(err)
+	}
+	network.SetBlockInterval(100 * time.Millisecond)
+
+	for {
+		lastPulseId, err := network.Chain().LastPulseId(nebulaId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lastPulseId >= 2 {
+			break
+		}
+		if err := network.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("pulses: expected 2, got %d", lastPulseId)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	var value [8]byte
+	binary.BigEndian.PutUint64(value[:], testValue)
+	for pulseId := uint64(1); pulseId <= 2; pulseId++ {
+		hash, err := network.Chain().Pulse(nebulaId, pulseId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(hash) != string(crypto.Keccak256(value[:])) {
+			t.Errorf("pulse %d: unexpected hash %x", pulseId, hash)
+		}
+	}
+
+	sent, err := network.Chain().Value(nebulaId, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.Value != strconv.Itoa(testValue) {
+		t.Errorf("value: expected %d, got %s", testValue, sent.Value)
+	}
+
+	// The consuls attest the chain height of the round at its first block.
+	for {
+		_, err := owner.Client.RoundHeight(account.Ethereum, state.CalculateScoreInterval)
+		if err == nil {
+			break
+		}
+		if err != gravity.ErrValueNotFound {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("round height is not attested")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Gravity-Tech/gravity-core/common/gravity"
+	"github.com/Gravity-Tech/gravity-core/ledger/app"
+	abcitypes "github.com/tendermint/tendermint/abci/types"
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	tmquery "github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/p2p"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// localRPC is the rpc endpoint of one validator of the network.
+type localRPC struct {
+	network *Network
+	index   int
+}
+
+var _ gravity.RPCClient = (*localRPC)(nil)
+
+func (rpc *localRPC) ABCIInfo(ctx context.Context) (*ctypes.ResultABCIInfo, error) {
+	v := rpc.network.validators[rpc.index]
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.closed {
+		return nil, ErrNetworkClosed
+	}
+
+	return &ctypes.ResultABCIInfo{Response: v.app.Info(abcitypes.RequestInfo{})}, nil
+}
+
+func (rpc *localRPC) ABCIQuery(ctx context.Context, path string, data tmbytes.HexBytes) (*ctypes.ResultABCIQuery, error) {
+	return rpc.ABCIQueryWithOptions(ctx, path, data, rpcclient.DefaultABCIQueryOptions)
+}
+
+func (rpc *localRPC) ABCIQueryWithOptions(ctx context.Context, path string, data tmbytes.HexBytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	v := rpc.network.validators[rpc.index]
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.closed {
+		return nil, ErrNetworkClosed
+	}
+
+	rs := v.app.Query(abcitypes.RequestQuery{
+		Data:   data,
+		Path:   path,
+		Height: opts.Height,
+		Prove:  opts.Prove,
+	})
+	return &ctypes.ResultABCIQuery{Response: rs}, nil
+}
+
+// BroadcastTxCommit returns once the transaction is executed in a block or
+// rejected by CheckTx.
+func (rpc *localRPC) BroadcastTxCommit(ctx context.Context, tx types.Tx) (*ctypes.ResultBroadcastTxCommit, error) {
+	pending, err := rpc.network.checkTx(rpc.index, tx)
+	if err != nil {
+		return nil, err
+	}
+	if pending.checkTx.Code != 0 {
+		return &ctypes.ResultBroadcastTxCommit{CheckTx: pending.checkTx, Hash: tx.Hash()}, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-rpc.network.stopped:
+		return nil, ErrNetworkClosed
+	case <-pending.included:
+	}
+
+	return &ctypes.ResultBroadcastTxCommit{
+		CheckTx:   pending.checkTx,
+		DeliverTx: pending.deliverTx,
+		Hash:      tx.Hash(),
+		Height:    pending.height,
+	}, nil
+}
+
+func (rpc *localRPC) BroadcastTxAsync(ctx context.Context, tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
+	return rpc.BroadcastTxSync(ctx, tx)
+}
+
+func (rpc *localRPC) BroadcastTxSync(ctx context.Context, tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
+	pending, err := rpc.network.checkTx(rpc.index, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ctypes.ResultBroadcastTx{
+		Code: pending.checkTx.Code,
+		Data: pending.checkTx.Data,
+		Log:  pending.checkTx.Log,
+		Hash: tx.Hash(),
+	}, nil
+}
+
+// Tx returns a transaction with its result once it is committed. The
+// transactions that are not committed when the network is closed never will
+// be, so they fail.
+func (rpc *localRPC) Tx(ctx context.Context, hash []byte, prove bool) (*ctypes.ResultTx, error) {
+	rpc.network.mu.Lock()
+	defer rpc.network.mu.Unlock()
+
+	pending, ok := rpc.network.txs[string(hash)]
+	if !ok {
+		select {
+		case <-rpc.network.stopped:
+			return &ctypes.ResultTx{
+				Hash:     hash,
+				TxResult: abcitypes.ResponseDeliverTx{Code: app.Error, Info: ErrNetworkClosed.Error()},
+			}, nil
+		default:
+		}
+		return nil, fmt.Errorf("tx (%X) not found", hash)
+	}
+
+	return &ctypes.ResultTx{
+		Hash:     hash,
+		Height:   pending.height,
+		TxResult: pending.deliverTx,
+		Tx:       pending.tx,
+	}, nil
+}
+
+func (rpc *localRPC) Status(ctx context.Context) (*ctypes.ResultStatus, error) {
+	rpc.network.mu.Lock()
+	defer rpc.network.mu.Unlock()
+
+	return &ctypes.ResultStatus{
+		NodeInfo: p2p.DefaultNodeInfo{Network: rpc.network.chainId},
+		SyncInfo: ctypes.SyncInfo{
+			LatestAppHash:     rpc.network.appHash,
+			LatestBlockHeight: rpc.network.height,
+		},
+	}, nil
+}
+
+// Subscribe streams the events the network publishes, NewBlock events for
+// now. Events are dropped when out is full.
+func (rpc *localRPC) Subscribe(ctx context.Context, subscriber, query string, outCapacity ...int) (<-chan ctypes.ResultEvent, error) {
+	q, err := tmquery.New(query)
+	if err != nil {
+		return nil, err
+	}
+
+	outCap := 1
+	if len(outCapacity) > 0 && outCapacity[0] > 0 {
+		outCap = outCapacity[0]
+	}
+
+	sub, err := rpc.network.eventBus.Subscribe(ctx, rpc.subscriber(subscriber), q, outCap)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan ctypes.ResultEvent, outCap)
+	go func() {
+		for {
+			select {
+			case msg := <-sub.Out():
+				select {
+				case out <- ctypes.ResultEvent{Query: query, Data: msg.Data(), Events: msg.Events()}:
+				default:
+				}
+			case <-sub.Cancelled():
+				return
+			case <-rpc.network.stopped:
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (rpc *localRPC) Unsubscribe(ctx context.Context, subscriber, query string) error {
+	q, err := tmquery.New(query)
+	if err != nil {
+		return err
+	}
+
+	return rpc.network.eventBus.Unsubscribe(ctx, rpc.subscriber(subscriber), q)
+}
+
+func (rpc *localRPC) UnsubscribeAll(ctx context.Context, subscriber string) error {
+	return rpc.network.eventBus.UnsubscribeAll(ctx, rpc.subscriber(subscriber))
+}
+
+// subscriber tells apart the subscribers of different validators that share
+// the event bus of the network.
+func (rpc *localRPC) subscriber(name string) string {
+	return fmt.Sprintf("%d/%s", rpc.index, name)
+}